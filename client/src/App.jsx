@@ -14,6 +14,14 @@ import AdminCalendar from './pages/admin/AdminCalendar';
 
 import AdminSettings from './pages/admin/AdminSettings';
 import VerifyEmailPage from './pages/VerifyEmailPage';
+import ForgotPasswordPage from './pages/ForgotPasswordPage';
+import ResetPasswordPage from './pages/ResetPasswordPage';
+import AuthCallbackPage from './pages/AuthCallbackPage';
+import CodeLoginPage from './pages/CodeLoginPage';
+import BarberAgendaPage from './pages/BarberAgendaPage';
+import ResendVerificationPage from './pages/ResendVerificationPage';
+import TwoFactorPage from './pages/TwoFactorPage';
+import SecurityPage from './pages/SecurityPage';
 
 function App() {
   return (
@@ -23,17 +31,35 @@ function App() {
           <Route index element={<HomePage />} />
           <Route path="reservar" element={<BookingPage />} />
           <Route path="login" element={<LoginPage />} />
+          <Route path="login/code" element={<CodeLoginPage />} />
+          <Route path="login/2fa" element={<TwoFactorPage />} />
           <Route path="register" element={<RegisterPage />} />
           <Route path="verify-email" element={<VerifyEmailPage />} />
+          <Route path="verify-email/resend" element={<ResendVerificationPage />} />
+          <Route path="forgot-password" element={<ForgotPasswordPage />} />
+          <Route path="reset-password" element={<ResetPasswordPage />} />
+          <Route path="auth/callback" element={<AuthCallbackPage />} />
 
-          {/* Protected Admin Routes */}
-          <Route element={<ProtectedRoute role="admin" />}>
+          {/* Any logged-in user */}
+          <Route element={<ProtectedRoute />}>
+            <Route path="seguridad" element={<SecurityPage />} />
+          </Route>
+
+          {/* Barbers see only their own agenda */}
+          <Route element={<ProtectedRoute roles={['barber', 'admin']} />}>
+            <Route path="agenda" element={<BarberAgendaPage />} />
+          </Route>
+
+          {/* Protected Admin Routes, also for receptionists except stats and settings */}
+          <Route element={<ProtectedRoute roles={['admin', 'receptionist']} />}>
             <Route path="admin" element={<AdminLayout />}>
-              <Route index element={<AdminDashboard />} />
+              <Route element={<ProtectedRoute roles={['admin']} redirectTo="/admin/appointments" />}>
+                <Route index element={<AdminDashboard />} />
+                <Route path="settings" element={<AdminSettings />} />
+              </Route>
               <Route path="appointments" element={<AdminAppointments />} />
               <Route path="users" element={<AdminUsers />} />
               <Route path="calendar" element={<AdminCalendar />} />
-              <Route path="settings" element={<AdminSettings />} />
             </Route>
           </Route>
         </Route>
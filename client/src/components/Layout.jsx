@@ -1,8 +1,11 @@
 import React from 'react';
 import { Link, Outlet } from 'react-router-dom';
 import { Calendar, BarChart } from 'lucide-react';
+import api from '../services/api';
 
 const Layout = () => {
+    const role = JSON.parse(localStorage.getItem('user') || '{}').role;
+
     return (
         <div className="min-h-screen bg-white text-gray-900 font-sans">
             <nav className="bg-ton-black shadow-lg sticky top-0 z-50 border-b border-ton-wood">
@@ -26,7 +29,16 @@ const Layout = () => {
                                     <Calendar className="w-4 h-4 mr-2" />
                                     Reservar
                                 </Link>
-                                {localStorage.getItem('user') && (
+                                {(role === 'barber' || role === 'admin') && (
+                                    <Link
+                                        to="/agenda"
+                                        className="text-white hover:text-ton-wood inline-flex items-center px-3 py-2 text-sm font-medium transition-colors duration-200 border-b-2 border-transparent hover:border-ton-wood"
+                                    >
+                                        <Calendar className="w-4 h-4 mr-2" />
+                                        Mi agenda
+                                    </Link>
+                                )}
+                                {(role === 'admin' || role === 'receptionist') && (
                                     <Link
                                         to="/admin"
                                         className="text-white hover:text-ton-wood inline-flex items-center px-3 py-2 text-sm font-medium transition-colors duration-200 border-b-2 border-transparent hover:border-ton-wood"
@@ -43,9 +55,18 @@ const Layout = () => {
                                     <span className="text-sm text-gray-300">
                                         Hola, {JSON.parse(localStorage.getItem('user')).name}
                                     </span>
+                                    <Link to="/seguridad" className="text-sm text-gray-300 hover:text-white transition-colors duration-200">
+                                        Seguridad
+                                    </Link>
                                     <button
-                                        onClick={() => {
+                                        onClick={async () => {
+                                            const refreshToken = localStorage.getItem('refresh_token');
+                                            if (refreshToken) {
+                                                try { await api.post('/auth/logout', { refresh_token: refreshToken }); } catch (e) { }
+                                            }
                                             localStorage.removeItem('user');
+                                            localStorage.removeItem('token');
+                                            localStorage.removeItem('refresh_token');
                                             window.location.reload();
                                         }}
                                         className="bg-ton-wood hover:bg-ton-wood-dark text-white px-4 py-2 text-sm font-medium transition-colors duration-200"
@@ -1,7 +1,7 @@
 import React from 'react';
 import { Navigate, Outlet } from 'react-router-dom';
 
-const ProtectedRoute = ({ role }) => {
+const ProtectedRoute = ({ roles, redirectTo = "/" }) => {
     const userStr = localStorage.getItem('user');
     const user = userStr ? JSON.parse(userStr) : null;
     const token = localStorage.getItem('token');
@@ -10,9 +10,9 @@ const ProtectedRoute = ({ role }) => {
         return <Navigate to="/login" replace />;
     }
 
-    if (role && user.role !== role) {
-        // Redirect non-admins to home if they try to access admin routes
-        return <Navigate to="/" replace />;
+    if (roles && !roles.includes(user.role)) {
+        // Redirect users whose role cannot see these routes
+        return <Navigate to={redirectTo} replace />;
     }
 
     return <Outlet />;
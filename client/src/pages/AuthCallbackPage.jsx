@@ -0,0 +1,33 @@
+import React, { useEffect } from 'react';
+import { Link } from 'react-router-dom';
+
+// Landing page after logging in with Google: the API puts the session in the URL fragment
+const AuthCallbackPage = () => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get('token');
+
+    useEffect(() => {
+        if (!token) return;
+        localStorage.setItem('token', token);
+        localStorage.setItem('refresh_token', params.get('refresh_token'));
+        localStorage.setItem('user', params.get('user'));
+        window.location.replace('/'); // Refresh to update Auth state, dropping the tokens from history
+    }, [token]);
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full text-center">
+                {token ? (
+                    <p className="text-gray-600">Iniciando sesión...</p>
+                ) : (
+                    <p className="text-red-500">
+                        No pudimos iniciar sesión.{' '}
+                        <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">Volver</Link>
+                    </p>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default AuthCallbackPage;
@@ -0,0 +1,67 @@
+import React, { useEffect, useState } from 'react';
+import { format } from 'date-fns';
+import api from '../services/api';
+
+// Appointment times are the shop's wall clock stored as UTC: format in UTC to avoid timezone conversion
+const formatTime = (iso) => {
+    const d = new Date(iso);
+    return `${String(d.getUTCHours()).padStart(2, '0')}:${String(d.getUTCMinutes()).padStart(2, '0')}`;
+};
+
+const BarberAgendaPage = () => {
+    const [date, setDate] = useState(format(new Date(), 'yyyy-MM-dd'));
+    const [appointments, setAppointments] = useState([]);
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        const fetchAgenda = async () => {
+            try {
+                const res = await api.get(`/me/agenda?date=${date}`);
+                setAppointments(Array.isArray(res.data) ? res.data : []);
+                setError('');
+            } catch (err) {
+                setAppointments([]);
+                setError('No se pudo cargar tu agenda.');
+            }
+        };
+        fetchAgenda();
+    }, [date]);
+
+    return (
+        <div className="max-w-3xl mx-auto bg-white rounded-xl shadow-lg border border-gray-200 p-6">
+            <div className="flex items-center justify-between mb-6">
+                <h1 className="text-2xl font-bold text-ton-black">Mi agenda</h1>
+                <input
+                    type="date"
+                    value={date}
+                    onChange={(e) => setDate(e.target.value)}
+                    className="border border-gray-300 rounded-lg px-3 py-2 text-sm"
+                />
+            </div>
+
+            {error && <p className="text-red-600 text-sm mb-4">{error}</p>}
+
+            {appointments.length === 0 ? (
+                <p className="text-gray-500 text-sm">No tienes turnos asignados para este día.</p>
+            ) : (
+                <ul className="divide-y divide-gray-200">
+                    {appointments.map((a) => (
+                        <li key={a.id} className="py-3 flex items-center justify-between">
+                            <div>
+                                <p className="font-medium text-ton-black">{a.client?.name || 'Cliente'}</p>
+                                <p className="text-sm text-gray-500">{a.service || 'Sin servicio indicado'}</p>
+                                {a.notes && <p className="text-xs text-gray-400 mt-1">{a.notes}</p>}
+                            </div>
+                            <div className="text-right">
+                                <p className="font-semibold">{formatTime(a.start_time)}</p>
+                                <p className="text-xs uppercase text-gray-400">{a.status}</p>
+                            </div>
+                        </li>
+                    ))}
+                </ul>
+            )}
+        </div>
+    );
+};
+
+export default BarberAgendaPage;
@@ -16,6 +16,8 @@ const BookingPage = () => {
     const [bookingStatus, setBookingStatus] = useState('idle'); // idle, submitting, success, error
     const [bookedAppointment, setBookedAppointment] = useState(null);
     const [adminBookingUser, setAdminBookingUser] = useState(null);
+    const [challenge, setChallenge] = useState(null); // Guest bookings wait for the code sent by email or WhatsApp
+    const [code, setCode] = useState('');
 
     useEffect(() => {
         if (adminUserId) {
@@ -99,6 +101,11 @@ const BookingPage = () => {
             }
 
             const res = await api.post('/appointments', payload);
+            if (res.status === 202) {
+                setChallenge(res.data);
+                setBookingStatus('verify');
+                return;
+            }
             setBookedAppointment(res.data);
             setBookingStatus('success');
             fetchSlots(); // Refresh
@@ -111,6 +118,23 @@ const BookingPage = () => {
         }
     };
 
+    const handleConfirmCode = async (e) => {
+        e.preventDefault();
+        setBookingStatus('submitting');
+        try {
+            const res = await api.post('/appointments/guest/confirm', { challenge_id: challenge.id, code });
+            setBookedAppointment(res.data);
+            setBookingStatus('success');
+            setChallenge(null);
+            setCode('');
+            fetchSlots();
+            setSelectedSlot(null);
+        } catch (error) {
+            console.error('Code confirmation error:', error);
+            setBookingStatus('verify-error');
+        }
+    };
+
     return (
         <div className="max-w-3xl mx-auto">
             <div className="bg-white shadow-2xl overflow-hidden border-t-4 border-ton-wood">
@@ -208,8 +232,42 @@ const BookingPage = () => {
                                 )}
                             </div>
 
+                            {/* Guest code confirmation */}
+                            {challenge && (
+                                <div className="border-t border-gray-200 pt-6 mt-6">
+                                    <h3 className="text-lg font-medium text-gray-900 mb-2">Ingresá tu código</h3>
+                                    <p className="text-sm text-gray-600 mb-4">
+                                        Te enviamos un código {challenge.channel === 'whatsapp' ? 'por WhatsApp' : 'por email'} a {challenge.destination}.
+                                    </p>
+                                    {bookingStatus === 'verify-error' && (
+                                        <div className="mb-4 bg-red-50 border-l-4 border-red-600 text-red-800 px-4 py-3 text-sm">
+                                            El código es incorrecto o venció.
+                                        </div>
+                                    )}
+                                    <form onSubmit={handleConfirmCode} className="flex space-x-3">
+                                        <input
+                                            type="text"
+                                            inputMode="numeric"
+                                            autoComplete="one-time-code"
+                                            required
+                                            maxLength={6}
+                                            className="block w-full border-2 border-gray-300 rounded-md shadow-sm py-3 px-4 focus:ring-2 focus:ring-ton-wood focus:border-ton-wood tracking-widest font-bold"
+                                            value={code}
+                                            onChange={(e) => setCode(e.target.value)}
+                                        />
+                                        <button
+                                            type="submit"
+                                            disabled={bookingStatus === 'submitting'}
+                                            className="px-6 py-3 border-2 border-ton-black font-bold text-white bg-ton-black hover:bg-ton-gray disabled:opacity-70"
+                                        >
+                                            Confirmar
+                                        </button>
+                                    </form>
+                                </div>
+                            )}
+
                             {/* Booking Form */}
-                            {selectedSlot && (
+                            {selectedSlot && !challenge && (
                                 <div className="animate-fade-in-up">
                                     <div className="border-t border-gray-200 pt-6 mt-6">
                                         <h3 className="text-lg font-medium text-gray-900 mb-4">Confirmar Turno</h3>
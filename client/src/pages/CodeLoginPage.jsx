@@ -0,0 +1,118 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import api, { twoFactorPath } from '../services/api';
+
+const inputClass = "appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm";
+const buttonClass = "group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500";
+
+// Login without a password: a code by WhatsApp, or a link and code by email.
+// The emailed link opens this page with the challenge id and code already filled in.
+const CodeLoginPage = () => {
+    const [searchParams] = useSearchParams();
+    const [channel, setChannel] = useState('email');
+    const [destination, setDestination] = useState('');
+    const [challenge, setChallenge] = useState(null);
+    const [code, setCode] = useState('');
+    const [error, setError] = useState('');
+    const linkUsed = useRef(false);
+
+    const verify = async (challengeId, value) => {
+        setError('');
+        try {
+            const res = await api.post('/auth/login/code/verify', { challenge_id: challengeId, code: value });
+            if (res.data.two_factor) {
+                window.location.href = twoFactorPath(res.data.two_factor);
+                return;
+            }
+            localStorage.setItem('token', res.data.token);
+            localStorage.setItem('refresh_token', res.data.refresh_token);
+            localStorage.setItem('user', JSON.stringify(res.data.user));
+            window.location.href = '/'; // Refresh to update Auth state
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Código incorrecto');
+        }
+    };
+
+    useEffect(() => {
+        const id = searchParams.get('id');
+        const linkCode = searchParams.get('code');
+        if (id && linkCode && !linkUsed.current) {
+            linkUsed.current = true;
+            verify(id, linkCode);
+        }
+    }, [searchParams]);
+
+    const requestCode = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            const res = await api.post('/auth/login/code', { channel, destination });
+            setChallenge(res.data);
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Error enviando el código');
+        }
+    };
+
+    const submitCode = (e) => {
+        e.preventDefault();
+        verify(challenge.id, code);
+    };
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full space-y-8">
+                <div>
+                    <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+                        Ingresar sin contraseña
+                    </h2>
+                    <p className="mt-2 text-center text-sm text-gray-600">
+                        <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            Volver a iniciar sesión
+                        </Link>
+                    </p>
+                </div>
+                {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                {challenge ? (
+                    <form className="mt-8 space-y-6" onSubmit={submitCode}>
+                        <p className="text-center text-gray-700">
+                            Si {challenge.destination} tiene una cuenta, te enviamos un código
+                            {challenge.channel === 'email' ? ' y un link para ingresar' : ' por WhatsApp'}.
+                        </p>
+                        <input
+                            type="text"
+                            inputMode="numeric"
+                            autoComplete="one-time-code"
+                            required
+                            className={inputClass}
+                            placeholder="Código"
+                            value={code}
+                            onChange={(e) => setCode(e.target.value)}
+                        />
+                        <button type="submit" className={buttonClass}>Ingresar</button>
+                    </form>
+                ) : (
+                    <form className="mt-8 space-y-6" onSubmit={requestCode}>
+                        <div className="flex gap-4 justify-center text-sm">
+                            <label><input type="radio" checked={channel === 'email'} onChange={() => setChannel('email')} /> Email</label>
+                            <label><input type="radio" checked={channel === 'whatsapp'} onChange={() => setChannel('whatsapp')} /> WhatsApp</label>
+                        </div>
+                        <input
+                            type={channel === 'email' ? 'email' : 'tel'}
+                            autoComplete={channel === 'email' ? 'email' : 'tel'}
+                            required
+                            className={inputClass}
+                            placeholder={channel === 'email' ? 'Email' : 'Teléfono'}
+                            value={destination}
+                            onChange={(e) => setDestination(e.target.value)}
+                        />
+                        <button type="submit" className={buttonClass}>Enviar código</button>
+                    </form>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default CodeLoginPage;
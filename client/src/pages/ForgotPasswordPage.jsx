@@ -0,0 +1,64 @@
+import React, { useState } from 'react';
+import { Link } from 'react-router-dom';
+import api from '../services/api';
+
+const ForgotPasswordPage = () => {
+    const [email, setEmail] = useState('');
+    const [sent, setSent] = useState(false);
+    const [error, setError] = useState('');
+
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            await api.post('/auth/forgot-password', { email });
+            setSent(true);
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Error enviando el email');
+        }
+    };
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full space-y-8">
+                <div>
+                    <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+                        Recuperar Contraseña
+                    </h2>
+                    <p className="mt-2 text-center text-sm text-gray-600">
+                        <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            Volver a iniciar sesión
+                        </Link>
+                    </p>
+                </div>
+                {sent ? (
+                    <div className="text-center text-gray-700">
+                        Si el email tiene una cuenta, te enviamos un link para restablecer tu contraseña.
+                    </div>
+                ) : (
+                    <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+                        {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                        <input
+                            type="email"
+                            autoComplete="email"
+                            required
+                            className="appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm"
+                            placeholder="Email"
+                            value={email}
+                            onChange={(e) => setEmail(e.target.value)}
+                        />
+                        <button
+                            type="submit"
+                            className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500"
+                        >
+                            Enviar link
+                        </button>
+                    </form>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default ForgotPasswordPage;
@@ -1,20 +1,35 @@
 import React, { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
-import api from '../services/api';
+import { useNavigate, Link, useSearchParams } from 'react-router-dom';
+import api, { twoFactorPath } from '../services/api';
+
+const googleErrors = {
+    cancelled: 'Cancelaste el inicio de sesión con Google',
+    inactive: 'Esta cuenta fue desactivada, contacta a la barbería',
+    unverified: 'Tu email de Google no está verificado',
+    failed: 'No pudimos iniciar sesión con Google, intenta de nuevo',
+};
 
 const LoginPage = () => {
     const navigate = useNavigate();
     const [formData, setFormData] = useState({ email: '', password: '' });
-    const [error, setError] = useState('');
+    const [searchParams] = useSearchParams();
+    const [error, setError] = useState(googleErrors[searchParams.get('error')] || '');
+    const [unverified, setUnverified] = useState(false);
 
     const handleSubmit = async (e) => {
         e.preventDefault();
         setError('');
+        setUnverified(false);
         try {
             const res = await api.post('/auth/login', formData);
+            if (res.data.two_factor) {
+                window.location.href = twoFactorPath(res.data.two_factor);
+                return;
+            }
             // Store token and user data
             if (res.data.token) {
                 localStorage.setItem('token', res.data.token);
+                localStorage.setItem('refresh_token', res.data.refresh_token);
             }
             localStorage.setItem('user', JSON.stringify(res.data.user));
             window.location.href = '/'; // Refresh to update Auth state
@@ -22,6 +37,7 @@ const LoginPage = () => {
         } catch (err) {
             console.error(err);
             setError(err.response?.data?.error || 'Error iniciando sesión');
+            setUnverified(Boolean(err.response?.data?.unverified));
         }
     };
 
@@ -41,6 +57,13 @@ const LoginPage = () => {
                 </div>
                 <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
                     {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                    {unverified && (
+                        <div className="text-sm text-center">
+                            <Link to={`/verify-email/resend?email=${encodeURIComponent(formData.email)}`} className="font-medium text-indigo-600 hover:text-indigo-500">
+                                ¿No te llegó el email? Reenvíalo o verifica por WhatsApp
+                            </Link>
+                        </div>
+                    )}
                     <div className="rounded-md shadow-sm -space-y-px">
                         <div>
                             <label htmlFor="email-address" className="sr-only">Email</label>
@@ -72,6 +95,15 @@ const LoginPage = () => {
                         </div>
                     </div>
 
+                    <div className="text-sm flex justify-between">
+                        <Link to="/login/code" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            Ingresar sin contraseña
+                        </Link>
+                        <Link to="/forgot-password" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            ¿Olvidaste tu contraseña?
+                        </Link>
+                    </div>
+
                     <div>
                         <button
                             type="submit"
@@ -81,6 +113,13 @@ const LoginPage = () => {
                         </button>
                     </div>
                 </form>
+
+                <a
+                    href={`${api.defaults.baseURL}/auth/google`}
+                    className="w-full flex justify-center py-2 px-4 border border-gray-300 text-sm font-medium rounded-md text-gray-700 bg-white hover:bg-gray-50"
+                >
+                    Continuar con Google
+                </a>
             </div>
         </div>
     );
@@ -32,6 +32,12 @@ const RegisterPage = () => {
                     <p className="text-gray-500 text-sm">
                         Por favor, revisa tu bandeja de entrada (y spam) para activar tu cuenta y poder reservar.
                     </p>
+                    <p className="text-gray-500 text-sm">
+                        ¿No te llegó?{' '}
+                        <Link to={`/verify-email/resend?email=${encodeURIComponent(formData.email)}`} className="text-white underline hover:text-gray-300">
+                            Reenvíalo o verifica por WhatsApp
+                        </Link>
+                    </p>
                     <div className="pt-4">
                         <Link to="/login" className="text-white underline hover:text-gray-300">
                             Volver al inicio
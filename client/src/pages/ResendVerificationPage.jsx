@@ -0,0 +1,104 @@
+import React, { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import api from '../services/api';
+
+const inputClass = "appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm";
+const buttonClass = "group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500";
+
+// For users who lost the verification email: a new link, or a code by WhatsApp to the
+// phone they registered with.
+const ResendVerificationPage = () => {
+    const [searchParams] = useSearchParams();
+    const [channel, setChannel] = useState('email');
+    const [email, setEmail] = useState(searchParams.get('email') || '');
+    const [challenge, setChallenge] = useState(null);
+    const [code, setCode] = useState('');
+    const [message, setMessage] = useState('');
+    const [error, setError] = useState('');
+
+    const resend = async (e) => {
+        e.preventDefault();
+        setError('');
+        setMessage('');
+        try {
+            const res = await api.post('/auth/verify/resend', { email, channel });
+            if (channel === 'whatsapp') {
+                setChallenge(res.data);
+            } else {
+                setMessage('Si el email tiene una cuenta sin verificar, te enviamos un nuevo enlace. Revisa tu bandeja de entrada (y spam).');
+            }
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Error enviando la verificación');
+        }
+    };
+
+    const verifyCode = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            await api.post('/auth/verify/phone', { challenge_id: challenge.id, code });
+            setChallenge(null);
+            setMessage('¡Cuenta verificada! Ya puedes iniciar sesión.');
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Código incorrecto');
+        }
+    };
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full space-y-8">
+                <div>
+                    <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+                        Verifica tu cuenta
+                    </h2>
+                    <p className="mt-2 text-center text-sm text-gray-600">
+                        <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            Volver a iniciar sesión
+                        </Link>
+                    </p>
+                </div>
+                {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                {message && <div className="text-green-600 text-sm text-center">{message}</div>}
+                {challenge ? (
+                    <form className="mt-8 space-y-6" onSubmit={verifyCode}>
+                        <p className="text-center text-gray-700">
+                            Si el email tiene una cuenta sin verificar, te enviamos un código por WhatsApp al teléfono con el que te registraste.
+                        </p>
+                        <input
+                            type="text"
+                            inputMode="numeric"
+                            autoComplete="one-time-code"
+                            required
+                            className={inputClass}
+                            placeholder="Código"
+                            value={code}
+                            onChange={(e) => setCode(e.target.value)}
+                        />
+                        <button type="submit" className={buttonClass}>Verificar</button>
+                    </form>
+                ) : (
+                    <form className="mt-8 space-y-6" onSubmit={resend}>
+                        <div className="flex gap-4 justify-center text-sm">
+                            <label><input type="radio" checked={channel === 'email'} onChange={() => setChannel('email')} /> Nuevo enlace por email</label>
+                            <label><input type="radio" checked={channel === 'whatsapp'} onChange={() => setChannel('whatsapp')} /> Código por WhatsApp</label>
+                        </div>
+                        <input
+                            type="email"
+                            autoComplete="email"
+                            required
+                            className={inputClass}
+                            placeholder="Email con el que te registraste"
+                            value={email}
+                            onChange={(e) => setEmail(e.target.value)}
+                        />
+                        <button type="submit" className={buttonClass}>Enviar</button>
+                    </form>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default ResendVerificationPage;
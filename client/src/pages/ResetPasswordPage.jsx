@@ -0,0 +1,68 @@
+import React, { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import api from '../services/api';
+
+const ResetPasswordPage = () => {
+    const [searchParams] = useSearchParams();
+    const token = searchParams.get('token');
+    const [password, setPassword] = useState('');
+    const [done, setDone] = useState(false);
+    const [error, setError] = useState('');
+
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            await api.post('/auth/reset-password', { token, password });
+            // Every session was closed, including this browser's
+            localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
+            localStorage.removeItem('user');
+            setDone(true);
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Error restableciendo la contraseña');
+        }
+    };
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full space-y-8">
+                <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+                    Nueva Contraseña
+                </h2>
+                {done ? (
+                    <div className="text-center text-gray-700 space-y-4">
+                        <p>Tu contraseña fue actualizada.</p>
+                        <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">
+                            Iniciar sesión
+                        </Link>
+                    </div>
+                ) : (
+                    <form className="mt-8 space-y-6" onSubmit={handleSubmit}>
+                        {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                        <input
+                            type="password"
+                            autoComplete="new-password"
+                            required
+                            minLength={6}
+                            className="appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm"
+                            placeholder="Nueva contraseña"
+                            value={password}
+                            onChange={(e) => setPassword(e.target.value)}
+                        />
+                        <button
+                            type="submit"
+                            disabled={!token}
+                            className="group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500 disabled:opacity-50"
+                        >
+                            Guardar contraseña
+                        </button>
+                    </form>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default ResetPasswordPage;
@@ -0,0 +1,135 @@
+import React, { useEffect, useState } from 'react';
+import api from '../services/api';
+
+const inputClass = "appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm";
+const buttonClass = "group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500";
+const secondaryButtonClass = "w-full flex justify-center py-2 px-4 border border-gray-300 text-sm font-medium rounded-md text-gray-700 bg-white hover:bg-gray-50";
+
+// Two-factor authentication of the logged-in user: turn it on with an authenticator
+// app, get new recovery codes, or turn it off where the role allows it.
+const SecurityPage = () => {
+    const [status, setStatus] = useState(null);
+    const [enrollment, setEnrollment] = useState(null);
+    const [recoveryCodes, setRecoveryCodes] = useState(null);
+    const [code, setCode] = useState('');
+    const [message, setMessage] = useState('');
+    const [error, setError] = useState('');
+
+    const loadStatus = async () => {
+        try {
+            const res = await api.get('/me/2fa');
+            setStatus(res.data);
+        } catch (err) {
+            console.error(err);
+            setError('No pudimos cargar la configuración');
+        }
+    };
+
+    useEffect(() => { loadStatus(); }, []);
+
+    // run posts the code, shows the recovery codes it may return and reloads the status
+    const run = async (e, path, done) => {
+        e?.preventDefault();
+        setError('');
+        setMessage('');
+        try {
+            const res = await api.post(path, { code });
+            setCode('');
+            setEnrollment(null);
+            setRecoveryCodes(res.data.recovery_codes || null);
+            setMessage(done);
+            loadStatus();
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'Código incorrecto');
+        }
+    };
+
+    const enroll = async () => {
+        setError('');
+        setMessage('');
+        try {
+            const res = await api.post('/me/2fa/enroll');
+            setEnrollment(res.data);
+        } catch (err) {
+            console.error(err);
+            setError(err.response?.data?.error || 'No pudimos iniciar la configuración');
+        }
+    };
+
+    const codeInput = (
+        <input
+            type="text"
+            autoComplete="one-time-code"
+            required
+            className={inputClass}
+            placeholder="Código"
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+        />
+    );
+
+    return (
+        <div className="max-w-md mx-auto py-12 px-4 space-y-6">
+            <h2 className="text-3xl font-extrabold text-gray-900">Verificación en dos pasos</h2>
+            {error && <div className="text-red-500 text-sm">{error}</div>}
+            {message && <div className="text-green-600 text-sm">{message}</div>}
+
+            {recoveryCodes && (
+                <div className="space-y-2">
+                    <p className="text-gray-700">
+                        Guarda estos códigos de recuperación en un lugar seguro. Cada uno sirve una sola vez si pierdes el teléfono, y no los volveremos a mostrar.
+                    </p>
+                    <ul className="grid grid-cols-2 gap-2 font-mono text-sm text-gray-900 bg-gray-100 p-4 rounded-md">
+                        {recoveryCodes.map((c) => <li key={c}>{c}</li>)}
+                    </ul>
+                </div>
+            )}
+
+            {status && !status.enabled && !enrollment && (
+                <div className="space-y-4">
+                    <p className="text-gray-700">
+                        Protege tu cuenta pidiendo, además de la contraseña, un código de una app de autenticación (Google Authenticator, Authy, 1Password...).
+                    </p>
+                    <button type="button" onClick={enroll} className={buttonClass}>Activar</button>
+                </div>
+            )}
+
+            {enrollment && (
+                <form className="space-y-4" onSubmit={(e) => run(e, '/me/2fa/confirm', 'Verificación en dos pasos activada')}>
+                    <div className="space-y-2 text-sm text-gray-700">
+                        <p>
+                            Desde el teléfono, <a href={enrollment.uri} className="font-medium text-indigo-600 hover:text-indigo-500">abre este enlace</a> o
+                            agrega una cuenta en la app con esta clave:
+                        </p>
+                        <p className="font-mono text-gray-900 break-all bg-gray-100 p-2 rounded-md">{enrollment.secret}</p>
+                        <p>Luego ingresa el código de 6 dígitos que muestra la app.</p>
+                    </div>
+                    {codeInput}
+                    <button type="submit" className={buttonClass}>Confirmar</button>
+                </form>
+            )}
+
+            {status?.enabled && (
+                <form className="space-y-4" onSubmit={(e) => e.preventDefault()}>
+                    <p className="text-gray-700">
+                        Activada. Te quedan {status.recovery_codes_left} códigos de recuperación.
+                        {status.required && ' Tu rol no permite desactivarla.'}
+                    </p>
+                    <p className="text-sm text-gray-600">Ingresa un código de la app para generar nuevos códigos de recuperación{!status.required && ' o desactivarla'}.</p>
+                    {codeInput}
+                    <button type="button" onClick={() => run(null, '/me/2fa/recovery-codes', 'Generamos nuevos códigos; los anteriores ya no sirven')} className={buttonClass}>
+                        Generar nuevos códigos de recuperación
+                    </button>
+                    {!status.required && (
+                        <button type="button" onClick={() => run(null, '/me/2fa/disable', 'Verificación en dos pasos desactivada')} className={secondaryButtonClass}>
+                            Desactivar
+                        </button>
+                    )}
+                </form>
+            )}
+        </div>
+    );
+};
+
+export default SecurityPage;
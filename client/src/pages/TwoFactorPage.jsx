@@ -0,0 +1,144 @@
+import React, { useState } from 'react';
+import { Link } from 'react-router-dom';
+import api from '../services/api';
+
+const inputClass = "appearance-none rounded-md relative block w-full px-3 py-2 border border-gray-300 placeholder-gray-500 text-gray-900 focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 sm:text-sm";
+const buttonClass = "group relative w-full flex justify-center py-2 px-4 border border-transparent text-sm font-medium rounded-md text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500";
+
+const saveSession = (data) => {
+    localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
+    localStorage.setItem('user', JSON.stringify(data.user));
+};
+
+// Second step of a login for accounts with two-factor authentication. The challenge
+// comes in the URL fragment, from the login pages or the Google callback. Admins who
+// never set up an authenticator app do it here before getting in.
+const TwoFactorPage = () => {
+    const [params] = useState(() => new URLSearchParams(window.location.hash.slice(1)));
+    const challengeToken = params.get('challenge_token');
+    const mustEnroll = params.get('enrollment_required') === 'true';
+    const [enrollment, setEnrollment] = useState(null);
+    const [recoveryCodes, setRecoveryCodes] = useState(null);
+    const [session, setSession] = useState(null);
+    const [code, setCode] = useState('');
+    const [error, setError] = useState('');
+
+    const fail = (err, fallback) => {
+        console.error(err);
+        setError(err.response?.data?.error || fallback);
+    };
+
+    const verify = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            const res = await api.post('/auth/2fa/verify', { challenge_token: challengeToken, code });
+            saveSession(res.data);
+            window.location.replace('/'); // Refresh to update Auth state
+        } catch (err) {
+            fail(err, 'Código incorrecto');
+        }
+    };
+
+    const enroll = async () => {
+        setError('');
+        try {
+            const res = await api.post('/auth/2fa/enroll', { challenge_token: challengeToken });
+            setEnrollment(res.data);
+        } catch (err) {
+            fail(err, 'No pudimos iniciar la configuración');
+        }
+    };
+
+    const confirm = async (e) => {
+        e.preventDefault();
+        setError('');
+        try {
+            const res = await api.post('/auth/2fa/enroll/confirm', { challenge_token: challengeToken, code });
+            setSession(res.data);
+            setRecoveryCodes(res.data.recovery_codes);
+        } catch (err) {
+            fail(err, 'Código incorrecto');
+        }
+    };
+
+    const finish = () => {
+        saveSession(session);
+        window.location.replace('/');
+    };
+
+    let content;
+    if (!challengeToken) {
+        content = (
+            <p className="text-center text-red-500">
+                El inicio de sesión expiró.{' '}
+                <Link to="/login" className="font-medium text-indigo-600 hover:text-indigo-500">Volver</Link>
+            </p>
+        );
+    } else if (recoveryCodes) {
+        content = (
+            <div className="space-y-6">
+                <p className="text-gray-700">
+                    Guarda estos códigos de recuperación en un lugar seguro. Cada uno sirve una sola vez si pierdes el teléfono, y no los volveremos a mostrar.
+                </p>
+                <ul className="grid grid-cols-2 gap-2 font-mono text-sm text-gray-900 bg-gray-100 p-4 rounded-md">
+                    {recoveryCodes.map((c) => <li key={c}>{c}</li>)}
+                </ul>
+                <button type="button" onClick={finish} className={buttonClass}>Ya los guardé, continuar</button>
+            </div>
+        );
+    } else if (mustEnroll && !enrollment) {
+        content = (
+            <div className="space-y-6">
+                <p className="text-gray-700">
+                    Tu cuenta necesita verificación en dos pasos. Configura una app de autenticación (Google Authenticator, Authy, 1Password...) para continuar.
+                </p>
+                <button type="button" onClick={enroll} className={buttonClass}>Configurar</button>
+            </div>
+        );
+    } else {
+        content = (
+            <form className="space-y-6" onSubmit={enrollment ? confirm : verify}>
+                {enrollment ? (
+                    <div className="space-y-2 text-sm text-gray-700">
+                        <p>
+                            Desde el teléfono, <a href={enrollment.uri} className="font-medium text-indigo-600 hover:text-indigo-500">abre este enlace</a> o
+                            agrega una cuenta en la app con esta clave:
+                        </p>
+                        <p className="font-mono text-gray-900 break-all bg-gray-100 p-2 rounded-md">{enrollment.secret}</p>
+                        <p>Luego ingresa el código de 6 dígitos que muestra la app.</p>
+                    </div>
+                ) : (
+                    <p className="text-gray-700">
+                        Ingresa el código de tu app de autenticación, o uno de tus códigos de recuperación.
+                    </p>
+                )}
+                <input
+                    type="text"
+                    autoComplete="one-time-code"
+                    required
+                    className={inputClass}
+                    placeholder="Código"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                />
+                <button type="submit" className={buttonClass}>{enrollment ? 'Activar' : 'Verificar'}</button>
+            </form>
+        );
+    }
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
+            <div className="max-w-md w-full space-y-8">
+                <h2 className="mt-6 text-center text-3xl font-extrabold text-gray-900">
+                    Verificación en dos pasos
+                </h2>
+                {error && <div className="text-red-500 text-sm text-center">{error}</div>}
+                {content}
+            </div>
+        </div>
+    );
+};
+
+export default TwoFactorPage;
@@ -74,7 +74,10 @@ const VerifyEmailPage = () => {
                         <div className="text-red-500 text-5xl mb-4">✕</div>
                         <h2 className="text-xl font-semibold text-white">Error</h2>
                         <p className="text-gray-400">{message}</p>
-                        <div className="pt-4">
+                        <div className="pt-4 space-y-2">
+                            <Link to="/verify-email/resend" className="block text-white underline text-sm">
+                                Pedir un nuevo enlace
+                            </Link>
                             <Link to="/" className="text-gray-500 hover:text-white underline text-sm">
                                 Volver al inicio
                             </Link>
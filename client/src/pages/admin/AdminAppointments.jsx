@@ -221,7 +221,25 @@ const AdminAppointments = () => {
                                                 );
                                             })()}
 
-                                            {a.status === 'pending' && (
+                                            {a.status === 'pending' && a.requires_approval && (
+                                                <>
+                                                    <button
+                                                        onClick={async () => { try { await api.post(`/appointments/${a.id}/approve`); fetchAppointments(); } catch (e) { alert('Error al aprobar'); } }}
+                                                        className="inline-flex items-center px-4 py-1.5 bg-ton-black text-white rounded-md text-xs font-bold mr-2 hover:bg-ton-wood transition-colors shadow-sm"
+                                                    >
+                                                        <Check className="w-3 h-3 mr-1" />
+                                                        Aprobar
+                                                    </button>
+                                                    <button
+                                                        onClick={async () => { if (!confirm('¿Rechazar este turno?')) return; try { await api.post(`/appointments/${a.id}/reject`); fetchAppointments(); } catch (e) { alert('Error al rechazar'); } }}
+                                                        className="inline-flex items-center px-4 py-1.5 border border-red-200 text-red-600 rounded-md text-xs font-bold mr-2 hover:bg-red-50 transition-colors"
+                                                    >
+                                                        Rechazar
+                                                    </button>
+                                                </>
+                                            )}
+
+                                            {a.status === 'pending' && !a.requires_approval && (
                                                 <button
                                                     onClick={async () => { try { await api.post(`/appointments/${a.id}/confirm`); fetchAppointments(); } catch (e) { alert('Error al confirmar'); } }}
                                                     className="inline-flex items-center px-4 py-1.5 bg-ton-black text-white rounded-md text-xs font-bold mr-2 hover:bg-ton-wood transition-colors shadow-sm"
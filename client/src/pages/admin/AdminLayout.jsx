@@ -4,6 +4,8 @@ import { Grid, Users, Calendar, BarChart, Settings } from 'lucide-react';
 
 const AdminLayout = () => {
     const location = useLocation();
+    const user = JSON.parse(localStorage.getItem('user') || '{}');
+    const isAdmin = user.role === 'admin';
 
     const isActive = (path) => {
         if (path === '/admin' && location.pathname === '/admin') return true;
@@ -32,10 +34,12 @@ const AdminLayout = () => {
                     <h2 className="text-xs font-bold text-gray-400 uppercase tracking-wider">Menú Principal</h2>
                 </div>
                 <nav className="p-4 space-y-2">
-                    <Link to="/admin" className={navItemClass('/admin')}>
-                        <Grid className={iconClass('/admin')} />
-                        <span>Resumen</span>
-                    </Link>
+                    {isAdmin && (
+                        <Link to="/admin" className={navItemClass('/admin')}>
+                            <Grid className={iconClass('/admin')} />
+                            <span>Resumen</span>
+                        </Link>
+                    )}
                     <Link to="appointments" className={navItemClass('appointments')}>
                         <Calendar className={iconClass('appointments')} />
                         <span>Citas</span>
@@ -8,7 +8,7 @@ const AdminUsers = () => {
         const fetchUsers = async () => {
             try {
                 const res = await api.get('/users');
-                setUsers(res.data || []);
+                setUsers(res.data?.clients || []);
             } catch (err) {
                 console.error('Error cargando usuarios', err);
             }
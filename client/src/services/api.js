@@ -15,4 +15,47 @@ api.interceptors.request.use((config) => {
     return config;
 });
 
+// Access tokens are short-lived: on a 401, renew them once with the refresh token
+// and retry. Concurrent requests share the same refresh, since each refresh token
+// works only once.
+let refreshing = null;
+
+const refreshTokens = async () => {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) throw new Error('no refresh token');
+    const res = await axios.post(`${api.defaults.baseURL}/auth/refresh`, { refresh_token: refreshToken });
+    localStorage.setItem('token', res.data.token);
+    localStorage.setItem('refresh_token', res.data.refresh_token);
+    return res.data.token;
+};
+
+api.interceptors.response.use(
+    (response) => response,
+    async (error) => {
+        const original = error.config;
+        if (error.response?.status !== 401 || original._retried || !localStorage.getItem('refresh_token')) {
+            return Promise.reject(error);
+        }
+        original._retried = true;
+        try {
+            refreshing = refreshing || refreshTokens().finally(() => { refreshing = null; });
+            const token = await refreshing;
+            original.headers.Authorization = `Bearer ${token}`;
+            return api(original);
+        } catch (refreshError) {
+            localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
+            localStorage.removeItem('user');
+            return Promise.reject(error);
+        }
+    }
+);
+
+// Where a login continues when it needs a second factor. The challenge goes in the
+// fragment, like the Google callback does, so it never reaches a server log.
+export const twoFactorPath = (challenge) => '/login/2fa#' + new URLSearchParams({
+    challenge_token: challenge.challenge_token,
+    enrollment_required: String(challenge.enrollment_required),
+}).toString();
+
 export default api;
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// runImportClients implements `api import-clients [-dry-run] file.csv`.
+// It prints the JSON report and returns the process exit code.
+func runImportClients(svc ports.ImportService, args []string) int {
+	fs := flag.NewFlagSet("import-clients", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and report without creating clients")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api import-clients [-dry-run] file.csv")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	report, err := svc.ImportClients(context.Background(), f, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import failed:", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d created, %d duplicates, %d invalid\n", report.Created, report.Duplicates, report.Invalid)
+	return 0
+}
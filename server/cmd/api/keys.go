@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+// devJWTSecret signs tokens when no keys are configured outside production.
+const devJWTSecret = "your-secret-key-change-me-in-prod"
+
+// loadTokenIssuer uses the keys in JWT_KEYS_DIR, signing with JWT_SIGNING_KEY_ID.
+// Without keys it falls back to HS256 with JWT_SECRET, which production refuses.
+func loadTokenIssuer(production bool) (*services.TokenIssuer, error) {
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		return services.LoadTokenIssuer(dir, os.Getenv("JWT_SIGNING_KEY_ID"))
+	}
+	if production {
+		return nil, errors.New("JWT_KEYS_DIR must be set when APP_ENV=production (see `api generate-jwt-key`)")
+	}
+
+	log.Println("Tokens: JWT_KEYS_DIR not set. Signing with HS256 and JWT_SECRET, for development only.")
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = devJWTSecret
+	}
+	return services.NewHMACTokenIssuer(secret), nil
+}
+
+// runGenerateJWTKey implements `api generate-jwt-key [-rsa] dir`. It writes a new
+// private key named after today's date and prints its key id.
+func runGenerateJWTKey(args []string) int {
+	fs := flag.NewFlagSet("generate-jwt-key", flag.ContinueOnError)
+	useRSA := fs.Bool("rsa", false, "generate a 2048-bit RSA key (RS256) instead of Ed25519 (EdDSA)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api generate-jwt-key [-rsa] dir")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var key interface{}
+	var err error
+	if *useRSA {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	kid := time.Now().UTC().Format("20060102-150405")
+	path := filepath.Join(fs.Arg(0), kid+".pem")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(kid)
+	return 0
+}
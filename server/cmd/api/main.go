@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones must resolve in minimal containers too
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/google"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/handler"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/holidays"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/messaging"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/middleware"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/oidc"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 
 	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/repository"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
@@ -22,6 +29,11 @@ func main() {
 		log.Println("No .env file found, using system env vars")
 	}
 
+	// Key generation needs no database
+	if len(os.Args) > 1 && os.Args[1] == "generate-jwt-key" {
+		os.Exit(runGenerateJWTKey(os.Args[2:]))
+	}
+
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		dsn = "host=localhost user=postgres password=postgres dbname=barberia port=5432 sslmode=disable"
@@ -36,15 +48,91 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	availRepo := repository.NewAvailabilityRepository(db)
 	apptRepo := repository.NewAppointmentRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	waitlistRepo := repository.NewWaitlistRepository(db)
+	holdRepo := repository.NewSlotHoldRepository(db)
+	eventRepo := repository.NewAppointmentEventRepository(db)
+	transactor := repository.NewTransactor(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	noteRepo := repository.NewClientNoteRepository(db)
+	codeRepo := repository.NewOneTimeCodeRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	oidcLoginRepo := repository.NewOIDCLoginRepository(db)
+	identityRepo := repository.NewUserIdentityRepository(db)
+	twoFactorLoginRepo := repository.NewTwoFactorLoginRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	pendingRegistrationRepo := repository.NewPendingRegistrationRepository(db)
+
+	// CLI subcommands run against the same database and exit
+	importService := services.NewImportService(userRepo, noteRepo)
+	if len(os.Args) > 1 && os.Args[1] == "import-clients" {
+		os.Exit(runImportClients(importService, os.Args[2:]))
+	}
 
 	// Adapters
 	calendarAdapter := google.NewCalendarAdapter()
 	messagingAdapter := messaging.NewLoggingWhatsApp()
 
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+	timezone := os.Getenv("TIMEZONE")
+	if timezone == "" {
+		timezone = "America/Argentina/Buenos_Aires"
+	}
+	shopLocation, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Fatalf("Invalid TIMEZONE %q: %v", timezone, err)
+	}
+	production := os.Getenv("APP_ENV") == "production"
+	apiURL := os.Getenv("API_URL")
+	if apiURL == "" {
+		apiURL = "http://localhost:8080"
+	}
+
+	paymentAdapter, err := loadPaymentService(production, apiURL, frontendURL)
+	if err != nil {
+		log.Fatalf("Failed to set up payments: %v", err)
+	}
+
+	tokenIssuer, err := loadTokenIssuer(production)
+	if err != nil {
+		log.Fatalf("Failed to load token signing keys: %v", err)
+	}
+
+	rateLimitStore, err := loadRateLimitStore(db)
+	if err != nil {
+		log.Fatalf("Failed to set up rate limiting: %v", err)
+	}
+
+	holidayProvider, err := holidays.NewArgentinaProvider()
+	if err != nil {
+		log.Fatalf("Failed to load holidays: %v", err)
+	}
+
 	// Services
-	availService := services.NewAvailabilityService(availRepo, apptRepo)
-	apptService := services.NewAppointmentService(apptRepo, availRepo, userRepo, calendarAdapter, messagingAdapter)
-	statsService := services.NewStatsService(apptRepo)
+	policyService := services.NewPolicyService(userRepo, settingsRepo)
+	availService := services.NewAvailabilityService(availRepo, apptRepo, holdRepo, settingsRepo, holidayProvider)
+	apptService := services.NewAppointmentService(apptRepo, availRepo, holidayProvider, userRepo, holdRepo, eventRepo, transactor, settingsRepo, policyService, paymentAdapter, calendarAdapter, messagingAdapter)
+	statsService := services.NewStatsService(apptRepo, paymentRepo, analyticsRepo, shopLocation)
+	registerService := services.NewRegisterService(paymentRepo, apptRepo, eventRepo, transactor, shopLocation)
+	exportService := services.NewExportService(apptRepo, userRepo, shopLocation)
+	clientService := services.NewClientService(userRepo, apptRepo, noteRepo)
+	userService := services.NewUserService(userRepo)
+	sessionService := services.NewSessionService(sessionRepo, userRepo, tokenIssuer)
+	rateLimitService := services.NewRateLimitService(rateLimitStore)
+	go rateLimitService.RunSweeper(context.Background(), time.Minute)
+	go sessionService.RunSweeper(context.Background(), time.Hour)
+	twoFactorService := services.NewTwoFactorService(twoFactorLoginRepo, recoveryCodeRepo, userRepo, sessionService, rateLimitStore)
+	go twoFactorService.RunSweeper(context.Background(), time.Hour)
+	holdService := services.NewHoldService(holdRepo, availService, settingsRepo)
+	go holdService.RunSweeper(context.Background(), time.Minute)
+
+	waitlistService := services.NewWaitlistService(waitlistRepo, apptRepo, holdRepo, transactor, settingsRepo, availService, apptService, messagingAdapter, frontendURL, shopLocation)
+	apptService.SetWaitlist(waitlistService)
+	go waitlistService.RunSweeper(context.Background(), time.Minute)
 
 	// Email Service (Env vars or hardcoded for MVP/Plan)
 	// Ideally: os.Getenv("SMTP_HOST"), ...
@@ -56,17 +144,57 @@ func main() {
 		"no-reply@barberia-ayrton.com",
 	)
 
+	guestBookingService := services.NewGuestBookingService(codeRepo, apptService, emailService, messagingAdapter)
+	go guestBookingService.RunSweeper(context.Background(), time.Hour)
+
 	// User handler
-	userHandler := handler.NewUserHandler(userRepo)
-	authHandler := handler.NewAuthHandler(userRepo, emailService)
+	userHandler := handler.NewUserHandler(userRepo, policyService, userService)
+	passwordResetService := services.NewPasswordResetService(userRepo, emailService, frontendURL)
+	passwordlessService := services.NewPasswordlessLoginService(codeRepo, userRepo, twoFactorService, emailService, messagingAdapter, frontendURL)
+	verificationService := services.NewEmailVerificationService(userRepo, pendingRegistrationRepo, codeRepo, emailService, messagingAdapter, frontendURL)
+	go verificationService.RunSweeper(context.Background(), time.Hour)
+	authHandler := handler.NewAuthHandler(userRepo, verificationService, passwordResetService, sessionService, twoFactorService, passwordlessService, rateLimitService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	jwksHandler := handler.NewJWKSHandler(tokenIssuer)
+
+	// Login with Google, only when a client id is configured
+	var googleHandler *handler.OIDCHandler
+	if googleConfig, ok := googleOIDCConfig(apiURL); ok {
+		googleLogin := services.NewOIDCLoginService(oidc.NewProvider(googleConfig), oidcLoginRepo, identityRepo, userRepo, twoFactorService)
+		go googleLogin.RunSweeper(context.Background(), time.Hour)
+		googleHandler = handler.NewOIDCHandler(googleLogin, frontendURL, strings.HasPrefix(apiURL, "https://"))
+	} else {
+		log.Println("OIDC: No GOOGLE_CLIENT_ID provided. Login with Google is disabled.")
+	}
 
 	// Handlers
 	availHandler := handler.NewAvailabilityHandler(availService)
-	apptHandler := handler.NewAppointmentHandler(apptService)
+	apptHandler := handler.NewAppointmentHandler(apptService, guestBookingService)
 	statsHandler := handler.NewStatsHandler(statsService)
+	settingsHandler := handler.NewSettingsHandler(settingsRepo)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistService)
+	holdHandler := handler.NewHoldHandler(holdService)
+	paymentHandler := handler.NewPaymentHandler(paymentAdapter, apptService)
+	registerHandler := handler.NewRegisterHandler(registerService, shopLocation)
+	exportHandler := handler.NewExportHandler(exportService, shopLocation)
+	importHandler := handler.NewImportHandler(importService)
+	clientHandler := handler.NewClientHandler(clientService)
+
+	// Rate limits per route group, overridable with RATE_LIMIT_<GROUP>_IP and _ACCOUNT
+	authLimit := middleware.RateLimit(rateLimitService, "auth", rateLimits("auth", domain.RateLimits{
+		PerIP: domain.RateLimit{Requests: 20, Window: time.Minute},
+	}))
+	bookingLimit := middleware.RateLimit(rateLimitService, "booking", rateLimits("booking", domain.RateLimits{
+		PerIP:      domain.RateLimit{Requests: 10, Window: time.Minute},
+		PerAccount: domain.RateLimit{Requests: 10, Window: time.Minute},
+	}))
 
 	// Router
 	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS
 	config := cors.DefaultConfig()
@@ -74,32 +202,135 @@ func main() {
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type"}
 	r.Use(cors.New(config))
 
+	// Public keys that verify our access tokens
+	r.GET("/.well-known/jwks.json", jwksHandler.Get)
+
 	// Routes
 	api := r.Group("/api")
 	{
 		// Auth
-		api.POST("/auth/register", authHandler.Register)
-		api.POST("/auth/login", authHandler.Login)
-		api.GET("/auth/verify", authHandler.VerifyEmail)
-
-		// Admin Routes (Protected)
-		admin := api.Group("/")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		auth := api.Group("/auth")
+		auth.Use(authLimit)
 		{
-			// Admin Stats
-			admin.GET("/admin/stats", statsHandler.GetDashboardStats)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.GET("/verify", authHandler.VerifyEmail)
+			auth.POST("/verify/resend", authHandler.ResendVerification)
+			auth.POST("/verify/phone", authHandler.VerifyPhone)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/login/code", authHandler.RequestLoginCode)
+			auth.POST("/login/code/verify", authHandler.VerifyLoginCode)
+			auth.POST("/2fa/verify", twoFactorHandler.Verify)
+			auth.POST("/2fa/enroll", twoFactorHandler.Enroll)
+			auth.POST("/2fa/enroll/confirm", twoFactorHandler.ConfirmEnrollment)
+			if googleHandler != nil {
+				auth.GET("/google", googleHandler.Begin)
+				auth.GET("/google/callback", googleHandler.Callback)
+			}
+		}
 
-			// Availability Management
-			admin.POST("/availability", availHandler.SetAvailability)
-			admin.DELETE("/availability/:id", availHandler.DeleteAvailability)
+		// Public booking
+		api.GET("/slots", availHandler.GetSlots)
+		api.POST("/holds", bookingLimit, holdHandler.Create)
+		api.DELETE("/holds/:token", holdHandler.Release)
+		api.POST("/appointments", middleware.OptionalAuthMiddleware(tokenIssuer, sessionService), bookingLimit, apptHandler.Create)
+		api.POST("/appointments/guest/confirm", bookingLimit, apptHandler.ConfirmGuest)
 
-			// Appointment Management
-			admin.GET("/appointments", apptHandler.List)
+		// Payment provider webhooks
+		api.POST("/payments/webhook", paymentHandler.Webhook)
 
-			// User Management
-			admin.GET("/users", userHandler.List)
-			admin.GET("/users/:id", userHandler.Get)
+		// Waitlist
+		api.POST("/waitlist", bookingLimit, waitlistHandler.Join)
+		api.POST("/waitlist/claim", bookingLimit, waitlistHandler.Claim)
+
+		// Client Routes (Protected)
+		me := api.Group("/me")
+		me.Use(middleware.AuthMiddleware(tokenIssuer, sessionService))
+		{
+			me.POST("/appointments/:id/cancel", apptHandler.CancelOwn)
+			me.POST("/logout-all", authHandler.LogoutAll)
+			me.GET("/2fa", twoFactorHandler.Status)
+			me.POST("/2fa/enroll", twoFactorHandler.EnrollSelf)
+			me.POST("/2fa/confirm", twoFactorHandler.ConfirmSelf)
+			me.POST("/2fa/recovery-codes", twoFactorHandler.RecoveryCodes)
+			me.POST("/2fa/disable", twoFactorHandler.Disable)
+			me.GET("/agenda", middleware.RequirePermission(domain.PermViewOwnAgenda), apptHandler.Agenda)
 		}
+
+		// Staff Routes (Protected), grouped by the permission they need
+		staff := func(p domain.Permission) *gin.RouterGroup {
+			return api.Group("/", middleware.AuthMiddleware(tokenIssuer, sessionService), middleware.RequirePermission(p))
+		}
+
+		// Admin Stats
+		stats := staff(domain.PermViewStats)
+		stats.GET("/admin/stats", statsHandler.GetDashboardStats)
+		stats.GET("/admin/analytics", statsHandler.GetAnalytics)
+
+		// Settings and availability management
+		settings := staff(domain.PermManageSettings)
+		settings.GET("/admin/settings", settingsHandler.Get)
+		settings.POST("/admin/settings", settingsHandler.Update)
+		settings.POST("/availability", availHandler.SetAvailability)
+		settings.DELETE("/availability/:id", availHandler.DeleteAvailability)
+		settings.GET("/admin/holidays", availHandler.ListHolidays)
+
+		// Appointment Management
+		agenda := staff(domain.PermManageAppointments)
+		agenda.GET("/appointments", apptHandler.List)
+		agenda.POST("/appointments/:id/confirm", apptHandler.Confirm)
+		agenda.POST("/appointments/:id/approve", apptHandler.Approve)
+		agenda.POST("/appointments/:id/reject", apptHandler.Reject)
+		agenda.POST("/appointments/:id/cancel", apptHandler.Cancel)
+		agenda.POST("/appointments/:id/complete", apptHandler.Complete)
+		agenda.POST("/appointments/:id/no-show", apptHandler.NoShow)
+		agenda.POST("/appointments/:id/reschedule", apptHandler.Reschedule)
+		agenda.PATCH("/appointments/:id/notes", apptHandler.UpdateNotes)
+		agenda.GET("/appointments/:id/history", apptHandler.History)
+		agenda.PUT("/appointments/:id/barber", apptHandler.AssignBarber)
+		agenda.GET("/admin/waitlist", waitlistHandler.List)
+		agenda.GET("/staff", userHandler.Staff)
+
+		// Point of sale
+		register := staff(domain.PermUseRegister)
+		register.POST("/appointments/:id/payments", registerHandler.RecordPayment)
+		register.GET("/appointments/:id/payments", registerHandler.ListPayments)
+		register.GET("/admin/register/close", registerHandler.CloseDay)
+
+		// Exports for the accountant
+		data := staff(domain.PermExportData)
+		data.GET("/admin/export/appointments", exportHandler.Appointments)
+		data.GET("/admin/export/clients", exportHandler.Clients)
+		data.POST("/admin/import/clients", importHandler.Clients)
+
+		// Client records
+		clients := staff(domain.PermManageClients)
+		clients.GET("/users", userHandler.List)
+		clients.GET("/users/:id", userHandler.Get)
+		clients.POST("/users", userHandler.Create)
+		clients.PATCH("/users/:id", userHandler.Update)
+		clients.POST("/users/:id/clear-block", userHandler.ClearBookingBlock)
+		clients.GET("/users/:id/profile", clientHandler.Profile)
+
+		// Private notes, kept to admins
+		private := staff(domain.PermManageClientsPrivate)
+		private.POST("/users/:id/notes", clientHandler.AddNote)
+		private.PATCH("/users/:id/notes/:noteId", clientHandler.UpdateNote)
+		private.DELETE("/users/:id/notes/:noteId", clientHandler.DeleteNote)
+
+		// User Management
+		users := staff(domain.PermManageUsers)
+		users.GET("/admin/roles", userHandler.Roles)
+		users.PUT("/users/:id/role", userHandler.SetRole)
+		users.POST("/users/:id/deactivate", userHandler.Deactivate)
+		users.POST("/users/:id/reactivate", userHandler.Reactivate)
+		users.POST("/users/:id/merge", userHandler.Merge)
+		users.GET("/users/:id/sessions", sessionHandler.List)
+		users.DELETE("/users/:id/sessions/:sessionId", sessionHandler.Revoke)
+		users.DELETE("/users/:id/sessions", sessionHandler.RevokeAll)
 	}
 
 	port := os.Getenv("PORT")
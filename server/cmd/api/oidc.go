@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/oidc"
+)
+
+// googleOIDCConfig reads the Google login settings. ok is false when no client id is
+// set, and the GOOGLE_*_URL and GOOGLE_ISSUER overrides point it at a local fake.
+func googleOIDCConfig(apiURL string) (config oidc.Config, ok bool) {
+	clientID := os.Getenv("GOOGLE_CLIENT_ID")
+	if clientID == "" {
+		return oidc.Config{}, false
+	}
+	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = apiURL + "/api/auth/google/callback"
+	}
+
+	config = oidc.Google(clientID, os.Getenv("GOOGLE_CLIENT_SECRET"), redirectURL)
+	for env, field := range map[string]*string{
+		"GOOGLE_ISSUER":    &config.Issuer,
+		"GOOGLE_AUTH_URL":  &config.AuthURL,
+		"GOOGLE_TOKEN_URL": &config.TokenURL,
+		"GOOGLE_JWKS_URL":  &config.JWKSURL,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+	return config, true
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/payment"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// loadPaymentService returns Mercado Pago when configured. The in-memory fake trusts
+// any webhook body, so it is only used outside production.
+func loadPaymentService(production bool, apiURL, frontendURL string) (ports.PaymentService, error) {
+	token := os.Getenv("MERCADOPAGO_ACCESS_TOKEN")
+	secret := os.Getenv("MERCADOPAGO_WEBHOOK_SECRET")
+	if token != "" {
+		if secret == "" {
+			return nil, errors.New("MERCADOPAGO_WEBHOOK_SECRET must be set with MERCADOPAGO_ACCESS_TOKEN, webhooks are only accepted signed")
+		}
+		return payment.NewMercadoPago(token, secret, apiURL+"/api/payments/webhook", frontendURL+"/booking/payment"), nil
+	}
+	if production {
+		return nil, errors.New("MERCADOPAGO_ACCESS_TOKEN and MERCADOPAGO_WEBHOOK_SECRET must be set when APP_ENV=production")
+	}
+
+	log.Println("PaymentService: No Mercado Pago token provided. Using in-memory fake payments, for development only.")
+	return payment.NewFake(frontendURL + "/booking/payment"), nil
+}
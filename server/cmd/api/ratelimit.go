@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/ratelimit"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/repository"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+// loadRateLimitStore picks where counters live from RATE_LIMIT_BACKEND: "memory" (the
+// default, per instance) or "postgres" (shared by every instance).
+func loadRateLimitStore(db *gorm.DB) (ports.RateLimitStore, error) {
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		return ratelimit.NewMemory(), nil
+	case "postgres":
+		return repository.NewRateLimitRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q, use memory or postgres", backend)
+	}
+}
+
+// rateLimits reads the limits of a route group from RATE_LIMIT_<GROUP>_IP and
+// RATE_LIMIT_<GROUP>_ACCOUNT, falling back to defaults for the ones not set.
+func rateLimits(group string, defaults domain.RateLimits) domain.RateLimits {
+	limits := defaults
+	for suffix, limit := range map[string]*domain.RateLimit{"_IP": &limits.PerIP, "_ACCOUNT": &limits.PerAccount} {
+		env := "RATE_LIMIT_" + strings.ToUpper(group) + suffix
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseRateLimit(value)
+		if err != nil {
+			log.Fatalf("Invalid %s %q: %v", env, value, err)
+		}
+		*limit = parsed
+	}
+	return limits
+}
+
+// parseRateLimit reads "<requests>/<window>", e.g. "10/1m". "0" disables the limit.
+func parseRateLimit(value string) (domain.RateLimit, error) {
+	if value == "0" {
+		return domain.RateLimit{}, nil
+	}
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return domain.RateLimit{}, fmt.Errorf("expected <requests>/<window>")
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid number of requests")
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid window")
+	}
+	return domain.RateLimit{Requests: n, Window: d}, nil
+}
+
+// trustedProxies reads TRUSTED_PROXIES, a comma-separated list of IPs or CIDRs whose
+// X-Forwarded-For is believed. With none, the client IP is the connection's address,
+// so it cannot be spoofed to get around per-IP limits.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
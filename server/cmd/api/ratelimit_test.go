@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	valid := map[string]domain.RateLimit{
+		"0":       {},
+		"10/1m":   {Requests: 10, Window: time.Minute},
+		"0/1h":    {Requests: 0, Window: time.Hour},
+		"3/1h30m": {Requests: 3, Window: 90 * time.Minute},
+	}
+	for value, want := range valid {
+		got, err := parseRateLimit(value)
+		if err != nil || got != want {
+			t.Errorf("%q: expected %+v, got %+v, %v", value, want, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "10", "ten/1m", "-1/1m", "10/", "10/soon", "10/0s", "10/-1m"} {
+		if _, err := parseRateLimit(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestRateLimits_EnvOverridesDefaults(t *testing.T) {
+	defaults := domain.RateLimits{
+		PerIP:      domain.RateLimit{Requests: 10, Window: time.Minute},
+		PerAccount: domain.RateLimit{Requests: 5, Window: time.Minute},
+	}
+	t.Setenv("RATE_LIMIT_AUTH_IP", "20/1h")
+
+	limits := rateLimits("auth", defaults)
+	if limits.PerIP != (domain.RateLimit{Requests: 20, Window: time.Hour}) || limits.PerAccount != defaults.PerAccount {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+}
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// utf8BOM makes Excel open the file as UTF-8 instead of the system codepage,
+// which would garble accents and ñ.
+const utf8BOM = "\ufeff"
+
+// The file is for Excel set up in Spanish, which splits columns on semicolons and
+// reads commas as the decimal separator.
+const (
+	separator        = ';'
+	decimalSeparator = ","
+)
+
+// formulaStarts are the characters a spreadsheet reads as the start of a formula.
+const formulaStarts = "=+-@\t\r"
+
+type CSVWriter struct {
+	out     io.Writer
+	w       *csv.Writer
+	started bool
+}
+
+func NewCSVWriter(w io.Writer) ports.SheetWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = separator
+	return &CSVWriter{out: w, w: cw}
+}
+
+func (c *CSVWriter) WriteRow(cells ...interface{}) error {
+	if !c.started {
+		c.started = true
+		if _, err := io.WriteString(c.out, utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case string:
+			record[i] = escapeFormula(v)
+		case float64:
+			record[i] = strings.Replace(formatCell(v), ".", decimalSeparator, 1)
+		default:
+			record[i] = formatCell(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula keeps text, e.g. a name a client typed as "=HYPERLINK(...)", from being
+// run as a formula, by starting it with a quote as Excel does for text.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune(formulaStarts, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		if v {
+			return "sí"
+		}
+		return "no"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,29 +1,38 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+	"gorm.io/gorm"
 )
 
 type AppointmentHandler struct {
-	svc ports.AppointmentService
+	svc    ports.AppointmentService
+	guests ports.GuestBookingService
 }
 
-func NewAppointmentHandler(svc ports.AppointmentService) *AppointmentHandler {
-	return &AppointmentHandler{svc: svc}
+func NewAppointmentHandler(svc ports.AppointmentService, guests ports.GuestBookingService) *AppointmentHandler {
+	return &AppointmentHandler{svc: svc, guests: guests}
 }
 
 type CreateAppointmentRequest struct {
-	ClientID  string    `json:"client_id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
-	StartTime time.Time `json:"start_time" binding:"required"`
-	Notes     string    `json:"notes"`
+	ClientID  string     `json:"client_id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Phone     string     `json:"phone"`
+	StartTime time.Time  `json:"start_time" binding:"required"`
+	Service   string     `json:"service"`
+	Notes     string     `json:"notes"`
+	HoldToken string     `json:"hold_token"` // Returned by POST /holds
+	VerifyVia string     `json:"verify_via"` // Guests only: "email" or "whatsapp"
+	BarberID  *uuid.UUID `json:"barber_id"`  // Staff only
 }
 
 func (h *AppointmentHandler) Create(c *gin.Context) {
@@ -33,31 +42,93 @@ func (h *AppointmentHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// If ClientID provided, prefer creating appointment for that client
+	booking := domain.BookingRequest{
+		ClientName:  req.Name,
+		ClientEmail: req.Email,
+		ClientPhone: req.Phone,
+		StartTime:   req.StartTime,
+		Service:     req.Service,
+		Notes:       req.Notes,
+		HoldToken:   req.HoldToken,
+		BarberID:    req.BarberID,
+	}
+
+	// Logged-in clients book for themselves; staff managing the agenda for any client,
+	// and only they choose the barber
+	actor := domain.ActorFromContext(c.Request.Context())
+	if req.BarberID != nil && !actor.Can(domain.PermManageAppointments) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only staff can choose the barber"})
+		return
+	}
 	if req.ClientID != "" {
 		cid, err := uuid.Parse(req.ClientID)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client_id"})
 			return
 		}
-		appt, err := h.svc.CreateAppointmentForClient(c.Request.Context(), cid, req.StartTime, req.Notes)
+		if actor == nil || (!actor.Can(domain.PermManageAppointments) && actor.ID != cid) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you can only book for your own account"})
+			return
+		}
+		appt, err := h.svc.CreateAppointmentForClient(c.Request.Context(), cid, booking)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCreateError(c, err)
 			return
 		}
 		c.JSON(http.StatusCreated, appt)
 		return
 	}
 
-	appt, err := h.svc.CreateAppointment(c.Request.Context(), req.Name, req.Email, req.Phone, req.StartTime, req.Notes)
+	// Guests get a code by email or WhatsApp and confirm with ConfirmGuest
+	challenge, err := h.guests.Start(c.Request.Context(), booking, domain.ContactChannel(req.VerifyVia))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCreateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, challenge)
+}
+
+type ConfirmGuestBookingRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" binding:"required"`
+	Code        string    `json:"code" binding:"required"`
+	HoldToken   string    `json:"hold_token"`
+}
+
+// ConfirmGuest books a guest appointment with the code sent by Create.
+func (h *AppointmentHandler) ConfirmGuest(c *gin.Context) {
+	var req ConfirmGuestBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appt, err := h.guests.Confirm(c.Request.Context(), req.ChallengeID, req.Code, req.HoldToken)
+	if err != nil {
+		respondCreateError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, appt)
 }
 
+func respondCreateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSlotHeld), errors.Is(err, services.ErrSlotUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidGuestBooking), errors.Is(err, services.ErrNotABarber):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookingBlocked), errors.Is(err, services.ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCode), errors.Is(err, services.ErrCodeExpired), errors.Is(err, services.ErrUnverifiedGuest):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyCodes):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func (h *AppointmentHandler) ValidateConcurrency(c *gin.Context) {
 	// Middleware or separate check if needed for complex locking,
 	// currently handled by Service/Repo constraints.
@@ -65,22 +136,57 @@ func (h *AppointmentHandler) ValidateConcurrency(c *gin.Context) {
 
 // List returns appointments for a given date range or presets (date/month/today)
 func (h *AppointmentHandler) List(c *gin.Context) {
-	// Support query params: start,end (RFC3339) OR date=YYYY-MM-DD OR month=YYYY-MM
-	var start time.Time
-	var end time.Time
-	var err error
+	start, end, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	appts, err := h.svc.ListAppointments(c.Request.Context(), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appts)
+}
+
+// Agenda returns the appointments assigned to the logged-in barber, for the same
+// ranges as List.
+func (h *AppointmentHandler) Agenda(c *gin.Context) {
+	start, end, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	barberID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+		return
+	}
+
+	appts, err := h.svc.ListBarberAgenda(c.Request.Context(), barberID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appts)
+}
 
+// dateRange reads start,end (RFC3339), date=YYYY-MM-DD or month=YYYY-MM, defaulting to
+// today. On a bad value it responds 400 and returns false.
+func dateRange(c *gin.Context) (start, end time.Time, ok bool) {
+	var err error
 	if s := c.Query("start"); s != "" {
 		start, err = time.Parse(time.RFC3339, s)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start format, use RFC3339"})
-			return
+			return start, end, false
 		}
 		if e := c.Query("end"); e != "" {
 			end, err = time.Parse(time.RFC3339, e)
 			if err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end format, use RFC3339"})
-				return
+				return start, end, false
 			}
 		} else {
 			end = start.Add(24 * time.Hour)
@@ -89,7 +195,7 @@ func (h *AppointmentHandler) List(c *gin.Context) {
 		d, err := time.Parse("2006-01-02", date)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
-			return
+			return start, end, false
 		}
 		start = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
 		end = start.Add(24 * time.Hour)
@@ -98,7 +204,7 @@ func (h *AppointmentHandler) List(c *gin.Context) {
 		t, err := time.Parse("2006-01", month)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month format, use YYYY-MM"})
-			return
+			return start, end, false
 		}
 		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
 		end = start.AddDate(0, 1, 0)
@@ -109,13 +215,7 @@ func (h *AppointmentHandler) List(c *gin.Context) {
 		end = start.Add(24 * time.Hour)
 	}
 
-	appts, err := h.svc.ListAppointments(c.Request.Context(), start, end)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, appts)
+	return start, end, true
 }
 
 func (h *AppointmentHandler) Confirm(c *gin.Context) {
@@ -127,13 +227,56 @@ func (h *AppointmentHandler) Confirm(c *gin.Context) {
 	}
 
 	if err := h.svc.ConfirmAppointment(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondStatusError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "confirmed"})
 }
 
+func (h *AppointmentHandler) Approve(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	if err := h.svc.ApproveAppointment(c.Request.Context(), id); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "approved"})
+}
+
+type RejectRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *AppointmentHandler) Reject(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	// The body is optional, like for Cancel
+	var req RejectRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.svc.RejectAppointment(c.Request.Context(), id, req.Note); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "rejected"})
+}
+
 func (h *AppointmentHandler) Cancel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -142,10 +285,196 @@ func (h *AppointmentHandler) Cancel(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.CancelAppointment(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// The body is optional: the admin panel's quick cancel button sends none
+	cancellation := domain.Cancellation{By: domain.CancelledByShop, Reason: domain.ReasonOther}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&cancellation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.svc.CancelAppointment(c.Request.Context(), id, cancellation); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
+}
+
+type CancelOwnRequest struct {
+	Reason domain.CancellationReason `json:"reason" binding:"required"`
+	Note   string                    `json:"note"`
+}
+
+// CancelOwn lets a logged-in client cancel one of their own appointments.
+func (h *AppointmentHandler) CancelOwn(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	var req CancelOwnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	if err := h.svc.CancelOwnAppointment(c.Request.Context(), actor.ID, id, req.Reason, req.Note); err != nil {
+		respondStatusError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
 }
+
+func (h *AppointmentHandler) Complete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	if err := h.svc.CompleteAppointment(c.Request.Context(), id); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "completed"})
+}
+
+func (h *AppointmentHandler) NoShow(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	if err := h.svc.MarkNoShow(c.Request.Context(), id); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "no_show"})
+}
+
+type RescheduleRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+}
+
+func (h *AppointmentHandler) Reschedule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	var req RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appt, err := h.svc.RescheduleAppointment(c.Request.Context(), id, req.StartTime)
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appt)
+}
+
+type UpdateNotesRequest struct {
+	Notes string `json:"notes"`
+}
+
+func (h *AppointmentHandler) UpdateNotes(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	var req UpdateNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.UpdateNotes(c.Request.Context(), id, req.Notes); err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notes": req.Notes})
+}
+
+// History returns the appointment's audit trail, oldest event first.
+func (h *AppointmentHandler) History(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	events, err := h.svc.GetHistory(c.Request.Context(), id)
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+type AssignBarberRequest struct {
+	BarberID *uuid.UUID `json:"barber_id"` // null unassigns the appointment
+}
+
+func (h *AppointmentHandler) AssignBarber(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	var req AssignBarberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appt, err := h.svc.AssignBarber(c.Request.Context(), id, req.BarberID)
+	if err != nil {
+		respondStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appt)
+}
+
+func respondStatusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "appointment not found"})
+	case errors.Is(err, services.ErrNotABarber):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrSlotHeld), errors.Is(err, services.ErrDepositPending),
+		errors.Is(err, services.ErrApprovalPending), errors.Is(err, services.ErrNoApprovalPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookingBlocked), errors.Is(err, services.ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCode), errors.Is(err, services.ErrCodeExpired), errors.Is(err, services.ErrUnverifiedGuest):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyCodes):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,26 +1,35 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log"
+	"math"
 	"net/http"
-
-	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
 	userRepo     ports.UserRepository
-	emailService ports.EmailService
+	verification ports.EmailVerificationService
+	resets       ports.PasswordResetService
+	sessions     ports.SessionService
+	twoFactor    ports.TwoFactorService
+	passwordless ports.PasswordlessLoginService
+	limiter      ports.RateLimitService
 }
 
-func NewAuthHandler(userRepo ports.UserRepository, emailService ports.EmailService) *AuthHandler {
-	return &AuthHandler{userRepo: userRepo, emailService: emailService}
+func NewAuthHandler(userRepo ports.UserRepository, verification ports.EmailVerificationService, resets ports.PasswordResetService, sessions ports.SessionService, twoFactor ports.TwoFactorService, passwordless ports.PasswordlessLoginService, limiter ports.RateLimitService) *AuthHandler {
+	return &AuthHandler{userRepo: userRepo, verification: verification, resets: resets, sessions: sessions, twoFactor: twoFactor, passwordless: passwordless, limiter: limiter}
 }
 
 type RegisterRequest struct {
@@ -37,9 +46,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Check if user exists
+	// Check if user exists. A guest with this email becomes the new account once the
+	// email is verified, keeping the bookings made as a guest.
 	existing, _ := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
-	if existing != nil {
+	if existing != nil && !existing.IsGuest {
 		c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
 		return
 	}
@@ -50,34 +60,42 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	token := uuid.New().String()
-
 	user := &domain.User{
-		Name:              req.Name,
-		Email:             req.Email,
-		Phone:             req.Phone,
-		Password:          string(hashedBytes),
-		Role:              domain.RoleClient,
-		VerificationToken: token,
-		IsVerified:        false,
+		Name:       req.Name,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Password:   string(hashedBytes),
+		Role:       domain.RoleClient,
+		IsVerified: false,
+	}
+
+	// The guest is only updated once the link proves the registration owns the email
+	if existing != nil {
+		registration := domain.PendingRegistration{Name: user.Name, Phone: user.Phone, Password: user.Password}
+		go func(guest domain.User) {
+			if err := h.verification.ClaimGuest(context.Background(), &guest, registration); err != nil {
+				log.Printf("Failed to send verification email: %v", err)
+			}
+		}(*existing)
+		c.JSON(http.StatusCreated, user)
+		return
 	}
 
 	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate verification link (hardcoded frontend URL for now, ideally env var)
-	// Assuming frontend runs on localhost:5173 for dev
-	link := "http://localhost:5173/verify-email?token=" + token
-
 	// Send email asynchronously to not block response
-	go func() {
-		if err := h.emailService.SendVerificationEmail(user.Email, user.Name, link); err != nil {
-			// Log error (should have a logger)
-			println("Failed to send verification email:", err.Error())
+	go func(user domain.User) {
+		if err := h.verification.SendLink(context.Background(), &user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
 		}
-	}()
+	}(*user)
 
 	c.JSON(http.StatusCreated, user)
 }
@@ -89,24 +107,81 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	// This is inefficient, but for MVP it works. Ideally look up by token index.
-	// Since we don't have GetByToken in repo yet, we might need to add it or iterate (bad).
-	// Let's add GetByVerificationToken to UserRepository first.
-	user, err := h.userRepo.GetByVerificationToken(c.Request.Context(), token)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+	if _, err := h.verification.VerifyEmail(c.Request.Context(), token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+type ResendVerificationRequest struct {
+	Email   string                `json:"email" binding:"required,email"`
+	Channel domain.ContactChannel `json:"channel"` // "email" (default) or "whatsapp"
+}
+
+// ResendVerification sends a new verification link, or a WhatsApp code to the phone on
+// the account. It answers the same whether or not the email has an unverified account.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user.IsVerified = true
-	user.VerificationToken = "" // Clear token
+	switch req.Channel {
+	case "", domain.ChannelEmail:
+		// Sent in the background so the response time does not reveal whether a user exists
+		go func() {
+			if err := h.verification.ResendLink(context.Background(), req.Email); err != nil {
+				log.Printf("Failed to resend verification email: %v", err)
+			}
+		}()
+		c.JSON(http.StatusOK, gin.H{"message": "if the email has an unverified account, we sent a new verification link"})
+	case domain.ChannelWhatsApp:
+		challenge, err := h.verification.SendPhoneCode(c.Request.Context(), req.Email)
+		if err != nil {
+			if errors.Is(err, services.ErrTooManyCodes) {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Failed to send verification code: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send the code"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidChannel.Error()})
+	}
+}
+
+type VerifyPhoneRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" binding:"required"`
+	Code        string    `json:"code" binding:"required"`
+}
 
-	if err := h.userRepo.Update(c.Request.Context(), user); err != nil {
+// VerifyPhone verifies the account with the WhatsApp code sent by ResendVerification.
+func (h *AuthHandler) VerifyPhone(c *gin.Context) {
+	var req VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.verification.VerifyPhone(c.Request.Context(), req.ChallengeID, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidCode) || errors.Is(err, services.ErrCodeExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify user"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "phone verified successfully"})
 }
 
 type LoginRequest struct {
@@ -121,42 +196,250 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Accounts are locked for a while after repeated failures, whether they exist or not:
+	// for the IP that failed, and for everyone after many more failures
+	lockedFor, err := h.limiter.LoginLockedFor(c.Request.Context(), req.Email, c.ClientIP())
+	if err != nil {
+		log.Printf("Failed to check login lockout: %v", err)
+	}
+	if lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+
 	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		h.loginFailed(c, req.Email)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		h.loginFailed(c, req.Email)
 		return
 	}
+	if err := h.limiter.LoginSucceeded(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
 
-	if !user.IsVerified {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "email not verified. please check your inbox"})
+	if !user.Verified() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "email not verified. please check your inbox", "unverified": true})
 		return
 	}
 
-	// Generate JWT
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key-change-me-in-prod" // Safe fallback for dev, but warning for prod
+	if !user.Active() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this account has been deactivated, please contact the shop"})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  user.ID,
-		"role": user.Role,
-		"exp":  time.Now().Add(time.Hour * 24).Unix(), // 24 hours expiration
-	})
-
-	tokenString, err := token.SignedString([]byte(secret))
+	// Users with two-factor authentication get a challenge instead of a session
+	result, err := h.twoFactor.StartLogin(c.Request.Context(), user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"user":  user,
-	})
+	respondLogin(c, result)
+}
+
+type LoginCodeRequest struct {
+	Channel     domain.ContactChannel `json:"channel" binding:"required"`
+	Destination string                `json:"destination" binding:"required"` // Email or phone, depending on the channel
+}
+
+// RequestLoginCode starts a passwordless login. It answers the same whether or not
+// the email or phone has an account.
+func (h *AuthHandler) RequestLoginCode(c *gin.Context) {
+	var req LoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := h.passwordless.RequestCode(c.Request.Context(), req.Channel, req.Destination)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidChannel):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTooManyCodes):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to send login code: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send the code"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+type VerifyLoginCodeRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" binding:"required"`
+	Code        string    `json:"code" binding:"required"`
+}
+
+// VerifyLoginCode finishes a passwordless login with the same response as Login,
+// which may ask for a second factor.
+func (h *AuthHandler) VerifyLoginCode(c *gin.Context) {
+	var req VerifyLoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.passwordless.VerifyCode(c.Request.Context(), req.ChallengeID, req.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCode), errors.Is(err, services.ErrCodeExpired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAccountInactive):
+			c.JSON(http.StatusForbidden, gin.H{"error": "this account has been deactivated, please contact the shop"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		}
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// loginFailed counts a wrong email or password towards the account lockout.
+func (h *AuthHandler) loginFailed(c *gin.Context, email string) {
+	lockedFor, err := h.limiter.LoginFailed(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	}
+	if lockedFor > 0 {
+		respondLockedOut(c, lockedFor)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+}
+
+func respondLockedOut(c *gin.Context, lockedFor time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedFor.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, please try again later"})
+}
+
+func respondLogin(c *gin.Context, result *domain.LoginResult) {
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// loginResponse is the body of every successful login. While a second factor is
+// pending it only holds the challenge, without anything about the user.
+func loginResponse(result *domain.LoginResult) gin.H {
+	if result.TwoFactor != nil {
+		return gin.H{"two_factor": result.TwoFactor}
+	}
+	return gin.H{
+		"token":              result.Tokens.AccessToken,
+		"expires_at":         result.Tokens.AccessExpiresAt,
+		"refresh_token":      result.Tokens.RefreshToken,
+		"refresh_expires_at": result.Tokens.RefreshExpiresAt,
+		"user":               result.User,
+	}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh returns a new access token and a new refresh token; the old one stops working.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.sessions.Refresh(c.Request.Context(), req.RefreshToken, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSession), errors.Is(err, services.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAccountInactive):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout closes the session of the given refresh token.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessions.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll closes every session of the logged-in user, this one included.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	if err := h.sessions.LogoutAll(c.Request.Context(), actor.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions"})
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword always answers the same, whether or not the email has an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Sent in the background so the response time does not reveal whether a user exists
+	go func() {
+		if err := h.resets.RequestReset(context.Background(), req.Email); err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"message": "if the email has an account, we sent a link to reset the password"})
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.resets.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResetToken), errors.Is(err, services.ErrPasswordTooShort):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password updated, please log in again"})
 }
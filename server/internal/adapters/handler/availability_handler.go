@@ -18,10 +18,11 @@ func NewAvailabilityHandler(svc ports.AvailabilityService) *AvailabilityHandler
 }
 
 type SetAvailabilityRequest struct {
-	Date         string `json:"date" binding:"required"`
-	StartTime    string `json:"start_time" binding:"required"`
-	EndTime      string `json:"end_time" binding:"required"`
-	SlotDuration int    `json:"slot_duration"`
+	Date          string `json:"date" binding:"required"`
+	StartTime     string `json:"start_time" binding:"required"`
+	EndTime       string `json:"end_time" binding:"required"`
+	SlotDuration  int    `json:"slot_duration"`
+	OpenOnHoliday bool   `json:"open_on_holiday"`
 }
 
 func (h *AvailabilityHandler) SetAvailability(c *gin.Context) {
@@ -37,7 +38,7 @@ func (h *AvailabilityHandler) SetAvailability(c *gin.Context) {
 		return
 	}
 
-	err := h.svc.SetAvailability(c.Request.Context(), req.Date, req.StartTime, req.EndTime, req.SlotDuration)
+	err := h.svc.SetAvailability(c.Request.Context(), req.Date, req.StartTime, req.EndTime, req.SlotDuration, req.OpenOnHoliday)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -68,7 +69,8 @@ func (h *AvailabilityHandler) GetSlots(c *gin.Context) {
 		return
 	}
 
-	slots, err := h.svc.GetAvailableSlots(c.Request.Context(), date)
+	// The client holding a slot (hold_token) still sees it as available
+	slots, err := h.svc.GetAvailableSlots(c.Request.Context(), date, c.Query("hold_token"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -91,3 +93,35 @@ func (h *AvailabilityHandler) DeleteAvailability(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Availability deleted"})
 }
+
+// ListHolidays returns public holidays between from and to (YYYY-MM-DD).
+// Defaults to the next 365 days starting today.
+func (h *AvailabilityHandler) ListHolidays(c *gin.Context) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if f := c.Query("from"); f != "" {
+		d, err := time.Parse("2006-01-02", f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Expected YYYY-MM-DD"})
+			return
+		}
+		from = d
+	}
+
+	to := from.AddDate(1, 0, 0)
+	if t := c.Query("to"); t != "" {
+		d, err := time.Parse("2006-01-02", t)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Expected YYYY-MM-DD"})
+			return
+		}
+		to = d
+	}
+
+	holidays, err := h.svc.ListHolidays(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, holidays)
+}
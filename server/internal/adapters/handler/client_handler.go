@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+	"gorm.io/gorm"
+)
+
+type ClientHandler struct {
+	svc ports.ClientService
+}
+
+func NewClientHandler(svc ports.ClientService) *ClientHandler {
+	return &ClientHandler{svc: svc}
+}
+
+type ClientNoteRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+func (h *ClientHandler) Profile(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	profile, err := h.svc.GetProfile(c.Request.Context(), id)
+	if err != nil {
+		respondNoteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ClientHandler) AddNote(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req ClientNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.svc.AddNote(c.Request.Context(), id, req.Body)
+	if err != nil {
+		respondNoteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
+
+func (h *ClientHandler) UpdateNote(c *gin.Context) {
+	id, noteID, ok := parseNoteIDs(c)
+	if !ok {
+		return
+	}
+	var req ClientNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.svc.UpdateNote(c.Request.Context(), id, noteID, req.Body)
+	if err != nil {
+		respondNoteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+func (h *ClientHandler) DeleteNote(c *gin.Context) {
+	id, noteID, ok := parseNoteIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteNote(c.Request.Context(), id, noteID); err != nil {
+		respondNoteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseNoteIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	noteID, err := uuid.Parse(c.Param("noteId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid note id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, noteID, true
+}
+
+func respondNoteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrEmptyNote):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Client notes failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong, please try again"})
+	}
+}
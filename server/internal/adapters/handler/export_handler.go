@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/export"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+type ExportHandler struct {
+	svc ports.ExportService
+	loc *time.Location
+}
+
+// NewExportHandler takes the shop's timezone, used to pick the current month and
+// date the file names.
+func NewExportHandler(svc ports.ExportService, loc *time.Location) *ExportHandler {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &ExportHandler{svc: svc, loc: loc}
+}
+
+// Appointments exports ?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive, default the current
+// month in the shop), optionally filtered by ?status=completed,cancelled, as ?format=csv|xlsx.
+func (h *ExportHandler) Appointments(c *gin.Context) {
+	format, ok := h.format(c)
+	if !ok {
+		return
+	}
+
+	// Appointments are stored as the shop's wall clock labelled UTC, so the days are too
+	now := time.Now().In(h.loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	var err error
+	if f := c.Query("from"); f != "" {
+		if from, err = time.Parse("2006-01-02", f); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if t := c.Query("to"); t != "" {
+		if to, err = time.Parse("2006-01-02", t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	filter := domain.AppointmentExportFilter{From: from, To: to.AddDate(0, 0, 1)}
+	if statuses := c.Query("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			filter.Statuses = append(filter.Statuses, domain.AppointmentStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	name := fmt.Sprintf("turnos_%s_%s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	out := h.start(c, format, name, "Turnos")
+	if err := h.svc.ExportAppointments(c.Request.Context(), filter, out); err != nil {
+		// Headers and part of the body are already sent; all we can do is log and cut the stream
+		log.Printf("Appointment export failed: %v", err)
+	}
+}
+
+// Clients exports every client with their visit count as ?format=csv|xlsx.
+func (h *ExportHandler) Clients(c *gin.Context) {
+	format, ok := h.format(c)
+	if !ok {
+		return
+	}
+
+	name := "clientes_" + time.Now().In(h.loc).Format("2006-01-02")
+	out := h.start(c, format, name, "Clientes")
+	if err := h.svc.ExportClients(c.Request.Context(), out); err != nil {
+		log.Printf("Client export failed: %v", err)
+	}
+}
+
+func (h *ExportHandler) format(c *gin.Context) (domain.ExportFormat, bool) {
+	format := domain.ExportFormat(c.DefaultQuery("format", string(domain.ExportCSV)))
+	if !format.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use csv or xlsx"})
+		return "", false
+	}
+	return format, true
+}
+
+// start sends the download headers and returns a writer streaming into the response.
+func (h *ExportHandler) start(c *gin.Context, format domain.ExportFormat, name, sheet string) ports.SheetWriter {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Status(http.StatusOK)
+
+	if format == domain.ExportXLSX {
+		return export.NewXLSXWriter(c.Writer, sheet)
+	}
+	return export.NewCSVWriter(c.Writer)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+type HoldHandler struct {
+	svc ports.SlotHoldService
+}
+
+func NewHoldHandler(svc ports.SlotHoldService) *HoldHandler {
+	return &HoldHandler{svc: svc}
+}
+
+type CreateHoldRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+}
+
+func (h *HoldHandler) Create(c *gin.Context) {
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, token, err := h.svc.HoldSlot(c.Request.Context(), req.StartTime)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSlotHeld), errors.Is(err, services.ErrSlotUnavailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"hold_token": token,
+		"start_time": hold.StartTime,
+		"expires_at": hold.ExpiresAt,
+	})
+}
+
+func (h *HoldHandler) Release(c *gin.Context) {
+	if err := h.svc.ReleaseHold(c.Request.Context(), c.Param("token")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "hold released"})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+const maxImportSize = 5 << 20 // 5 MB
+
+type ImportHandler struct {
+	svc ports.ImportService
+}
+
+func NewImportHandler(svc ports.ImportService) *ImportHandler {
+	return &ImportHandler{svc: svc}
+}
+
+// Clients imports a CSV sent as the multipart field "file" or as the raw request body.
+// With ?dry_run=true nothing is created and the report shows what would happen.
+func (h *ImportHandler) Clients(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	var file io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	if fh, err := c.FormFile("file"); err == nil {
+		if fh.Size > maxImportSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "could not read file"})
+			return
+		}
+		defer f.Close()
+		file = f
+	}
+
+	report, err := h.svc.ImportClients(c.Request.Context(), file, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		case errors.Is(err, services.ErrInvalidImport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+type JWKSHandler struct {
+	tokens ports.TokenIssuer
+}
+
+func NewJWKSHandler(tokens ports.TokenIssuer) *JWKSHandler {
+	return &JWKSHandler{tokens: tokens}
+}
+
+// Get serves the JWKS document so other services can verify our access tokens.
+func (h *JWKSHandler) Get(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": h.tokens.JWKS()})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+const (
+	// oidcStateCookie ties a login to the browser that started it, so a callback URL
+	// from someone else's login cannot be used to log a victim into that account.
+	oidcStateCookie = "oidc_state"
+	// Matches the time the service allows to come back from the provider
+	oidcStateCookieTTL = 10 * time.Minute
+)
+
+// OIDCHandler runs the browser side of a login with an OpenID Connect provider.
+// Both endpoints are browser redirects, so errors also end up on the web client.
+type OIDCHandler struct {
+	logins        ports.OIDCLoginService
+	frontendURL   string
+	secureCookies bool
+}
+
+// NewOIDCHandler takes whether the API is served over HTTPS, for the state cookie.
+func NewOIDCHandler(logins ports.OIDCLoginService, frontendURL string, secureCookies bool) *OIDCHandler {
+	return &OIDCHandler{logins: logins, frontendURL: frontendURL, secureCookies: secureCookies}
+}
+
+// Begin sends the browser to the provider's login page.
+func (h *OIDCHandler) Begin(c *gin.Context) {
+	authURL, state, err := h.logins.Begin(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		h.fail(c, "failed")
+		return
+	}
+	h.setStateCookie(c, c.FullPath(), hashState(state), int(oidcStateCookieTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback is where the provider sends the browser back. The new session is handed
+// to the web client in the URL fragment, which browsers never send to a server.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// The state must be the one Begin gave this browser; the cookie is single use
+	cookie, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, path.Dir(c.FullPath()), "", -1)
+	if c.Query("error") != "" {
+		h.fail(c, "cancelled")
+		return
+	}
+	state := c.Query("state")
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(hashState(state))) != 1 {
+		h.fail(c, "failed")
+		return
+	}
+
+	result, err := h.logins.Complete(c.Request.Context(), state, c.Query("code"), c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountInactive):
+			h.fail(c, "inactive")
+		case errors.Is(err, services.ErrOIDCEmailUnverified):
+			h.fail(c, "unverified")
+		default:
+			log.Printf("OIDC login failed: %v", err)
+			h.fail(c, "failed")
+		}
+		return
+	}
+
+	// The second factor is asked for on the web client, with the challenge token
+	if result.TwoFactor != nil {
+		fragment := url.Values{
+			"challenge_token":     {result.TwoFactor.Token},
+			"enrollment_required": {strconv.FormatBool(result.TwoFactor.EnrollmentRequired)},
+		}
+		c.Redirect(http.StatusFound, h.frontendURL+"/login/2fa#"+fragment.Encode())
+		return
+	}
+
+	user, err := json.Marshal(result.User)
+	if err != nil {
+		h.fail(c, "failed")
+		return
+	}
+	fragment := url.Values{
+		"token":         {result.Tokens.AccessToken},
+		"refresh_token": {result.Tokens.RefreshToken},
+		"user":          {string(user)},
+	}
+	c.Redirect(http.StatusFound, h.frontendURL+"/auth/callback#"+fragment.Encode())
+}
+
+func (h *OIDCHandler) fail(c *gin.Context, reason string) {
+	c.Redirect(http.StatusFound, h.frontendURL+"/login?"+url.Values{"error": {reason}}.Encode())
+}
+
+// setStateCookie sets, or with a negative maxAge clears, the state cookie. Lax is
+// needed for the browser to send it on the provider's redirect back, and the path
+// of Begin covers the callback below it.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, cookiePath, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     cookiePath,
+		MaxAge:   maxAge,
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
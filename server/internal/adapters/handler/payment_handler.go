@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+type PaymentHandler struct {
+	payments ports.PaymentService
+	apptSvc  ports.AppointmentService
+}
+
+func NewPaymentHandler(payments ports.PaymentService, apptSvc ports.AppointmentService) *PaymentHandler {
+	return &PaymentHandler{payments: payments, apptSvc: apptSvc}
+}
+
+// Webhook receives payment notifications from the provider.
+// Errors applying the payment return 500 so the provider retries.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "could not read body"})
+		return
+	}
+
+	notification, err := h.payments.VerifyWebhook(c.Request.Context(), ports.WebhookRequest{
+		Header: c.Request.Header,
+		Query:  c.Request.URL.Query(),
+		Body:   body,
+	})
+	if err != nil {
+		log.Printf("Rejected payment webhook: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook"})
+		return
+	}
+	if notification == nil {
+		c.Status(http.StatusOK) // Not about a payment
+		return
+	}
+
+	if err := h.apptSvc.ApplyPayment(c.Request.Context(), notification); err != nil {
+		log.Printf("Failed to apply payment %s: %v", notification.PaymentID, err)
+		if errors.Is(err, services.ErrPaymentMismatch) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "payment does not match the deposit"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
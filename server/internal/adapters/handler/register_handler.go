@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+	"gorm.io/gorm"
+)
+
+type RegisterHandler struct {
+	svc ports.RegisterService
+	loc *time.Location
+}
+
+// NewRegisterHandler takes the shop's timezone, used to tell what day today is.
+func NewRegisterHandler(svc ports.RegisterService, loc *time.Location) *RegisterHandler {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &RegisterHandler{svc: svc, loc: loc}
+}
+
+type RecordPaymentRequest struct {
+	Method   domain.PaymentMethod `json:"method" binding:"required"`
+	Amount   float64              `json:"amount"`
+	Tip      float64              `json:"tip"`
+	Discount float64              `json:"discount"`
+	Note     string               `json:"note"`
+}
+
+func (h *RegisterHandler) RecordPayment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	var req RecordPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment := &domain.Payment{
+		Method:   req.Method,
+		Amount:   req.Amount,
+		Tip:      req.Tip,
+		Discount: req.Discount,
+		Note:     req.Note,
+	}
+	if err := h.svc.RecordPayment(c.Request.Context(), id, payment); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "appointment not found"})
+		case errors.Is(err, services.ErrNotBillable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment)
+}
+
+func (h *RegisterHandler) ListPayments(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+
+	payments, err := h.svc.ListPayments(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, payments)
+}
+
+// CloseDay returns the cash-register close for ?date=YYYY-MM-DD (default today).
+func (h *RegisterHandler) CloseDay(c *gin.Context) {
+	date := time.Now().In(h.loc)
+	if d := c.Query("date"); d != "" {
+		parsed, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	report, err := h.svc.CloseDay(c.Request.Context(), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
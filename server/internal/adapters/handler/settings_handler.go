@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+type SettingsHandler struct {
+	repo ports.SettingsRepository
+}
+
+func NewSettingsHandler(repo ports.SettingsRepository) *SettingsHandler {
+	return &SettingsHandler{repo: repo}
+}
+
+func (h *SettingsHandler) Get(c *gin.Context) {
+	settings, err := h.repo.Get(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// Update applies a partial update: fields missing from the body keep their current value.
+func (h *SettingsHandler) Update(c *gin.Context) {
+	settings, err := h.repo.Get(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.ShouldBindJSON(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings.ID = domain.SettingsID
+	if !settings.RestrictionMode.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "restriction_mode must be approval or deposit"})
+		return
+	}
+
+	if err := h.repo.Save(c.Request.Context(), settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
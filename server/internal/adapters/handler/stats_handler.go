@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
 )
 
 type StatsHandler struct {
@@ -23,3 +27,40 @@ func (h *StatsHandler) GetDashboardStats(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetAnalytics reports on ?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive, default the
+// last 30 days) grouped by ?group_by=day|week|month (default day).
+func (h *StatsHandler) GetAnalytics(c *gin.Context) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -29)
+	to := today
+
+	if f := c.Query("from"); f != "" {
+		parsed, err := time.Parse("2006-01-02", f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from format, use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if t := c.Query("to"); t != "" {
+		parsed, err := time.Parse("2006-01-02", t)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to format, use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	groupBy := domain.Grouping(c.DefaultQuery("group_by", string(domain.GroupByDay)))
+
+	report, err := h.svc.GetAnalytics(c.Request.Context(), from, to.AddDate(0, 0, 1), groupBy)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+// TwoFactorHandler finishes logins that need a code from an authenticator app, and
+// lets logged-in users turn two-factor authentication on and off.
+type TwoFactorHandler struct {
+	twoFactor ports.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactor ports.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor}
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // From the app, or a recovery code
+}
+
+// Verify finishes a login with a code, with the same response as Login.
+func (h *TwoFactorHandler) Verify(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.twoFactor.CompleteLogin(c.Request.Context(), req.ChallengeToken, req.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	respondLogin(c, result)
+}
+
+// Enroll starts setting up the app during a login of a user who must have one.
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.twoFactor.EnrollForLogin(c.Request.Context(), req.ChallengeToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmEnrollment turns two-factor authentication on and finishes the login. The
+// response is Login's plus the recovery codes, which are never shown again.
+func (h *TwoFactorHandler) ConfirmEnrollment(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, codes, err := h.twoFactor.ConfirmForLogin(c.Request.Context(), req.ChallengeToken, req.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	res := loginResponse(result)
+	res["recovery_codes"] = codes
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	status, err := h.twoFactor.Status(c.Request.Context(), actor.ID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollSelf starts setting up an authenticator app for the logged-in user.
+func (h *TwoFactorHandler) EnrollSelf(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(c.Request.Context(), actor.ID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *TwoFactorHandler) ConfirmSelf(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactor.Confirm(c.Request.Context(), actor.ID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RecoveryCodes replaces the recovery codes; the old ones stop working.
+func (h *TwoFactorHandler) RecoveryCodes(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), actor.ID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	actor := domain.ActorFromContext(c.Request.Context())
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.twoFactor.Disable(c.Request.Context(), actor.ID, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication turned off"})
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorLogin), errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "this account has been deactivated, please contact the shop"})
+	case errors.Is(err, services.ErrTooManyTwoFactorCodes):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrNoTwoFactorEnrollment):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Two-factor authentication failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong, please try again"})
+	}
+}
@@ -1,43 +1,86 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	repo ports.UserRepository
+	repo   ports.UserRepository
+	policy ports.PolicyService
+	users  ports.UserService
 }
 
-func NewUserHandler(repo ports.UserRepository) *UserHandler {
-	return &UserHandler{repo: repo}
+func NewUserHandler(repo ports.UserRepository, policy ports.PolicyService, users ports.UserService) *UserHandler {
+	return &UserHandler{repo: repo, policy: policy, users: users}
 }
 
+// List searches clients. Query params:
+//   - q: words matched against name, email and phone, ignoring accents
+//   - verified, has_upcoming, blocked: true/false
+//   - last_visit_before: YYYY-MM-DD; min_no_shows, min_late_cancels
+//   - sort: name, created_at or last_visit; order: asc or desc
+//   - limit (default 50, max 200) and cursor (next_cursor of the previous page)
 func (h *UserHandler) List(c *gin.Context) {
-	// optional query params: limit, offset
-	limit := 1000
-	offset := 0
-	if l := c.Query("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil && v > 0 {
-			limit = v
-		}
+	search := domain.ClientSearch{
+		Query:  c.Query("q"),
+		Sort:   domain.ClientSort(c.DefaultQuery("sort", string(domain.SortByName))),
+		Desc:   c.Query("order") == "desc",
+		Cursor: c.Query("cursor"),
+	}
+	if !search.Sort.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort, use name, created_at or last_visit"})
+		return
+	}
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		search.Limit = v
+	}
+
+	if v, err := strconv.Atoi(c.Query("min_no_shows")); err == nil && v > 0 {
+		search.MinNoShows = v
+	}
+	if v, err := strconv.Atoi(c.Query("min_late_cancels")); err == nil && v > 0 {
+		search.MinLateCancels = v
 	}
-	if o := c.Query("offset"); o != "" {
-		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
-			offset = v
+	if b, err := strconv.ParseBool(c.Query("blocked")); err == nil {
+		search.BookingBlocked = &b
+	}
+	if b, err := strconv.ParseBool(c.Query("verified")); err == nil {
+		search.Verified = &b
+	}
+	if b, err := strconv.ParseBool(c.Query("has_upcoming")); err == nil {
+		search.HasUpcoming = &b
+	}
+	if d := c.Query("last_visit_before"); d != "" {
+		before, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid last_visit_before format, use YYYY-MM-DD"})
+			return
 		}
+		search.LastVisitBefore = &before
 	}
 
-	users, err := h.repo.ListClients(c.Request.Context(), limit, offset)
+	page, err := h.repo.SearchClients(c.Request.Context(), search)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, page)
 }
 
 func (h *UserHandler) Get(c *gin.Context) {
@@ -50,9 +93,203 @@ func (h *UserHandler) Get(c *gin.Context) {
 
 	user, err := h.repo.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+type CreateUserRequest struct {
+	Name     string      `json:"name" binding:"required"`
+	Email    string      `json:"email"`
+	Phone    string      `json:"phone"`
+	Role     domain.Role `json:"role"`
+	Password string      `json:"password"`
+}
+
+func (h *UserHandler) Create(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.Create(c.Request.Context(), domain.NewUser{
+		Name:     req.Name,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Role:     req.Role,
+		Password: req.Password,
+	})
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+type UpdateUserRequest struct {
+	Name       *string      `json:"name"`
+	Email      *string      `json:"email"`
+	Phone      *string      `json:"phone"`
+	Role       *domain.Role `json:"role"`
+	IsVerified *bool        `json:"is_verified"`
+}
+
+// Update changes only the fields present in the body.
+func (h *UserHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	user, err := h.users.Update(c.Request.Context(), id, domain.UserChanges{
+		Name:       req.Name,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Role:       req.Role,
+		IsVerified: req.IsVerified,
+	})
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, user)
 }
+
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	h.setActive(c, h.users.Deactivate)
+}
+
+func (h *UserHandler) Reactivate(c *gin.Context) {
+	h.setActive(c, h.users.Reactivate)
+}
+
+func (h *UserHandler) setActive(c *gin.Context, apply func(ctx context.Context, id uuid.UUID) (*domain.User, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := apply(c.Request.Context(), id)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type MergeUsersRequest struct {
+	DuplicateID uuid.UUID `json:"duplicate_id" binding:"required"`
+}
+
+// Merge folds the user in the body into the one in the path.
+func (h *UserHandler) Merge(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.Merge(c.Request.Context(), id, req.DuplicateID)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type SetRoleRequest struct {
+	Role domain.Role `json:"role" binding:"required"`
+}
+
+func (h *UserHandler) SetRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.SetRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type RoleResponse struct {
+	Role        domain.Role         `json:"role"`
+	Permissions []domain.Permission `json:"permissions"`
+}
+
+// Roles lists every role with what it may do, for the role picker.
+func (h *UserHandler) Roles(c *gin.Context) {
+	roles := make([]RoleResponse, 0, len(domain.Roles))
+	for _, role := range domain.Roles {
+		roles = append(roles, RoleResponse{Role: role, Permissions: role.Permissions()})
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// Staff lists everyone working at the shop, to assign roles and appointments.
+func (h *UserHandler) Staff(c *gin.Context) {
+	staff, err := h.repo.ListStaff(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, staff)
+}
+
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMerge):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidUser), errors.Is(err, services.ErrPasswordTooShort):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("User management failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong, please try again"})
+	}
+}
+
+// ClearBookingBlock lets a blocked client book online again and resets their policy strikes.
+func (h *UserHandler) ClearBookingBlock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.policy.ClearBlock(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "booking block cleared"})
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/services"
+)
+
+type WaitlistHandler struct {
+	svc ports.WaitlistService
+}
+
+func NewWaitlistHandler(svc ports.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{svc: svc}
+}
+
+type JoinWaitlistRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	Phone       string `json:"phone" binding:"required"`
+	Date        string `json:"date" binding:"required"`
+	WindowStart string `json:"window_start"`
+	WindowEnd   string `json:"window_end"`
+	Service     string `json:"service"`
+}
+
+func (h *WaitlistHandler) Join(c *gin.Context) {
+	var req JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := &domain.WaitlistEntry{
+		ClientName:  req.Name,
+		ClientEmail: req.Email,
+		ClientPhone: req.Phone,
+		Date:        req.Date,
+		WindowStart: req.WindowStart,
+		WindowEnd:   req.WindowEnd,
+		Service:     req.Service,
+	}
+	if err := h.svc.Join(c.Request.Context(), entry); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWaitlistEntry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrSlotsAvailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to join the waitlist: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join the waitlist"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+type ClaimWaitlistRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *WaitlistHandler) Claim(c *gin.Context) {
+	var req ClaimWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appt, err := h.svc.Claim(c.Request.Context(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidClaim):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrClaimExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, appt)
+}
+
+// List returns the waitlist for a date (YYYY-MM-DD), defaulting to today.
+func (h *WaitlistHandler) List(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+		return
+	}
+
+	entries, err := h.svc.ListByDate(c.Request.Context(), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,67 @@
+package holidays
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// argentinaData lists national holidays per year, already resolved to the
+// dates set by decree (movable holidays moved, "feriados puente" included).
+// It has to be extended every year once the government publishes the calendar.
+//
+//go:embed data/argentina.json
+var argentinaData []byte
+
+type ArgentinaProvider struct {
+	byDate map[string]domain.Holiday
+	sorted []domain.Holiday
+}
+
+func NewArgentinaProvider() (ports.HolidayProvider, error) {
+	var years map[string][]domain.Holiday
+	if err := json.Unmarshal(argentinaData, &years); err != nil {
+		return nil, fmt.Errorf("parsing embedded holiday data: %w", err)
+	}
+
+	p := &ArgentinaProvider{byDate: make(map[string]domain.Holiday)}
+	for year, list := range years {
+		for _, h := range list {
+			if _, err := time.Parse("2006-01-02", h.Date); err != nil || h.Date[:4] != year {
+				return nil, fmt.Errorf("invalid holiday date %q for year %s", h.Date, year)
+			}
+			p.byDate[h.Date] = h
+			p.sorted = append(p.sorted, h)
+		}
+	}
+	sort.Slice(p.sorted, func(i, j int) bool { return p.sorted[i].Date < p.sorted[j].Date })
+
+	return p, nil
+}
+
+func (p *ArgentinaProvider) GetHoliday(ctx context.Context, date time.Time) (*domain.Holiday, error) {
+	h, ok := p.byDate[date.Format("2006-01-02")]
+	if !ok {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+func (p *ArgentinaProvider) ListHolidays(ctx context.Context, from, to time.Time) ([]domain.Holiday, error) {
+	start := from.Format("2006-01-02")
+	end := to.Format("2006-01-02")
+
+	holidays := []domain.Holiday{}
+	for _, h := range p.sorted {
+		if h.Date >= start && h.Date <= end {
+			holidays = append(holidays, h)
+		}
+	}
+	return holidays, nil
+}
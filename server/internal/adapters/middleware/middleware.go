@@ -1,16 +1,20 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware requires a valid access token whose session is still open. The role
+// is read from the user, so role changes apply without waiting for a new token.
+func AuthMiddleware(tokens ports.TokenIssuer, sessions ports.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,40 +30,64 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			secret = "your-secret-key-change-me-in-prod"
-		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := tokens.Verify(parts[1])
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		user, err := checkSession(c.Request.Context(), sessions, claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", claims["sub"])
-		c.Set("role", claims["role"])
+		setUser(c, user, claims.SessionID)
+		c.Next()
+	}
+}
 
+// OptionalAuthMiddleware identifies the user when a valid token is sent and lets
+// anonymous requests through, for public routes that behave differently when logged in.
+func OptionalAuthMiddleware(tokens ports.TokenIssuer, sessions ports.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			if claims, err := tokens.Verify(tokenString); err == nil {
+				if user, err := checkSession(c.Request.Context(), sessions, claims); err == nil {
+					setUser(c, user, claims.SessionID)
+				}
+			}
+		}
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// checkSession loads the token's session, so logouts and revocations apply at once.
+func checkSession(ctx context.Context, sessions ports.SessionService, claims *domain.AccessClaims) (*domain.User, error) {
+	user, err := sessions.Authenticate(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != claims.UserID {
+		return nil, errors.New("Invalid token claims")
+	}
+	return user, nil
+}
+
+func setUser(c *gin.Context, user *domain.User, sessionID uuid.UUID) {
+	c.Set("userID", user.ID.String())
+	c.Set("role", string(user.Role))
+	c.Set("sessionID", sessionID)
+
+	// Make the user available to services for auditing and role checks
+	actor := domain.Actor{ID: user.ID, Role: user.Role}
+	c.Request = c.Request.WithContext(domain.ContextWithActor(c.Request.Context(), actor))
+}
+
+// RequirePermission lets the request through only if the user's role grants p. It
+// goes after AuthMiddleware, which loads the role on every request.
+func RequirePermission(p domain.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
@@ -68,8 +96,8 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !domain.Role(role.(string)).Can(p) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
 			c.Abort()
 			return
 		}
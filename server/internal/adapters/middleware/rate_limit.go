@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// RateLimit throttles a route group per client IP and, once a user is known (place it
+// after an auth middleware), per account. Rejected requests get a 429 with Retry-After.
+func RateLimit(limiter ports.RateLimitService, group string, limits domain.RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		buckets := []rateBucket{{group + ":ip:" + c.ClientIP(), limits.PerIP}}
+		if userID := c.GetString("userID"); userID != "" {
+			buckets = append(buckets, rateBucket{group + ":user:" + userID, limits.PerAccount})
+		}
+
+		for _, bucket := range buckets {
+			retryAfter, err := limiter.Allow(ctx, bucket.key, bucket.limit)
+			if err != nil {
+				// Better to serve without limits than to stop serving
+				log.Printf("Rate limit %s: %v", bucket.key, err)
+				continue
+			}
+			if retryAfter > 0 {
+				c.Header("Retry-After", retryAfterSeconds(retryAfter))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+type rateBucket struct {
+	key   string
+	limit domain.RateLimit
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
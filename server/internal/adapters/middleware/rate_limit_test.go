@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+// fakeLimiter allows Requests per key and records the keys it was asked about.
+type fakeLimiter struct {
+	Requests int
+	Err      error
+	Counts   map[string]int
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (time.Duration, error) {
+	if f.Err != nil {
+		return 0, f.Err
+	}
+	f.Counts[key]++
+	if f.Counts[key] > f.Requests {
+		return 1500 * time.Millisecond, nil
+	}
+	return 0, nil
+}
+func (f *fakeLimiter) LoginLockedFor(ctx context.Context, account, ip string) (time.Duration, error) {
+	return 0, nil
+}
+func (f *fakeLimiter) LoginFailed(ctx context.Context, account, ip string) (time.Duration, error) {
+	return 0, nil
+}
+func (f *fakeLimiter) LoginSucceeded(ctx context.Context, account, ip string) error { return nil }
+
+func newRateLimitedRouter(limiter *fakeLimiter, userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("userID", userID)
+		}
+	})
+	r.Use(RateLimit(limiter, "auth", domain.RateLimits{
+		PerIP:      domain.RateLimit{Requests: 2, Window: time.Minute},
+		PerAccount: domain.RateLimit{Requests: 2, Window: time.Minute},
+	}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func get(r *gin.Engine, ip string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	limiter := &fakeLimiter{Requests: 2, Counts: make(map[string]int)}
+	r := newRateLimitedRouter(limiter, "")
+
+	for i := 0; i < 2; i++ {
+		if w := get(r, "1.2.3.4"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+	w := get(r, "1.2.3.4")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := get(r, "5.6.7.8"); w.Code != http.StatusOK {
+		t.Errorf("expected other IPs not to be limited, got %d", w.Code)
+	}
+	if limiter.Counts["auth:ip:1.2.3.4"] != 3 {
+		t.Errorf("unexpected buckets %v", limiter.Counts)
+	}
+}
+
+func TestRateLimit_PerAccount(t *testing.T) {
+	limiter := &fakeLimiter{Requests: 2, Counts: make(map[string]int)}
+	r := newRateLimitedRouter(limiter, "user-1")
+
+	get(r, "1.2.3.4")
+	get(r, "5.6.7.8")
+	if w := get(r, "9.9.9.9"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the account to be limited across IPs, got %d", w.Code)
+	}
+	if limiter.Counts["auth:user:user-1"] != 3 {
+		t.Errorf("unexpected buckets %v", limiter.Counts)
+	}
+}
+
+func TestRateLimit_ServesWhenTheStoreFails(t *testing.T) {
+	r := newRateLimitedRouter(&fakeLimiter{Err: errors.New("store down")}, "user-1")
+
+	if w := get(r, "1.2.3.4"); w.Code != http.StatusOK {
+		t.Errorf("expected the request to be served, got %d", w.Code)
+	}
+}
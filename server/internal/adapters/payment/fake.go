@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// Fake is an in-memory PaymentService for development and tests.
+// Payments are "made" by posting {"appointment_id": "...", "status": "paid"} to the webhook.
+type Fake struct {
+	checkoutURL string
+
+	mu      sync.Mutex
+	intents map[uuid.UUID]float64 // Appointment ID -> amount
+	refunds map[string]float64    // Payment ID -> refunded amount
+}
+
+func NewFake(checkoutURL string) *Fake {
+	return &Fake{
+		checkoutURL: checkoutURL,
+		intents:     make(map[uuid.UUID]float64),
+		refunds:     make(map[string]float64),
+	}
+}
+
+var _ ports.PaymentService = (*Fake)(nil)
+
+func (f *Fake) CreatePayment(ctx context.Context, appt *domain.Appointment, amount float64) (*domain.PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.intents[appt.ID] = amount
+	log.Printf("[FakePayments] Deposit of %.2f requested for appointment %s", amount, appt.ID)
+	return &domain.PaymentIntent{
+		ProviderID:  "fake-pref-" + appt.ID.String(),
+		CheckoutURL: f.checkoutURL + "?appointment_id=" + appt.ID.String(),
+	}, nil
+}
+
+func (f *Fake) VerifyWebhook(ctx context.Context, req ports.WebhookRequest) (*domain.PaymentNotification, error) {
+	var body struct {
+		AppointmentID uuid.UUID            `json:"appointment_id"`
+		Status        domain.PaymentStatus `json:"status"`
+	}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	amount, ok := f.intents[body.AppointmentID]
+	if !ok {
+		return nil, fmt.Errorf("no fake payment for appointment %s", body.AppointmentID)
+	}
+
+	return &domain.PaymentNotification{
+		PaymentID:     "fake-pay-" + body.AppointmentID.String(),
+		AppointmentID: body.AppointmentID,
+		Status:        body.Status,
+		Amount:        amount,
+		Currency:      domain.DepositCurrency,
+	}, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, paymentID string, amount float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.refunds[paymentID] += amount
+	log.Printf("[FakePayments] Refunded %.2f of payment %s", amount, paymentID)
+	return nil
+}
+
+// Refunded returns the total refunded for a payment.
+func (f *Fake) Refunded(paymentID string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.refunds[paymentID]
+}
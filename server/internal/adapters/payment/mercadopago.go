@@ -0,0 +1,234 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+const mercadoPagoAPI = "https://api.mercadopago.com"
+
+// MercadoPago creates Checkout Pro preferences and handles their payment webhooks.
+type MercadoPago struct {
+	accessToken     string
+	webhookSecret   string
+	notificationURL string // Our public webhook URL
+	returnURL       string // Where the client lands after paying
+	baseURL         string
+	client          *http.Client
+}
+
+func NewMercadoPago(accessToken, webhookSecret, notificationURL, returnURL string) ports.PaymentService {
+	return &MercadoPago{
+		accessToken:     accessToken,
+		webhookSecret:   webhookSecret,
+		notificationURL: notificationURL,
+		returnURL:       returnURL,
+		baseURL:         mercadoPagoAPI,
+		client:          &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type mpPreferenceItem struct {
+	Title      string  `json:"title"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+	CurrencyID string  `json:"currency_id"`
+}
+
+type mpPreferenceRequest struct {
+	Items             []mpPreferenceItem `json:"items"`
+	ExternalReference string             `json:"external_reference"`
+	NotificationURL   string             `json:"notification_url,omitempty"`
+	BackURLs          map[string]string  `json:"back_urls,omitempty"`
+	AutoReturn        string             `json:"auto_return,omitempty"`
+}
+
+type mpPreferenceResponse struct {
+	ID        string `json:"id"`
+	InitPoint string `json:"init_point"`
+}
+
+func (m *MercadoPago) CreatePayment(ctx context.Context, appt *domain.Appointment, amount float64) (*domain.PaymentIntent, error) {
+	req := mpPreferenceRequest{
+		Items: []mpPreferenceItem{{
+			Title:      "Seña turno " + appt.StartTime.Format("02/01/2006 15:04"),
+			Quantity:   1,
+			UnitPrice:  amount,
+			CurrencyID: domain.DepositCurrency,
+		}},
+		ExternalReference: appt.ID.String(),
+		NotificationURL:   m.notificationURL,
+	}
+	if m.returnURL != "" {
+		req.BackURLs = map[string]string{
+			"success": m.returnURL + "?status=approved",
+			"pending": m.returnURL + "?status=pending",
+			"failure": m.returnURL + "?status=failure",
+		}
+		req.AutoReturn = "approved"
+	}
+
+	var res mpPreferenceResponse
+	if err := m.do(ctx, http.MethodPost, "/checkout/preferences", req, &res, ""); err != nil {
+		return nil, err
+	}
+	return &domain.PaymentIntent{ProviderID: res.ID, CheckoutURL: res.InitPoint}, nil
+}
+
+type mpPayment struct {
+	ID                int64   `json:"id"`
+	Status            string  `json:"status"`
+	ExternalReference string  `json:"external_reference"`
+	TransactionAmount float64 `json:"transaction_amount"`
+	CurrencyID        string  `json:"currency_id"`
+}
+
+func (m *MercadoPago) VerifyWebhook(ctx context.Context, req ports.WebhookRequest) (*domain.PaymentNotification, error) {
+	var body struct {
+		Type string `json:"type"`
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(req.Body, &body)
+
+	notificationType := req.Query.Get("type")
+	if notificationType == "" {
+		notificationType = body.Type
+	}
+	if notificationType != "payment" {
+		return nil, nil
+	}
+
+	dataID := req.Query.Get("data.id")
+	if dataID == "" {
+		dataID = body.Data.ID
+	}
+	if dataID == "" {
+		return nil, errors.New("webhook without payment id")
+	}
+
+	if err := m.verifySignature(req.Header, dataID); err != nil {
+		return nil, err
+	}
+
+	// The webhook only carries the ID: fetch the payment to learn its state
+	var p mpPayment
+	if err := m.do(ctx, http.MethodGet, "/v1/payments/"+dataID, nil, &p, ""); err != nil {
+		return nil, err
+	}
+	apptID, err := uuid.Parse(p.ExternalReference)
+	if err != nil {
+		return nil, fmt.Errorf("payment %d has unknown external reference %q", p.ID, p.ExternalReference)
+	}
+
+	return &domain.PaymentNotification{
+		PaymentID:     strconv.FormatInt(p.ID, 10),
+		AppointmentID: apptID,
+		Status:        mapMercadoPagoStatus(p.Status),
+		Amount:        p.TransactionAmount,
+		Currency:      p.CurrencyID,
+	}, nil
+}
+
+// verifySignature checks the x-signature header: an HMAC-SHA256 of
+// "id:<data.id>;request-id:<x-request-id>;ts:<ts>;" keyed with the webhook secret.
+func (m *MercadoPago) verifySignature(header http.Header, dataID string) error {
+	if m.webhookSecret == "" {
+		return errors.New("no webhook secret configured, unsigned webhooks are rejected")
+	}
+
+	var ts, v1 string
+	for _, part := range strings.Split(header.Get("x-signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "ts":
+			ts = value
+		case "v1":
+			v1 = value
+		}
+	}
+	if ts == "" || v1 == "" {
+		return errors.New("missing webhook signature")
+	}
+
+	manifest := "id:" + strings.ToLower(dataID) + ";request-id:" + header.Get("x-request-id") + ";ts:" + ts + ";"
+	mac := hmac.New(sha256.New, []byte(m.webhookSecret))
+	mac.Write([]byte(manifest))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(v1)) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
+
+// Refund gives back amount of the payment. The idempotency key comes from the payment,
+// so a deposit refunded again, e.g. on a webhook retry, is not refunded twice.
+func (m *MercadoPago) Refund(ctx context.Context, paymentID string, amount float64) error {
+	body := map[string]float64{"amount": amount}
+	return m.do(ctx, http.MethodPost, "/v1/payments/"+paymentID+"/refunds", body, nil, "refund-"+paymentID)
+}
+
+func (m *MercadoPago) do(ctx context.Context, method, path string, in, out interface{}, idempotencyKey string) error {
+	var reqBody io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("X-Idempotency-Key", idempotencyKey)
+	}
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("mercadopago %s %s: %s: %s", method, path, res.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func mapMercadoPagoStatus(status string) domain.PaymentStatus {
+	switch status {
+	case "approved":
+		return domain.PaymentPaid
+	case "rejected", "cancelled":
+		return domain.PaymentFailed
+	case "refunded", "charged_back":
+		return domain.PaymentRefunded
+	default: // pending, in_process, authorized, in_mediation
+		return domain.PaymentPending
+	}
+}
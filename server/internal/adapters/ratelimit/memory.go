@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// Memory keeps rate limit counters in this process. Each instance counts on its own,
+// so deployments with several instances should use the Postgres store.
+type Memory struct {
+	mu       sync.Mutex
+	counters map[string]domain.RateCounter
+}
+
+func NewMemory() *Memory {
+	return &Memory{counters: make(map[string]domain.RateCounter)}
+}
+
+var _ ports.RateLimitStore = (*Memory)(nil)
+
+func (m *Memory) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (*domain.RateCounter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter, ok := m.counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		counter = domain.RateCounter{Key: key, ResetAt: now.Add(window)}
+	}
+	counter.Count++
+	counter.LastHitAt = now
+	m.counters[key] = counter
+	return &counter, nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string, now time.Time) (*domain.RateCounter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter, ok := m.counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		return nil, nil
+	}
+	return &counter, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *Memory) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, counter := range m.counters {
+		if counter.ResetAt.Before(before) {
+			delete(m.counters, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+// AnalyticsRepository runs the reporting aggregates. Periods are truncated in UTC,
+// like the rest of the scheduling code.
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) ports.AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// A client's visits are their completed appointments; they count as new in the
+// period of their first visit and as returning afterwards. The grand total row
+// (GROUPING SETS) counts distinct clients over the whole range instead.
+const appointmentsByPeriodSQL = `
+WITH first_visits AS (
+	SELECT client_id, MIN(start_time) AS first_visit
+	FROM appointments
+	WHERE status = @completed
+	GROUP BY client_id
+), appts AS (
+	SELECT
+		date_trunc(@group_by, a.start_time AT TIME ZONE 'UTC') AS period_start,
+		date_trunc(@group_by, f.first_visit AT TIME ZONE 'UTC') AS first_period,
+		f.first_visit >= @start AS first_in_range,
+		a.client_id, a.status, a.start_time, a.end_time
+	FROM appointments a
+	LEFT JOIN first_visits f ON f.client_id = a.client_id
+	WHERE a.start_time >= @start AND a.start_time < @end
+)
+SELECT
+	GROUPING(period_start) = 1 AS is_total,
+	COALESCE(period_start, CAST(@start_day AS timestamp)) AS period_start,
+	COUNT(*) FILTER (WHERE status <> @rescheduled) AS appointments,
+	COUNT(*) FILTER (WHERE status = @completed) AS completed,
+	COUNT(*) FILTER (WHERE status = @cancelled) AS cancelled,
+	COUNT(*) FILTER (WHERE status = @no_show) AS no_shows,
+	COALESCE(SUM(EXTRACT(EPOCH FROM end_time - start_time) / 60) FILTER (WHERE status NOT IN @freed), 0)::bigint AS booked_minutes,
+	COUNT(DISTINCT client_id) FILTER (WHERE status = @completed AND first_period = period_start) AS new_clients,
+	COUNT(DISTINCT client_id) FILTER (WHERE status = @completed AND first_period < period_start) AS returning_clients,
+	COUNT(DISTINCT client_id) FILTER (WHERE status = @completed AND first_in_range) AS range_new_clients,
+	COUNT(DISTINCT client_id) FILTER (WHERE status = @completed AND NOT first_in_range) AS range_returning_clients
+FROM appts
+GROUP BY GROUPING SETS ((period_start), ())
+ORDER BY is_total, period_start`
+
+type appointmentPeriodRow struct {
+	domain.PeriodStats
+	IsTotal               bool
+	RangeNewClients       int64
+	RangeReturningClients int64
+}
+
+func (r *AnalyticsRepository) AppointmentsByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) ([]domain.PeriodStats, domain.PeriodStats, error) {
+	var rows []appointmentPeriodRow
+	err := conn(ctx, r.db).Raw(appointmentsByPeriodSQL, map[string]interface{}{
+		"group_by":    string(groupBy),
+		"start":       start,
+		"start_day":   start.Format("2006-01-02"),
+		"end":         end,
+		"completed":   domain.StatusCompleted,
+		"cancelled":   domain.StatusCancelled,
+		"no_show":     domain.StatusNoShow,
+		"rescheduled": domain.StatusRescheduled,
+		"freed":       domain.FreedStatuses,
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, domain.PeriodStats{}, err
+	}
+
+	var periods []domain.PeriodStats
+	var total domain.PeriodStats
+	for _, row := range rows {
+		if row.IsTotal {
+			total = row.PeriodStats
+			total.NewClients = row.RangeNewClients
+			total.ReturningClients = row.RangeReturningClients
+			continue
+		}
+		periods = append(periods, row.PeriodStats)
+	}
+	return periods, total, nil
+}
+
+// Availability dates and times are stored as text (YYYY-MM-DD, HH:MM).
+const availabilityByPeriodSQL = `
+SELECT
+	date_trunc(@group_by, date::date::timestamp) AS period_start,
+	COALESCE(SUM(EXTRACT(EPOCH FROM end_time::time - start_time::time) / 60), 0)::bigint AS available_minutes
+FROM availabilities
+WHERE NOT is_blocked AND date >= @start AND date < @end
+GROUP BY period_start
+ORDER BY period_start`
+
+func (r *AnalyticsRepository) AvailabilityByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) ([]domain.PeriodStats, error) {
+	var rows []domain.PeriodStats
+	err := conn(ctx, r.db).Raw(availabilityByPeriodSQL, map[string]interface{}{
+		"group_by": string(groupBy),
+		"start":    start.Format("2006-01-02"),
+		"end":      end.Format("2006-01-02"),
+	}).Scan(&rows).Error
+	return rows, err
+}
+
+const revenueByPeriodSQL = `
+SELECT
+	date_trunc(@group_by, created_at AT TIME ZONE @tz) AS period_start,
+	COALESCE(SUM(amount), 0) AS revenue,
+	COALESCE(SUM(tip), 0) AS tips
+FROM payments
+WHERE created_at >= @start AND created_at < @end
+GROUP BY period_start
+ORDER BY period_start`
+
+func (r *AnalyticsRepository) RevenueByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping, loc *time.Location) ([]domain.PeriodStats, error) {
+	var rows []domain.PeriodStats
+	err := conn(ctx, r.db).Raw(revenueByPeriodSQL, map[string]interface{}{
+		"group_by": string(groupBy),
+		"tz":       loc.String(),
+		"start":    start,
+		"end":      end,
+	}).Scan(&rows).Error
+	return rows, err
+}
+
+const heatmapSQL = `
+SELECT
+	EXTRACT(DOW FROM start_time AT TIME ZONE 'UTC')::int AS weekday,
+	EXTRACT(HOUR FROM start_time AT TIME ZONE 'UTC')::int AS hour,
+	COUNT(*) AS appointments
+FROM appointments
+WHERE status NOT IN @freed AND start_time >= @start AND start_time < @end
+GROUP BY weekday, hour
+ORDER BY weekday, hour`
+
+func (r *AnalyticsRepository) Heatmap(ctx context.Context, start, end time.Time) ([]domain.HeatmapCell, error) {
+	var cells []domain.HeatmapCell
+	err := conn(ctx, r.db).Raw(heatmapSQL, map[string]interface{}{
+		"start": start,
+		"end":   end,
+		"freed": domain.FreedStatuses,
+	}).Scan(&cells).Error
+	return cells, err
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+// AppointmentEventRepository is append-only: events are never updated or deleted.
+type AppointmentEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAppointmentEventRepository(db *gorm.DB) ports.AppointmentEventRepository {
+	return &AppointmentEventRepository{db: db}
+}
+
+func (r *AppointmentEventRepository) Create(ctx context.Context, event *domain.AppointmentEvent) error {
+	return conn(ctx, r.db).Omit("Actor").Create(event).Error
+}
+
+func (r *AppointmentEventRepository) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.AppointmentEvent, error) {
+	var events []domain.AppointmentEvent
+	err := conn(ctx, r.db).Preload("Actor").
+		Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC").
+		Find(&events).Error
+	return events, err
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AppointmentRepository struct {
@@ -26,8 +27,8 @@ func (r *AppointmentRepository) Create(ctx context.Context, appointment *domain.
 
 	// Check if there is already an appointment that overlaps
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Appointment{}).
-		Where("status != ?", domain.StatusCancelled).
+	err := conn(ctx, r.db).Model(&domain.Appointment{}).
+		Where("status NOT IN ?", domain.FreedStatuses).
 		Where("start_time < ? AND end_time > ?", appointment.EndTime, appointment.StartTime).
 		Count(&count).Error
 
@@ -38,32 +39,61 @@ func (r *AppointmentRepository) Create(ctx context.Context, appointment *domain.
 		return gorm.ErrDuplicatedKey // Or custom error
 	}
 
-	return r.db.WithContext(ctx).Create(appointment).Error
+	return conn(ctx, r.db).Create(appointment).Error
 }
 
 func (r *AppointmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
 	var appt domain.Appointment
-	err := r.db.WithContext(ctx).Preload("Client").Where("id = ?", id).First(&appt).Error
+	err := conn(ctx, r.db).Preload("Client").Where("id = ?", id).First(&appt).Error
 	return &appt, err
 }
 
 func (r *AppointmentRepository) Update(ctx context.Context, appointment *domain.Appointment) error {
-	return r.db.WithContext(ctx).Save(appointment).Error
+	return conn(ctx, r.db).Save(appointment).Error
+}
+
+func (r *AppointmentRepository) UpdateIf(ctx context.Context, appointment *domain.Appointment, status domain.AppointmentStatus, paymentStatus domain.PaymentStatus) (bool, error) {
+	result := conn(ctx, r.db).Model(appointment).
+		Where("status = ? AND COALESCE(payment_status, '') = ?", status, paymentStatus).
+		Select("*").Omit(clause.Associations, "created_at").
+		Updates(appointment)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
 func (r *AppointmentRepository) ListByDateRange(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
 	var appts []domain.Appointment
-	err := r.db.WithContext(ctx).Preload("Client").
+	err := conn(ctx, r.db).Preload("Client").
 		Where("start_time >= ? AND start_time < ?", start, end).
 		Find(&appts).Error
 	return appts, err
 }
 
+func (r *AppointmentRepository) ListByBarber(ctx context.Context, barberID uuid.UUID, start, end time.Time) ([]domain.Appointment, error) {
+	var appts []domain.Appointment
+	err := conn(ctx, r.db).Preload("Client").
+		Where("barber_id = ? AND start_time >= ? AND start_time < ?", barberID, start, end).
+		Order("start_time ASC").
+		Find(&appts).Error
+	return appts, err
+}
+
+func (r *AppointmentRepository) ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.Appointment, error) {
+	var appts []domain.Appointment
+	err := conn(ctx, r.db).
+		Where("client_id = ?", clientID).
+		Order("start_time DESC").
+		Find(&appts).Error
+	return appts, err
+}
+
 func (r *AppointmentRepository) CountByMonth(ctx context.Context, month time.Month, year int) (int64, error) {
 	var count int64
 	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 	end := start.AddDate(0, 1, 0)
-	err := r.db.WithContext(ctx).Model(&domain.Appointment{}).
+	err := conn(ctx, r.db).Model(&domain.Appointment{}).
 		Where("start_time >= ? AND start_time < ?", start, end).
 		Count(&count).Error
 	return count, err
@@ -73,16 +103,62 @@ func (r *AppointmentRepository) CountCompletedByMonth(ctx context.Context, month
 	var count int64
 	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 	end := start.AddDate(0, 1, 0)
-	err := r.db.WithContext(ctx).Model(&domain.Appointment{}).
-		Where("status = ? AND start_time >= ? AND start_time < ?", domain.StatusConfirmed, start, end). // Assuming Confirmed = Completed for now
+	err := conn(ctx, r.db).Model(&domain.Appointment{}).
+		Where("status = ? AND start_time >= ? AND start_time < ?", domain.StatusCompleted, start, end).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *AppointmentRepository) CountNoShowsByMonth(ctx context.Context, month time.Month, year int) (int64, error) {
+	var count int64
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	err := conn(ctx, r.db).Model(&domain.Appointment{}).
+		Where("status = ? AND start_time >= ? AND start_time < ?", domain.StatusNoShow, start, end).
 		Count(&count).Error
 	return count, err
 }
 
 func (r *AppointmentRepository) CountByStatus(ctx context.Context, status domain.AppointmentStatus) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Appointment{}).
+	err := conn(ctx, r.db).Model(&domain.Appointment{}).
 		Where("status = ?", status).
 		Count(&count).Error
 	return count, err
 }
+
+func (r *AppointmentRepository) EachForExport(ctx context.Context, filter domain.AppointmentExportFilter, fn func(row *domain.AppointmentExportRow) error) error {
+	payments := r.db.Model(&domain.Payment{}).
+		Select("appointment_id, SUM(amount) AS paid, SUM(tip) AS tips, SUM(discount) AS discounts").
+		Group("appointment_id")
+
+	q := conn(ctx, r.db).Table("appointments AS a").
+		Select(`a.id, a.start_time, a.end_time, a.status, a.service, a.notes,
+			u.name AS client_name, u.email AS client_email, u.phone AS client_phone,
+			a.deposit_amount, a.payment_status,
+			COALESCE(p.paid, 0) AS paid, COALESCE(p.tips, 0) AS tips, COALESCE(p.discounts, 0) AS discounts`).
+		Joins("LEFT JOIN users u ON u.id = a.client_id").
+		Joins("LEFT JOIN (?) p ON p.appointment_id = a.id", payments).
+		Where("a.start_time >= ? AND a.start_time < ?", filter.From, filter.To).
+		Order("a.start_time ASC")
+	if len(filter.Statuses) > 0 {
+		q = q.Where("a.status IN ?", filter.Statuses)
+	}
+
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row domain.AppointmentExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
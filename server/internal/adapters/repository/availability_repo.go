@@ -20,19 +20,19 @@ func NewAvailabilityRepository(db *gorm.DB) ports.AvailabilityRepository {
 func (r *AvailabilityRepository) Save(ctx context.Context, availability *domain.Availability) error {
 	// If exists for date, update. Else create.
 	var existing domain.Availability
-	err := r.db.WithContext(ctx).Where("date = ?", availability.Date).First(&existing).Error
+	err := conn(ctx, r.db).Where("date = ?", availability.Date).First(&existing).Error
 	if err == nil {
 		// Update
 		availability.ID = existing.ID
-		return r.db.WithContext(ctx).Save(availability).Error
+		return conn(ctx, r.db).Save(availability).Error
 	}
 	// Create
-	return r.db.WithContext(ctx).Create(availability).Error
+	return conn(ctx, r.db).Create(availability).Error
 }
 
 func (r *AvailabilityRepository) GetByDate(ctx context.Context, date string) (*domain.Availability, error) {
 	var avail domain.Availability
-	err := r.db.WithContext(ctx).Where("date = ?", date).First(&avail).Error
+	err := conn(ctx, r.db).Where("date = ?", date).First(&avail).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -41,10 +41,10 @@ func (r *AvailabilityRepository) GetByDate(ctx context.Context, date string) (*d
 
 func (r *AvailabilityRepository) ListAll(ctx context.Context) ([]domain.Availability, error) {
 	var avails []domain.Availability
-	err := r.db.WithContext(ctx).Find(&avails).Error
+	err := conn(ctx, r.db).Find(&avails).Error
 	return avails, err
 }
 
 func (r *AvailabilityRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&domain.Availability{}, id).Error
+	return conn(ctx, r.db).Delete(&domain.Availability{}, id).Error
 }
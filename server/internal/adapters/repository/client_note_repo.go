@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type ClientNoteRepository struct {
+	db *gorm.DB
+}
+
+func NewClientNoteRepository(db *gorm.DB) ports.ClientNoteRepository {
+	return &ClientNoteRepository{db: db}
+}
+
+func (r *ClientNoteRepository) Create(ctx context.Context, note *domain.ClientNote) error {
+	return conn(ctx, r.db).Omit("Author").Create(note).Error
+}
+
+func (r *ClientNoteRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.ClientNote, error) {
+	var note domain.ClientNote
+	err := conn(ctx, r.db).Preload("Author").Where("id = ?", id).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (r *ClientNoteRepository) Update(ctx context.Context, note *domain.ClientNote) error {
+	return conn(ctx, r.db).Omit("Author").Save(note).Error
+}
+
+func (r *ClientNoteRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return conn(ctx, r.db).Delete(&domain.ClientNote{}, "id = ?", id).Error
+}
+
+func (r *ClientNoteRepository) ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.ClientNote, error) {
+	var notes []domain.ClientNote
+	err := conn(ctx, r.db).Preload("Author").
+		Where("client_id = ?", clientID).
+		Order("created_at DESC").
+		Find(&notes).Error
+	return notes, err
+}
@@ -9,17 +9,22 @@ import (
 )
 
 func NewDB(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError maps unique violations to gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
 
 	// AutoMigrate
-	err = db.AutoMigrate(&domain.User{}, &domain.Availability{}, &domain.Appointment{})
+	err = db.AutoMigrate(&domain.User{}, &domain.Availability{}, &domain.Appointment{}, &domain.Settings{}, &domain.WaitlistEntry{}, &domain.SlotHold{}, &domain.AppointmentEvent{}, &domain.Payment{}, &domain.ClientNote{}, &domain.OneTimeCode{}, &domain.Session{}, &domain.OIDCLogin{}, &domain.UserIdentity{}, &domain.RateCounter{}, &domain.TwoFactorLogin{}, &domain.RecoveryCode{}, &domain.PendingRegistration{})
 	if err != nil {
 		log.Printf("Error migrating database: %v", err)
 		return nil, err
 	}
+	if err := migrate(db); err != nil {
+		log.Printf("Error migrating database: %v", err)
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -0,0 +1,68 @@
+package repository
+
+import "gorm.io/gorm"
+
+// migrations are changes AutoMigrate cannot make, such as altering an existing index.
+// Each statement must be idempotent: they all run on every start, after AutoMigrate.
+var migrations = []string{
+	// Imported clients may have no email, so only non-empty emails must be unique
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'users' AND indexname = 'idx_users_email' AND indexdef NOT LIKE '%WHERE%') THEN
+			DROP INDEX idx_users_email;
+			CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE email <> '';
+		END IF;
+	END $$`,
+
+	// Emails are unique regardless of case. Existing case duplicates must be merged
+	// first; until then the previous index stays and a warning is logged on start.
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'users' AND indexname = 'idx_users_email' AND indexdef LIKE '%lower(%') THEN
+			RETURN;
+		END IF;
+		IF EXISTS (SELECT 1 FROM users WHERE email <> '' GROUP BY lower(trim(email)) HAVING COUNT(*) > 1) THEN
+			RAISE WARNING 'some users share an email in different case: merge them to enable case-insensitive unique emails';
+			RETURN;
+		END IF;
+		UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+		DROP INDEX IF EXISTS idx_users_email;
+		CREATE UNIQUE INDEX idx_users_email ON users (lower(email)) WHERE email <> '';
+	END $$`,
+
+	// Imported notes moved from users.notes to the private client_notes
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'notes') THEN
+			INSERT INTO client_notes (client_id, body, created_at, updated_at)
+				SELECT id, notes, created_at, created_at FROM users WHERE notes IS NOT NULL AND notes <> '';
+			ALTER TABLE users DROP COLUMN notes;
+		END IF;
+	END $$`,
+
+	// Holds may not overlap at all, not only start at the same time. Holds last
+	// minutes, so the existing ones are dropped rather than checked for overlaps.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'slot_holds_no_overlap') THEN
+			DELETE FROM slot_holds;
+			ALTER TABLE slot_holds ADD CONSTRAINT slot_holds_no_overlap
+				EXCLUDE USING gist (tstzrange(start_time, end_time) WITH &&);
+		END IF;
+	END $$`,
+
+	// Accent-insensitive client search: unaccent is not IMMUTABLE, so it is wrapped to
+	// be usable in the trigram index that serves the LIKE '%term%' searches
+	`CREATE EXTENSION IF NOT EXISTS unaccent`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+		AS $$ SELECT public.unaccent('public.unaccent', $1) $$
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search ON users
+		USING gin (immutable_unaccent(lower(name || ' ' || email || ' ' || phone)) gin_trgm_ops)`,
+}
+
+func migrate(db *gorm.DB) error {
+	for _, stmt := range migrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
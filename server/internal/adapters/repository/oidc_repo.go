@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OIDCLoginRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCLoginRepository(db *gorm.DB) ports.OIDCLoginRepository {
+	return &OIDCLoginRepository{db: db}
+}
+
+func (r *OIDCLoginRepository) Create(ctx context.Context, login *domain.OIDCLogin) error {
+	return conn(ctx, r.db).Create(login).Error
+}
+
+func (r *OIDCLoginRepository) Consume(ctx context.Context, stateHash string) (*domain.OIDCLogin, error) {
+	// DELETE ... RETURNING: of two concurrent callbacks, only one gets the row
+	var login domain.OIDCLogin
+	res := conn(ctx, r.db).Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).Delete(&login)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &login, nil
+}
+
+func (r *OIDCLoginRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.OIDCLogin{})
+	return res.RowsAffected, res.Error
+}
+
+type UserIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) ports.UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+func (r *UserIdentityRepository) GetBySubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	if err := conn(ctx, r.db).First(&identity, "provider = ? AND subject = ?", provider, subject).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *UserIdentityRepository) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	return conn(ctx, r.db).Create(identity).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OneTimeCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewOneTimeCodeRepository(db *gorm.DB) ports.OneTimeCodeRepository {
+	return &OneTimeCodeRepository{db: db}
+}
+
+func (r *OneTimeCodeRepository) Create(ctx context.Context, code *domain.OneTimeCode) error {
+	return conn(ctx, r.db).Create(code).Error
+}
+
+func (r *OneTimeCodeRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.OneTimeCode, error) {
+	var code domain.OneTimeCode
+	if err := conn(ctx, r.db).First(&code, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *OneTimeCodeRepository) IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	// UPDATE ... RETURNING: concurrent guesses each see their own count
+	var code domain.OneTimeCode
+	res := conn(ctx, r.db).Model(&code).Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("id = ?", id).UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return code.Attempts, nil
+}
+
+func (r *OneTimeCodeRepository) Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	res := conn(ctx, r.db).Model(&domain.OneTimeCode{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		UpdateColumn("consumed_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *OneTimeCodeRepository) CountSince(ctx context.Context, purpose domain.CodePurpose, destination string, since time.Time) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&domain.OneTimeCode{}).
+		Where("purpose = ? AND destination = ? AND created_at >= ?", purpose, destination, since).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *OneTimeCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.OneTimeCode{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) ports.PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	return conn(ctx, r.db).Create(payment).Error
+}
+
+func (r *PaymentRepository) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := conn(ctx, r.db).
+		Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *PaymentRepository) TotalsByMethod(ctx context.Context, start, end time.Time) ([]domain.PaymentTotals, error) {
+	var totals []domain.PaymentTotals
+	err := conn(ctx, r.db).Model(&domain.Payment{}).
+		Select(`method,
+			COUNT(*) AS payments,
+			COALESCE(SUM(amount), 0) AS amount,
+			COALESCE(SUM(tip), 0) AS tips,
+			COALESCE(SUM(discount), 0) AS discounts,
+			COALESCE(SUM(amount + tip), 0) AS total`).
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("method").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *PaymentRepository) Summarize(ctx context.Context, start, end time.Time) (*domain.RevenueSummary, error) {
+	var summary domain.RevenueSummary
+	err := conn(ctx, r.db).Model(&domain.Payment{}).
+		Select(`COALESCE(SUM(amount), 0) AS revenue,
+			COALESCE(SUM(tip), 0) AS tips,
+			COALESCE(SUM(discount), 0) AS discounts,
+			COUNT(DISTINCT appointment_id) AS tickets`).
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Scan(&summary).Error
+	return &summary, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+// RateLimitRepository shares rate limit counters between API instances.
+type RateLimitRepository struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) ports.RateLimitStore {
+	return &RateLimitRepository{db: db}
+}
+
+func (r *RateLimitRepository) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (*domain.RateCounter, error) {
+	// A single upsert, so concurrent hits from several instances all count
+	var counter domain.RateCounter
+	err := conn(ctx, r.db).Raw(`
+		INSERT INTO rate_counters (key, count, last_hit_at, reset_at) VALUES (@key, 1, @now, @reset)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_counters.reset_at <= @now THEN 1 ELSE rate_counters.count + 1 END,
+			reset_at = CASE WHEN rate_counters.reset_at <= @now THEN @reset ELSE rate_counters.reset_at END,
+			last_hit_at = @now
+		RETURNING key, count, last_hit_at, reset_at`,
+		map[string]interface{}{"key": key, "now": now.UTC(), "reset": now.Add(window).UTC()},
+	).Scan(&counter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &counter, nil
+}
+
+func (r *RateLimitRepository) Get(ctx context.Context, key string, now time.Time) (*domain.RateCounter, error) {
+	var counter domain.RateCounter
+	err := conn(ctx, r.db).First(&counter, "key = ? AND reset_at > ?", key, now.UTC()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &counter, nil
+}
+
+func (r *RateLimitRepository) Delete(ctx context.Context, key string) error {
+	return conn(ctx, r.db).Delete(&domain.RateCounter{}, "key = ?", key).Error
+}
+
+func (r *RateLimitRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("reset_at < ?", before.UTC()).Delete(&domain.RateCounter{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PendingRegistrationRepository struct {
+	db *gorm.DB
+}
+
+func NewPendingRegistrationRepository(db *gorm.DB) ports.PendingRegistrationRepository {
+	return &PendingRegistrationRepository{db: db}
+}
+
+func (r *PendingRegistrationRepository) Create(ctx context.Context, registration *domain.PendingRegistration) error {
+	return conn(ctx, r.db).Create(registration).Error
+}
+
+func (r *PendingRegistrationRepository) Consume(ctx context.Context, tokenHash string) (*domain.PendingRegistration, error) {
+	// DELETE ... RETURNING: of two concurrent verifications, only one gets the row
+	var registration domain.PendingRegistration
+	res := conn(ctx, r.db).Clauses(clause.Returning{}).
+		Where("token_hash = ?", tokenHash).Delete(&registration)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &registration, nil
+}
+
+func (r *PendingRegistrationRepository) LatestByUser(ctx context.Context, userID uuid.UUID) (*domain.PendingRegistration, error) {
+	var registration domain.PendingRegistration
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("created_at DESC").First(&registration).Error; err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+func (r *PendingRegistrationRepository) UpdateToken(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	return conn(ctx, r.db).Model(&domain.PendingRegistration{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"token_hash": tokenHash, "expires_at": expiresAt}).Error
+}
+
+func (r *PendingRegistrationRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.PendingRegistration{}).Error
+}
+
+func (r *PendingRegistrationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.PendingRegistration{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) ports.SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	return conn(ctx, r.db).Create(session).Error
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Session, error) {
+	var session domain.Session
+	if err := conn(ctx, r.db).First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash string, usedAt, expiresAt time.Time, ip string) (bool, error) {
+	res := conn(ctx, r.db).Model(&domain.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"last_used_at":       usedAt,
+			"expires_at":         expiresAt,
+			"ip":                 ip,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id uuid.UUID, reason string, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": at, "revoked_reason": reason}).Error
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID, reason string, at time.Time) (int64, error) {
+	res := conn(ctx, r.db).Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": at, "revoked_reason": reason})
+	return res.RowsAffected, res.Error
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.Session{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type SettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewSettingsRepository(db *gorm.DB) ports.SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) Get(ctx context.Context) (*domain.Settings, error) {
+	var settings domain.Settings
+	err := conn(ctx, r.db).Where("id = ?", domain.SettingsID).First(&settings).Error
+	if err == gorm.ErrRecordNotFound {
+		return domain.DefaultSettings(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *SettingsRepository) Save(ctx context.Context, settings *domain.Settings) error {
+	settings.ID = domain.SettingsID
+	return conn(ctx, r.db).Save(settings).Error
+}
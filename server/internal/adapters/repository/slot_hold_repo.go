@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type SlotHoldRepository struct {
+	db *gorm.DB
+}
+
+func NewSlotHoldRepository(db *gorm.DB) ports.SlotHoldRepository {
+	return &SlotHoldRepository{db: db}
+}
+
+func (r *SlotHoldRepository) Create(ctx context.Context, hold *domain.SlotHold) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// An expired hold that the sweeper has not removed yet must not block the slot.
+		if err := tx.Where("start_time < ? AND end_time > ? AND expires_at < ?", hold.EndTime, hold.StartTime, time.Now().UTC()).
+			Delete(&domain.SlotHold{}).Error; err != nil {
+			return err
+		}
+		// The exclusion constraint on the held time range rejects overlapping holds,
+		// concurrent ones from any instance included.
+		err := tx.Create(hold).Error
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation {
+			return gorm.ErrDuplicatedKey
+		}
+		return err
+	})
+}
+
+// exclusionViolation is the Postgres error code of a row conflicting with an EXCLUDE
+// constraint, which TranslateError does not map.
+const exclusionViolation = "23P01"
+
+func (r *SlotHoldRepository) GetByTokenHash(ctx context.Context, hash string) (*domain.SlotHold, error) {
+	var hold domain.SlotHold
+	err := conn(ctx, r.db).Where("token_hash = ?", hash).First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *SlotHoldRepository) ListActiveByDateRange(ctx context.Context, start, end, now time.Time) ([]domain.SlotHold, error) {
+	var holds []domain.SlotHold
+	err := conn(ctx, r.db).
+		Where("start_time >= ? AND start_time < ? AND expires_at >= ?", start, end, now).
+		Find(&holds).Error
+	return holds, err
+}
+
+func (r *SlotHoldRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return conn(ctx, r.db).Delete(&domain.SlotHold{}, id).Error
+}
+
+func (r *SlotHoldRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", now).Delete(&domain.SlotHold{})
+	return res.RowsAffected, res.Error
+}
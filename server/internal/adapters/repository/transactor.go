@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) ports.Transactor {
+	return &Transactor{db: db}
+}
+
+// InTransaction runs fn with a ctx carrying the transaction. Nested calls join the
+// outer transaction through a savepoint.
+func (t *Transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn is the handle repositories query with: the transaction in ctx if there is
+// one, so their writes commit or roll back together, or else db.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorLoginRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorLoginRepository(db *gorm.DB) ports.TwoFactorLoginRepository {
+	return &TwoFactorLoginRepository{db: db}
+}
+
+func (r *TwoFactorLoginRepository) Create(ctx context.Context, login *domain.TwoFactorLogin) error {
+	return conn(ctx, r.db).Create(login).Error
+}
+
+func (r *TwoFactorLoginRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.TwoFactorLogin, error) {
+	var login domain.TwoFactorLogin
+	if err := conn(ctx, r.db).First(&login, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (r *TwoFactorLoginRepository) IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	// UPDATE ... RETURNING: concurrent guesses each see their own count
+	var login domain.TwoFactorLogin
+	res := conn(ctx, r.db).Model(&login).Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("id = ?", id).UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return login.Attempts, nil
+}
+
+func (r *TwoFactorLoginRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return conn(ctx, r.db).Delete(&domain.TwoFactorLogin{}, "id = ?", id).Error
+}
+
+func (r *TwoFactorLoginRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).Where("expires_at < ?", before).Delete(&domain.TwoFactorLogin{})
+	return res.RowsAffected, res.Error
+}
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) ports.RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+func (r *RecoveryCodeRepository) Replace(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = domain.RecoveryCode{ID: uuid.New(), UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepository) Use(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	res := conn(ctx, r.db).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		UpdateColumn("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *RecoveryCodeRepository) CountUnused(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return int(count), err
+}
+
+func (r *RecoveryCodeRepository) DeleteAll(ctx context.Context, userID uuid.UUID) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
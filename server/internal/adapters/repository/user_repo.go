@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -18,12 +24,13 @@ func NewUserRepository(db *gorm.DB) ports.UserRepository {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	user.Email = domain.NormalizeEmail(user.Email)
+	return conn(ctx, r.db).Create(user).Error
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	var user domain.User
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	err := conn(ctx, r.db).Where("id = ?", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,22 +39,248 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := conn(ctx, r.db).Where("lower(email) = ?", domain.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) ListClients(ctx context.Context, limit, offset int) ([]domain.User, error) {
+func (r *UserRepository) GetByPhone(ctx context.Context, phones ...string) (*domain.User, error) {
+	var user domain.User
+	err := conn(ctx, r.db).
+		Where("regexp_replace(phone, '[^0-9]', '', 'g') IN ? AND merged_into_id IS NULL", phones).
+		Order("created_at ASC").
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+const (
+	defaultClientPage = 50
+	maxClientPage     = 200
+)
+
+// clientSortColumns are the keyset expressions; never-visited clients sort as -infinity.
+var clientSortColumns = map[domain.ClientSort]string{
+	domain.SortByName:      "name",
+	domain.SortByCreatedAt: "created_at",
+	domain.SortByLastVisit: "COALESCE(last_visit_at, '-infinity')",
+}
+
+// clientCursor is the position after the last client of a page: its sort value and ID,
+// and the order it belongs to, so it cannot be used with another one.
+type clientCursor struct {
+	Sort  domain.ClientSort `json:"s"`
+	Desc  bool              `json:"d,omitempty"`
+	Value string            `json:"v"`
+	ID    uuid.UUID         `json:"id"`
+}
+
+func (r *UserRepository) SearchClients(ctx context.Context, search domain.ClientSearch) (*domain.ClientPage, error) {
+	if !search.Sort.Valid() {
+		search.Sort = domain.SortByName
+	}
+	if search.Limit <= 0 {
+		search.Limit = defaultClientPage
+	}
+	if search.Limit > maxClientPage {
+		search.Limit = maxClientPage
+	}
+
+	q := r.filterClients(conn(ctx, r.db).Model(&domain.User{}), search)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	sortColumn := clientSortColumns[search.Sort]
+	direction, cmp := "ASC", ">"
+	if search.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	page := q.Session(&gorm.Session{})
+	if search.Cursor != "" {
+		cursor, err := decodeClientCursor(search.Cursor, search.Sort, search.Desc)
+		if err != nil {
+			return nil, err
+		}
+		value := interface{}(cursor.Value)
+		if search.Sort != domain.SortByName {
+			value = gorm.Expr("CAST(? AS timestamptz)", cursor.Value)
+		}
+		page = page.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, cmp), value, cursor.ID)
+	}
+
+	// Fetch one extra row to know whether there is a next page
 	var users []domain.User
-	err := r.db.WithContext(ctx).Where("role = ?", domain.RoleClient).Limit(limit).Offset(offset).Find(&users).Error
+	err := page.Order(fmt.Sprintf("%s %s, id %s", sortColumn, direction, direction)).
+		Limit(search.Limit + 1).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ClientPage{Clients: users, Total: total}
+	if len(users) > search.Limit {
+		result.Clients = users[:search.Limit]
+		result.NextCursor = encodeClientCursor(search.Sort, search.Desc, result.Clients[search.Limit-1])
+	}
+	return result, nil
+}
+
+func (r *UserRepository) filterClients(q *gorm.DB, search domain.ClientSearch) *gorm.DB {
+	q = q.Where("role = ? AND merged_into_id IS NULL", domain.RoleClient)
+
+	// Each word must appear somewhere; the expression matches idx_users_search
+	for _, term := range strings.Fields(search.Query) {
+		pattern := "%" + escapeLike(term) + "%"
+		cond := "immutable_unaccent(lower(name || ' ' || email || ' ' || phone)) LIKE immutable_unaccent(lower(?))"
+		if digits := phoneDigits(term); digits != "" {
+			// Phones may be stored with separators: also compare digits only
+			q = q.Where(r.db.Where(cond, pattern).Or("regexp_replace(phone, '[^0-9]', '', 'g') LIKE ?", "%"+digits+"%"))
+		} else {
+			q = q.Where(cond, pattern)
+		}
+	}
+
+	if search.MinNoShows > 0 {
+		q = q.Where("no_show_count >= ?", search.MinNoShows)
+	}
+	if search.MinLateCancels > 0 {
+		q = q.Where("late_cancel_count >= ?", search.MinLateCancels)
+	}
+	if search.BookingBlocked != nil {
+		q = q.Where("booking_blocked = ?", *search.BookingBlocked)
+	}
+	if search.Verified != nil {
+		q = q.Where("is_verified = ?", *search.Verified)
+	}
+	if search.HasUpcoming != nil {
+		upcoming := "EXISTS (SELECT 1 FROM appointments a WHERE a.client_id = users.id AND a.start_time > now() AND a.status IN ?)"
+		statuses := []domain.AppointmentStatus{domain.StatusPending, domain.StatusConfirmed}
+		if *search.HasUpcoming {
+			q = q.Where(upcoming, statuses)
+		} else {
+			q = q.Where("NOT "+upcoming, statuses)
+		}
+	}
+	if search.LastVisitBefore != nil {
+		q = q.Where("last_visit_at < ?", *search.LastVisitBefore)
+	}
+	if search.Active != nil {
+		if *search.Active {
+			q = q.Where("deactivated_at IS NULL")
+		} else {
+			q = q.Where("deactivated_at IS NOT NULL")
+		}
+	}
+	return q
+}
+
+func encodeClientCursor(sort domain.ClientSort, desc bool, last domain.User) string {
+	cursor := clientCursor{Sort: sort, Desc: desc, ID: last.ID}
+	switch sort {
+	case domain.SortByCreatedAt:
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case domain.SortByLastVisit:
+		cursor.Value = "-infinity"
+		if last.LastVisitAt != nil {
+			cursor.Value = last.LastVisitAt.Format(time.RFC3339Nano)
+		}
+	default:
+		cursor.Value = last.Name
+	}
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeClientCursor rejects cursors of another order, and values that are not
+// timestamps for the date orders, which would otherwise reach the query.
+func decodeClientCursor(raw string, sort domain.ClientSort, desc bool) (*clientCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	var cursor clientCursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, domain.ErrInvalidCursor
+	}
+	if sort != domain.SortByName && !(sort == domain.SortByLastVisit && cursor.Value == "-infinity") {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// phoneDigits returns the digits of a term that looks like (part of) a phone number.
+func phoneDigits(term string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, term)
+	if len(digits) < 3 || strings.Trim(term, "0123456789-+() .") != "" {
+		return ""
+	}
+	return digits
+}
+
+func (r *UserRepository) ListStaff(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := conn(ctx, r.db).
+		Where("role <> ? AND merged_into_id IS NULL", domain.RoleClient).
+		Order("name ASC").
+		Find(&users).Error
 	return users, err
 }
 
-func (r *UserRepository) GetByVerificationToken(ctx context.Context, token string) (*domain.User, error) {
+func (r *UserRepository) GetByPasswordResetHash(ctx context.Context, hash string) (*domain.User, error) {
+	var user domain.User
+	err := conn(ctx, r.db).Where("password_reset_hash = ?", hash).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) ResetPassword(ctx context.Context, hash, password string, at time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("password_reset_hash = ? AND password_reset_expires_at > ? AND deactivated_at IS NULL", hash, at).
+		Updates(map[string]interface{}{
+			"password":                  password,
+			"password_reset_hash":       "",
+			"password_reset_expires_at": nil,
+			"sessions_revoked_at":       at,
+			"is_verified":               true,
+			"is_guest":                  false,
+			"verification_hash":         "",
+			"verification_expires_at":   nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) GetByVerificationHash(ctx context.Context, hash string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.WithContext(ctx).Where("verification_token = ?", token).First(&user).Error
+	err := conn(ctx, r.db).Where("verification_hash = ?", hash).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -55,5 +288,286 @@ func (r *UserRepository) GetByVerificationToken(ctx context.Context, token strin
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	user.Email = domain.NormalizeEmail(user.Email)
+	return conn(ctx, r.db).Save(user).Error
+}
+
+func (r *UserRepository) UpdateVerificationToken(ctx context.Context, id uuid.UUID, hash string, expiresAt time.Time) error {
+	return conn(ctx, r.db).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"verification_hash":       hash,
+		"verification_expires_at": expiresAt,
+	}).Error
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, hash string, at time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("verification_hash = ? AND verification_expires_at > ?", hash, at).
+		Updates(map[string]interface{}{
+			"is_verified":             true,
+			"is_guest":                false,
+			"verification_hash":       "",
+			"verification_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND phone = ?", id, phone).
+		Update("phone_verified", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) ClaimGuest(ctx context.Context, id uuid.UUID, registration *domain.PendingRegistration) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND is_guest = ? AND deactivated_at IS NULL", id, true).
+		Updates(map[string]interface{}{
+			"name":                    registration.Name,
+			"phone":                   registration.Phone,
+			"password":                registration.Password,
+			"phone_verified":          false,
+			"is_verified":             true,
+			"is_guest":                false,
+			"verification_hash":       "",
+			"verification_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) ClaimEmail(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND is_verified = ? AND deactivated_at IS NULL", id, false).
+		Updates(map[string]interface{}{
+			"phone":                   gorm.Expr("CASE WHEN is_guest THEN phone ELSE '' END"),
+			"password":                "",
+			"phone_verified":          false,
+			"sessions_revoked_at":     at,
+			"is_verified":             true,
+			"is_guest":                false,
+			"verification_hash":       "",
+			"verification_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) IncrementPolicyCounters(ctx context.Context, id uuid.UUID, lateCancels, noShows int) error {
+	return conn(ctx, r.db).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"late_cancel_count": gorm.Expr("late_cancel_count + ?", lateCancels),
+		"no_show_count":     gorm.Expr("no_show_count + ?", noShows),
+		"policy_strikes":    gorm.Expr("policy_strikes + ?", lateCancels+noShows),
+	}).Error
+}
+
+func (r *UserRepository) BlockBooking(ctx context.Context, id uuid.UUID, minStrikes int) error {
+	return conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND NOT booking_blocked AND policy_strikes >= ?", id, minStrikes).
+		Update("booking_blocked", true).Error
+}
+
+func (r *UserRepository) ClearBookingBlock(ctx context.Context, id uuid.UUID) error {
+	result := conn(ctx, r.db).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"booking_blocked": false,
+		"policy_strikes":  0,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) RecordVisit(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND (last_visit_at IS NULL OR last_visit_at < ?)", id, at).
+		Update("last_visit_at", at).Error
+}
+
+func (r *UserRepository) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) StartTOTPEnrollment(ctx context.Context, id uuid.UUID, secret string) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND two_factor_enabled_at IS NULL", id).
+		Update("totp_pending_secret", secret)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) EnableTOTP(ctx context.Context, id uuid.UUID, pendingSecret string, step int64, at time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.User{}).
+		Where("id = ? AND totp_pending_secret = ? AND two_factor_enabled_at IS NULL", id, pendingSecret).
+		Updates(map[string]interface{}{
+			"totp_secret":           pendingSecret,
+			"totp_pending_secret":   "",
+			"totp_last_step":        step,
+			"two_factor_enabled_at": at,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return conn(ctx, r.db).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":           "",
+		"totp_pending_secret":   "",
+		"two_factor_enabled_at": nil,
+	}).Error
+}
+
+func (r *UserRepository) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID, check func(survivor, duplicate *domain.User) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var survivor, duplicate domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", survivorID).First(&survivor).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", duplicateID).First(&duplicate).Error; err != nil {
+			return err
+		}
+		if err := check(&survivor, &duplicate); err != nil {
+			return err
+		}
+
+		// Everything the duplicate owns or did moves to the survivor
+		moves := []struct{ table, column string }{
+			{"appointments", "client_id"},
+			{"client_notes", "client_id"},
+			{"client_notes", "author_id"},
+			{"appointment_events", "actor_id"},
+			{"payments", "recorded_by_id"},
+			{"user_identities", "user_id"},
+			{"sessions", "user_id"},
+			{"pending_registrations", "user_id"},
+		}
+		for _, m := range moves {
+			err := tx.Table(m.table).Where(m.column+" = ?", duplicateID).Update(m.column, survivorID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		email := duplicate.Email
+		takesEmail := inherit(&survivor, &duplicate)
+
+		// Free the duplicate's email before the survivor may take it
+		now := time.Now()
+		err := tx.Model(&domain.User{}).Where("id = ?", duplicateID).Updates(map[string]interface{}{
+			"email":          "",
+			"merged_into_id": survivorID,
+			"deactivated_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if takesEmail {
+			survivor.Email = email
+		}
+		if err := tx.Save(&survivor).Error; err != nil {
+			return err
+		}
+
+		// Waitlist entries point at the client by contact details: the open ones now
+		// reach the survivor. Slot holds belong to whoever has the token, not to a
+		// user, so they need no move.
+		var contacts []string
+		var args []interface{}
+		if email != "" {
+			contacts, args = append(contacts, "lower(client_email) = ?"), append(args, email)
+		}
+		if phone := phoneDigits(duplicate.Phone); phone != "" {
+			contacts, args = append(contacts, "regexp_replace(client_phone, '[^0-9]', '', 'g') = ?"), append(args, phone)
+		}
+		if len(contacts) == 0 {
+			return nil
+		}
+		return tx.Model(&domain.WaitlistEntry{}).
+			Where("status IN ?", []domain.WaitlistStatus{domain.WaitlistWaiting, domain.WaitlistOffered}).
+			Where("("+strings.Join(contacts, " OR ")+")", args...).
+			Updates(map[string]interface{}{
+				"client_name":  survivor.Name,
+				"client_email": survivor.Email,
+				"client_phone": survivor.Phone,
+			}).Error
+	})
+}
+
+// inherit gives survivor the duplicate's history and any contact data it lacks, except
+// the email, which it reports whether survivor takes over. A contact stays verified only
+// when it is the one verified.
+func inherit(survivor, duplicate *domain.User) bool {
+	survivor.LateCancelCount += duplicate.LateCancelCount
+	survivor.NoShowCount += duplicate.NoShowCount
+	survivor.PolicyStrikes += duplicate.PolicyStrikes
+	survivor.BookingBlocked = survivor.BookingBlocked || duplicate.BookingBlocked
+	if duplicate.LastVisitAt != nil && (survivor.LastVisitAt == nil || duplicate.LastVisitAt.After(*survivor.LastVisitAt)) {
+		survivor.LastVisitAt = duplicate.LastVisitAt
+	}
+
+	switch {
+	case survivor.Phone == "":
+		survivor.Phone = duplicate.Phone
+		survivor.PhoneVerified = duplicate.PhoneVerified
+	case phoneDigits(survivor.Phone) == phoneDigits(duplicate.Phone):
+		survivor.PhoneVerified = survivor.PhoneVerified || duplicate.PhoneVerified
+	}
+
+	if survivor.Email != "" || duplicate.Email == "" {
+		return false
+	}
+	survivor.IsVerified = duplicate.IsVerified
+	return true
+}
+
+func (r *UserRepository) EachClientForExport(ctx context.Context, fn func(row *domain.ClientExportRow) error) error {
+	visits := r.db.Model(&domain.Appointment{}).
+		Select("client_id, COUNT(*) AS visits").
+		Where("status = ?", domain.StatusCompleted).
+		Group("client_id")
+
+	rows, err := conn(ctx, r.db).Table("users AS u").
+		Select(`u.id, u.name, u.email, u.phone, u.is_verified, u.created_at, u.last_visit_at,
+			COALESCE(v.visits, 0) AS visits, u.no_show_count, u.late_cancel_count, u.booking_blocked`).
+		Joins("LEFT JOIN (?) v ON v.client_id = u.id", visits).
+		Where("u.role = ? AND u.merged_into_id IS NULL", domain.RoleClient).
+		Order("u.name ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row domain.ClientExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
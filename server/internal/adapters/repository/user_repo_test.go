@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func TestClientCursor_RoundTrip(t *testing.T) {
+	lastVisit := time.Date(2026, 5, 2, 10, 30, 0, 123, time.UTC)
+	user := domain.User{ID: uuid.New(), Name: "José Ñúñez", CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC), LastVisitAt: &lastVisit}
+
+	cases := []struct {
+		sort  domain.ClientSort
+		desc  bool
+		user  domain.User
+		value string
+	}{
+		{domain.SortByName, false, user, "José Ñúñez"},
+		{domain.SortByCreatedAt, true, user, "2026-01-02T03:04:05.000000006Z"},
+		{domain.SortByLastVisit, false, user, "2026-05-02T10:30:00.000000123Z"},
+		{domain.SortByLastVisit, true, domain.User{ID: user.ID}, "-infinity"},
+	}
+	for _, c := range cases {
+		cursor, err := decodeClientCursor(encodeClientCursor(c.sort, c.desc, c.user), c.sort, c.desc)
+		if err != nil {
+			t.Errorf("%s desc=%v: unexpected error: %v", c.sort, c.desc, err)
+			continue
+		}
+		if cursor.ID != user.ID || cursor.Value != c.value {
+			t.Errorf("%s desc=%v: expected %q, got %+v", c.sort, c.desc, c.value, cursor)
+		}
+	}
+}
+
+func TestClientCursor_RejectsMismatchedAndTampered(t *testing.T) {
+	user := domain.User{ID: uuid.New(), Name: "Ana", CreatedAt: time.Now()}
+	byName := encodeClientCursor(domain.SortByName, false, user)
+
+	if _, err := decodeClientCursor(byName, domain.SortByCreatedAt, false); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("expected a name cursor to be rejected for another sort, got %v", err)
+	}
+	if _, err := decodeClientCursor(byName, domain.SortByName, true); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("expected a cursor to be rejected for the other direction, got %v", err)
+	}
+
+	encode := func(cursor clientCursor) string {
+		b, _ := json.Marshal(cursor)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	cases := []struct {
+		name string
+		raw  string
+		sort domain.ClientSort
+	}{
+		{"not base64", "%%%", domain.SortByName},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("nope")), domain.SortByName},
+		{"no id", encode(clientCursor{Sort: domain.SortByName, Value: "Ana"}), domain.SortByName},
+		{"name as timestamp", encode(clientCursor{Sort: domain.SortByCreatedAt, Value: "Ana", ID: user.ID}), domain.SortByCreatedAt},
+		{"-infinity creation", encode(clientCursor{Sort: domain.SortByCreatedAt, Value: "-infinity", ID: user.ID}), domain.SortByCreatedAt},
+	}
+	for _, c := range cases {
+		if _, err := decodeClientCursor(c.raw, c.sort, false); !errors.Is(err, domain.ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", c.name, err)
+		}
+	}
+}
+
+func TestPhoneDigits(t *testing.T) {
+	cases := map[string]string{
+		"1155550000":       "1155550000",
+		"+54 9 11 5555-00": "54911555500",
+		"(011) 5555.0000":  "01155550000",
+		"555":              "555",
+		"55":               "",
+		"ana":              "",
+		"ana5555":          "",
+		"5555x":            "",
+	}
+	for term, want := range cases {
+		if got := phoneDigits(term); got != want {
+			t.Errorf("phoneDigits(%q) = %q, want %q", term, got, want)
+		}
+	}
+}
+
+func TestMergeInherit_VerifiedOnlyWithTheContact(t *testing.T) {
+	cases := []struct {
+		name                    string
+		survivor, duplicate     domain.User
+		takesEmail              bool
+		verified, phoneVerified bool
+		phone                   string
+	}{
+		{
+			name:       "keeps its own unverified email",
+			survivor:   domain.User{Email: "ana@example.com", Phone: "1155550000"},
+			duplicate:  domain.User{Email: "ana.old@example.com", IsVerified: true, Phone: "1166660000", PhoneVerified: true},
+			takesEmail: false, verified: false, phoneVerified: false, phone: "1155550000",
+		},
+		{
+			name:       "takes the email and phone it lacks with their verification",
+			survivor:   domain.User{IsVerified: true},
+			duplicate:  domain.User{Email: "ana@example.com", Phone: "1166660000", PhoneVerified: true},
+			takesEmail: true, verified: false, phoneVerified: true, phone: "1166660000",
+		},
+		{
+			name:       "same phone written differently",
+			survivor:   domain.User{Email: "ana@example.com", IsVerified: true, Phone: "11 5555-0000"},
+			duplicate:  domain.User{Phone: "1155550000", PhoneVerified: true},
+			takesEmail: false, verified: true, phoneVerified: true, phone: "11 5555-0000",
+		},
+	}
+	for _, c := range cases {
+		takesEmail := inherit(&c.survivor, &c.duplicate)
+		if takesEmail != c.takesEmail || c.survivor.IsVerified != c.verified || c.survivor.PhoneVerified != c.phoneVerified || c.survivor.Phone != c.phone {
+			t.Errorf("%s: got takesEmail=%v %+v", c.name, takesEmail, c.survivor)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) ports.WaitlistRepository {
+	return &WaitlistRepository{db: db}
+}
+
+func (r *WaitlistRepository) Create(ctx context.Context, entry *domain.WaitlistEntry) error {
+	return conn(ctx, r.db).Create(entry).Error
+}
+
+func (r *WaitlistRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WaitlistEntry, error) {
+	var entry domain.WaitlistEntry
+	err := conn(ctx, r.db).Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *WaitlistRepository) GetByClaimTokenHash(ctx context.Context, hash string) (*domain.WaitlistEntry, error) {
+	var entry domain.WaitlistEntry
+	err := conn(ctx, r.db).Where("claim_token_hash = ?", hash).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *WaitlistRepository) ListByDate(ctx context.Context, date string, statuses ...domain.WaitlistStatus) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	q := conn(ctx, r.db).Where("date = ?", date)
+	if len(statuses) > 0 {
+		q = q.Where("status IN ?", statuses)
+	}
+	err := q.Order("created_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *WaitlistRepository) ListExpiredOffers(ctx context.Context, now time.Time) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	err := conn(ctx, r.db).
+		Where("status = ? AND offer_expires_at < ?", domain.WaitlistOffered, now).
+		Order("offer_expires_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *WaitlistRepository) Update(ctx context.Context, entry *domain.WaitlistEntry) error {
+	return conn(ctx, r.db).Save(entry).Error
+}
+
+func (r *WaitlistRepository) UpdateIf(ctx context.Context, entry *domain.WaitlistEntry, status domain.WaitlistStatus) (bool, error) {
+	result := conn(ctx, r.db).Model(entry).Where("status = ?", status).
+		Select("*").Omit("created_at").
+		Updates(entry)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Actor is the authenticated user performing a request.
+type Actor struct {
+	ID   uuid.UUID
+	Role Role
+}
+
+// IsStaff reports whether the actor works at the shop, as opposed to a client booking online.
+func (a *Actor) IsStaff() bool {
+	return a != nil && a.Role.IsStaff()
+}
+
+// Can reports whether the actor's role grants p. Anonymous actors have no permissions.
+func (a *Actor) Can(p Permission) bool {
+	return a != nil && a.Role.Can(p)
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx carrying the authenticated user,
+// so services can attribute changes to them and apply role-specific rules.
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the authenticated user, or nil for anonymous requests.
+func ActorFromContext(ctx context.Context) *Actor {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok {
+		return nil
+	}
+	return &actor
+}
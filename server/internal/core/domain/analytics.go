@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// Grouping is the bucket size of an analytics report.
+type Grouping string
+
+const (
+	GroupByDay   Grouping = "day"
+	GroupByWeek  Grouping = "week" // Weeks start on Monday
+	GroupByMonth Grouping = "month"
+)
+
+func (g Grouping) Valid() bool {
+	return g == GroupByDay || g == GroupByWeek || g == GroupByMonth
+}
+
+// PeriodStats aggregates appointments, availability and payments over one period.
+// Appointments excludes rescheduled ones, which live on as their replacement.
+type PeriodStats struct {
+	PeriodStart      time.Time `json:"-"`
+	Period           string    `json:"period"` // First day of the period, YYYY-MM-DD
+	Appointments     int64     `json:"appointments"`
+	Completed        int64     `json:"completed"`
+	Cancelled        int64     `json:"cancelled"`
+	NoShows          int64     `json:"no_shows"`
+	BookedMinutes    int64     `json:"booked_minutes"`
+	AvailableMinutes int64     `json:"available_minutes"`
+	NewClients       int64     `json:"new_clients"`       // First visit falls in the period
+	ReturningClients int64     `json:"returning_clients"` // Visited before the period
+	Revenue          float64   `json:"revenue"`
+	Tips             float64   `json:"tips"`
+
+	OccupancyRate    float64 `json:"occupancy_rate"`    // Booked ÷ available minutes
+	CancellationRate float64 `json:"cancellation_rate"` // Cancelled ÷ appointments
+	NoShowRate       float64 `json:"no_show_rate"`      // No-shows ÷ (completed + no-shows)
+}
+
+// Add accumulates the counters of other into s. Rates must be recomputed afterwards.
+func (s *PeriodStats) Add(other PeriodStats) {
+	s.Appointments += other.Appointments
+	s.Completed += other.Completed
+	s.Cancelled += other.Cancelled
+	s.NoShows += other.NoShows
+	s.BookedMinutes += other.BookedMinutes
+	s.AvailableMinutes += other.AvailableMinutes
+	s.NewClients += other.NewClients
+	s.ReturningClients += other.ReturningClients
+	s.Revenue += other.Revenue
+	s.Tips += other.Tips
+}
+
+// ComputeRates fills the rate fields from the counters.
+func (s *PeriodStats) ComputeRates() {
+	s.OccupancyRate = ratio(s.BookedMinutes, s.AvailableMinutes)
+	s.CancellationRate = ratio(s.Cancelled, s.Appointments)
+	s.NoShowRate = ratio(s.NoShows, s.Completed+s.NoShows)
+}
+
+func ratio(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// HeatmapCell counts the appointments starting on a weekday (0 = Sunday) and hour.
+type HeatmapCell struct {
+	Weekday      int   `json:"weekday"`
+	Hour         int   `json:"hour"`
+	Appointments int64 `json:"appointments"`
+}
+
+// Analytics is the report for [From, To).
+type Analytics struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	GroupBy Grouping      `json:"group_by"`
+	Periods []PeriodStats `json:"periods"`
+	Totals  PeriodStats   `json:"totals"`
+	Heatmap []HeatmapCell `json:"heatmap"`
+}
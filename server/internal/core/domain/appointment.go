@@ -9,20 +9,75 @@ import (
 type AppointmentStatus string
 
 const (
-	StatusPending   AppointmentStatus = "pending"
-	StatusConfirmed AppointmentStatus = "confirmed"
-	StatusCancelled AppointmentStatus = "cancelled"
+	StatusPending     AppointmentStatus = "pending"
+	StatusConfirmed   AppointmentStatus = "confirmed"
+	StatusCancelled   AppointmentStatus = "cancelled"
+	StatusCompleted   AppointmentStatus = "completed"
+	StatusNoShow      AppointmentStatus = "no_show"
+	StatusRescheduled AppointmentStatus = "rescheduled" // Replaced by a new appointment at another time
 )
 
+// appointmentTransitions lists the statuses each status may move to.
+// Statuses missing from the map are final.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	StatusPending:   {StatusConfirmed, StatusCancelled, StatusRescheduled},
+	StatusConfirmed: {StatusCompleted, StatusNoShow, StatusCancelled, StatusRescheduled},
+}
+
+// CanTransitionTo reports whether an appointment in status s may move to next.
+func (s AppointmentStatus) CanTransitionTo(next AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// FreedStatuses are the statuses whose appointments no longer take up their slot.
+var FreedStatuses = []AppointmentStatus{StatusCancelled, StatusRescheduled}
+
+// OccupiesSlot reports whether an appointment in status s still blocks its time slot.
+func (s AppointmentStatus) OccupiesSlot() bool {
+	for _, freed := range FreedStatuses {
+		if s == freed {
+			return false
+		}
+	}
+	return true
+}
+
 type Appointment struct {
-	ID            uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	ClientID      uuid.UUID         `gorm:"type:uuid" json:"client_id"`
-	Client        User              `gorm:"foreignKey:ClientID" json:"client,omitempty"`
-	StartTime     time.Time         `json:"start_time"`
-	EndTime       time.Time         `json:"end_time"`
-	Status        AppointmentStatus `gorm:"default:'pending'" json:"status"`
-	GoogleEventID string            `json:"google_event_id,omitempty"`
-	Notes         string            `json:"notes,omitempty"`
-	CreatedAt     time.Time         `json:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
+	ID                uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ClientID          uuid.UUID         `gorm:"type:uuid" json:"client_id"`
+	Client            User              `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+	StartTime         time.Time         `json:"start_time"`
+	EndTime           time.Time         `json:"end_time"`
+	Status            AppointmentStatus `gorm:"default:'pending'" json:"status"`
+	GoogleEventID     string            `json:"google_event_id,omitempty"`
+	Service           string            `json:"service,omitempty"`
+	Notes             string            `json:"notes,omitempty"`
+	RescheduledFromID *uuid.UUID        `gorm:"type:uuid" json:"rescheduled_from_id,omitempty"` // Original appointment, when created by a reschedule
+	BarberID          *uuid.UUID        `gorm:"type:uuid;index" json:"barber_id,omitempty"`     // Staff member doing the appointment, once assigned
+
+	// Set at booking time for clients over the reliability threshold
+	RequiresApproval bool `json:"requires_approval"`
+	DepositRequired  bool `json:"deposit_required"`
+
+	// Deposit, when DepositRequired
+	DepositAmount      float64       `gorm:"type:numeric(12,2)" json:"deposit_amount,omitempty"`
+	PaymentStatus      PaymentStatus `json:"payment_status,omitempty"`
+	PaymentProviderID  string        `json:"-"` // Preference/intent ID
+	PaymentID          string        `json:"-"` // Provider payment ID, used for refunds
+	PaymentCheckoutURL string        `json:"payment_checkout_url,omitempty"`
+
+	// Cancellation details, set when Status is cancelled
+	CancelledBy        CancelledBy        `json:"cancelled_by,omitempty"`
+	CancellationReason CancellationReason `json:"cancellation_reason,omitempty"`
+	CancellationNote   string             `json:"cancellation_note,omitempty"`
+	CancelledAt        *time.Time         `json:"cancelled_at,omitempty"`
+	LateCancellation   bool               `json:"late_cancellation"` // Cancelled by the client inside the policy window
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AppointmentEventType string
+
+const (
+	EventCreated        AppointmentEventType = "created"
+	EventConfirmed      AppointmentEventType = "confirmed"
+	EventApproved       AppointmentEventType = "approved"
+	EventCancelled      AppointmentEventType = "cancelled"
+	EventRescheduled    AppointmentEventType = "rescheduled"
+	EventCompleted      AppointmentEventType = "completed"
+	EventNoShow         AppointmentEventType = "no_show"
+	EventNotesChanged   AppointmentEventType = "notes_changed"
+	EventPaymentTaken   AppointmentEventType = "payment_recorded"
+	EventBarberAssigned AppointmentEventType = "barber_assigned"
+)
+
+// statusEvents maps the status an appointment moves to onto the event recorded for it.
+var statusEvents = map[AppointmentStatus]AppointmentEventType{
+	StatusConfirmed:   EventConfirmed,
+	StatusCancelled:   EventCancelled,
+	StatusRescheduled: EventRescheduled,
+	StatusCompleted:   EventCompleted,
+	StatusNoShow:      EventNoShow,
+}
+
+// EventForStatus returns the event type recorded when an appointment moves to status.
+func EventForStatus(status AppointmentStatus) AppointmentEventType {
+	return statusEvents[status]
+}
+
+// AppointmentEvent is an append-only record of a change to an appointment.
+// Before and After hold the changed value (status, notes, start time) as text.
+type AppointmentEvent struct {
+	ID            uuid.UUID            `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppointmentID uuid.UUID            `gorm:"type:uuid;index" json:"appointment_id"`
+	Type          AppointmentEventType `json:"type"`
+	ActorID       *uuid.UUID           `gorm:"type:uuid" json:"actor_id,omitempty"` // Nil for guests and system actions
+	Actor         *User                `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	Before        string               `json:"before,omitempty"`
+	After         string               `json:"after,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
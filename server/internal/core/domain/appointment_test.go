@@ -0,0 +1,27 @@
+package domain
+
+import "testing"
+
+func TestAppointmentStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to AppointmentStatus
+		want     bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusCompleted, false},
+		{StatusConfirmed, StatusCompleted, true},
+		{StatusConfirmed, StatusNoShow, true},
+		{StatusConfirmed, StatusRescheduled, true},
+		{StatusCancelled, StatusConfirmed, false},
+		{StatusCompleted, StatusNoShow, false},
+		{StatusNoShow, StatusCompleted, false},
+		{StatusRescheduled, StatusConfirmed, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookingRequest holds what a client submits to book an appointment.
+// Client fields are ignored when booking for an existing client ID.
+type BookingRequest struct {
+	ClientName  string
+	ClientEmail string
+	ClientPhone string
+	StartTime   time.Time
+	Service     string // Free text, e.g. "corte y barba"
+	Notes       string
+	HoldToken   string     // Token of the client's slot hold, if any
+	BarberID    *uuid.UUID // Set by staff only
+
+	// VerifiedVia is the contact a guest proved they own with a one-time code or link.
+	// Guest bookings without it are rejected.
+	VerifiedVia ContactChannel
+}
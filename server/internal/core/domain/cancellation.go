@@ -0,0 +1,36 @@
+package domain
+
+// CancelledBy tells whether the client or the shop called off an appointment.
+type CancelledBy string
+
+const (
+	CancelledByClient CancelledBy = "client"
+	CancelledByShop   CancelledBy = "shop"
+)
+
+type CancellationReason string
+
+const (
+	ReasonScheduleConflict CancellationReason = "schedule_conflict"
+	ReasonIllness          CancellationReason = "illness"
+	ReasonEmergency        CancellationReason = "emergency"
+	ReasonShopUnavailable  CancellationReason = "shop_unavailable"
+	ReasonDuplicate        CancellationReason = "duplicate"
+	ReasonOther            CancellationReason = "other"
+)
+
+// Valid reports whether r is one of the known reason codes.
+func (r CancellationReason) Valid() bool {
+	switch r {
+	case ReasonScheduleConflict, ReasonIllness, ReasonEmergency, ReasonShopUnavailable, ReasonDuplicate, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// Cancellation describes why and by whom an appointment is cancelled.
+type Cancellation struct {
+	By     CancelledBy        `json:"cancelled_by" binding:"required,oneof=client shop"`
+	Reason CancellationReason `json:"reason" binding:"required"`
+	Note   string             `json:"note"`
+}
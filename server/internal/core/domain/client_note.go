@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClientNote is a private note the shop keeps about a client. Clients never see them.
+type ClientNote struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ClientID  uuid.UUID  `gorm:"type:uuid;index" json:"client_id"`
+	AuthorID  *uuid.UUID `gorm:"type:uuid" json:"author_id,omitempty"` // Nil for notes brought in by an import
+	Author    *User      `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	Body      string     `json:"body" binding:"required"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ClientProfile gathers what the shop knows about a client.
+type ClientProfile struct {
+	Client       User          `json:"client"`
+	TotalVisits  int64         `json:"total_visits"` // Completed appointments
+	LastVisitAt  *time.Time    `json:"last_visit_at,omitempty"`
+	LastService  string        `json:"last_service,omitempty"` // Service of the last completed appointment
+	Appointments []Appointment `json:"appointments"`           // Newest first
+	Notes        []ClientNote  `json:"notes"`                  // Newest first
+}
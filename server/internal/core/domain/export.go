@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+)
+
+func (f ExportFormat) Valid() bool {
+	return f == ExportCSV || f == ExportXLSX
+}
+
+func (f ExportFormat) ContentType() string {
+	if f == ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// AppointmentExportFilter selects appointments starting in [From, To). No statuses means all.
+type AppointmentExportFilter struct {
+	From     time.Time
+	To       time.Time
+	Statuses []AppointmentStatus
+}
+
+// AppointmentExportRow is an appointment joined with its client and register payments.
+type AppointmentExportRow struct {
+	ID            uuid.UUID
+	StartTime     time.Time
+	EndTime       time.Time
+	Status        AppointmentStatus
+	Service       string
+	Notes         string
+	ClientName    string
+	ClientEmail   string
+	ClientPhone   string
+	DepositAmount float64
+	PaymentStatus PaymentStatus
+	Paid          float64 // Sum of register payments, without tips
+	Tips          float64
+	Discounts     float64
+}
+
+// ClientExportRow is a client with their visit count.
+type ClientExportRow struct {
+	ID              uuid.UUID
+	Name            string
+	Email           string
+	Phone           string
+	IsVerified      bool
+	CreatedAt       time.Time
+	LastVisitAt     *time.Time
+	Visits          int64 // Completed appointments
+	NoShowCount     int64
+	LateCancelCount int64
+	BookingBlocked  bool
+}
@@ -0,0 +1,15 @@
+package domain
+
+type HolidayType string
+
+const (
+	HolidayFixed   HolidayType = "inamovible"  // Always observed on its calendar date
+	HolidayMovable HolidayType = "trasladable" // May be moved to a Monday by decree
+	HolidayBridge  HolidayType = "puente"      // "Feriado puente": non-working day for tourism purposes
+)
+
+type Holiday struct {
+	Date string      `json:"date"` // YYYY-MM-DD, same format as Availability.Date
+	Name string      `json:"name"`
+	Type HolidayType `json:"type"`
+}
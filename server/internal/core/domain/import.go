@@ -0,0 +1,31 @@
+package domain
+
+import "github.com/google/uuid"
+
+type ImportRowStatus string
+
+const (
+	ImportCreated   ImportRowStatus = "created"
+	ImportWouldAdd  ImportRowStatus = "would_create" // Dry run: the row is valid and new
+	ImportDuplicate ImportRowStatus = "duplicate"    // Matches an existing client or an earlier row
+	ImportInvalid   ImportRowStatus = "invalid"
+)
+
+// ImportRow is the outcome of one CSV row, with the values after normalization.
+type ImportRow struct {
+	Line   int             `json:"line"` // Line in the file, counting the header as 1
+	Name   string          `json:"name"`
+	Email  string          `json:"email,omitempty"`
+	Phone  string          `json:"phone,omitempty"`
+	Status ImportRowStatus `json:"status"`
+	Reason string          `json:"reason,omitempty"`
+	UserID *uuid.UUID      `json:"user_id,omitempty"` // Created user, or the existing duplicate
+}
+
+type ImportReport struct {
+	DryRun     bool        `json:"dry_run"`
+	Created    int         `json:"created"` // In a dry run, how many would be created
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Rows       []ImportRow `json:"rows"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OIDCLogin is a login started with an OpenID Connect provider, kept until the provider
+// redirects back. The state sent to the provider is only stored as a hash.
+type OIDCLogin struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Provider     string    `json:"provider"`
+	StateHash    string    `gorm:"uniqueIndex" json:"-"`
+	CodeVerifier string    `json:"-"` // PKCE secret, only its challenge was sent to the provider
+	Nonce        string    `json:"-"` // Must come back inside the ID token
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserIdentity links a user to their account at an external identity provider.
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	Provider  string    `gorm:"uniqueIndex:idx_user_identities_subject" json:"provider"`
+	Subject   string    `gorm:"uniqueIndex:idx_user_identities_subject" json:"subject"` // The provider's stable user id ("sub")
+	Email     string    `json:"email"`                                                  // As reported by the provider when linked
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCClaims are the ID token claims a login relies on, once the token is validated.
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCCallback is where a login ends: a new session, or a second factor to ask for,
+// for the user that was found, linked or created.
+type OIDCCallback struct {
+	LoginResult
+	Created bool
+}
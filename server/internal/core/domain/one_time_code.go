@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContactChannel is where a one-time code is delivered.
+type ContactChannel string
+
+const (
+	ChannelEmail    ContactChannel = "email"
+	ChannelWhatsApp ContactChannel = "whatsapp"
+)
+
+func (c ContactChannel) Valid() bool {
+	return c == ChannelEmail || c == ChannelWhatsApp
+}
+
+// CodePurpose scopes a code so one issued for one flow cannot be redeemed in another.
+type CodePurpose string
+
+const (
+	PurposeGuestBooking CodePurpose = "guest_booking"
+	PurposeLogin        CodePurpose = "login"
+	PurposeVerifyPhone  CodePurpose = "verify_phone"
+)
+
+// OneTimeCode is a short numeric code sent to an email or phone to prove the requester
+// owns it. Only the hash is stored; Payload keeps what to do once it is redeemed.
+type OneTimeCode struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Purpose     CodePurpose    `gorm:"index:idx_one_time_codes_destination" json:"purpose"`
+	Channel     ContactChannel `json:"channel"`
+	Destination string         `gorm:"index:idx_one_time_codes_destination" json:"-"`
+	CodeHash    string         `json:"-"`
+	Payload     string         `gorm:"type:text" json:"-"`
+	Attempts    int            `gorm:"default:0" json:"-"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	ConsumedAt  *time.Time     `json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// GuestBookingChallenge is returned when a guest asks to book: the booking is confirmed
+// by posting the code sent to Destination together with ID.
+type GuestBookingChallenge struct {
+	ID          uuid.UUID      `json:"id"`
+	Channel     ContactChannel `json:"channel"`
+	Destination string         `json:"destination"` // Masked, e.g. "a***@gmail.com"
+	ExpiresAt   time.Time      `json:"expires_at"`
+}
+
+// LoginChallenge is returned when a passwordless login is requested: the user logs in
+// by posting the code sent to Destination together with ID. It looks the same whether
+// or not the email or phone belongs to an account.
+type LoginChallenge struct {
+	ID          uuid.UUID      `json:"id"`
+	Channel     ContactChannel `json:"channel"`
+	Destination string         `json:"destination,omitempty"` // Masked, e.g. "***0018"
+	ExpiresAt   time.Time      `json:"expires_at"`
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentNone     PaymentStatus = ""        // No deposit involved
+	PaymentPending  PaymentStatus = "pending" // Waiting for the client to pay
+	PaymentPaid     PaymentStatus = "paid"
+	PaymentFailed   PaymentStatus = "failed" // Rejected or cancelled at the provider
+	PaymentRefunded PaymentStatus = "refunded"
+)
+
+// DepositCurrency is the currency deposits are charged in.
+const DepositCurrency = "ARS"
+
+// PaymentIntent is a payment started at the provider, waiting for the client.
+type PaymentIntent struct {
+	ProviderID  string // Preference/intent ID at the provider
+	CheckoutURL string // Where the client pays
+}
+
+// PaymentNotification is the verified content of a provider webhook.
+type PaymentNotification struct {
+	PaymentID     string // Payment ID at the provider, used for refunds
+	AppointmentID uuid.UUID
+	Status        PaymentStatus
+	Amount        float64
+	Currency      string
+}
+
+type PaymentMethod string
+
+const (
+	MethodCash        PaymentMethod = "cash"
+	MethodTransfer    PaymentMethod = "transfer"
+	MethodCard        PaymentMethod = "card"
+	MethodMercadoPago PaymentMethod = "mercadopago"
+)
+
+// PaymentMethods lists the accepted methods, in the order reports show them.
+var PaymentMethods = []PaymentMethod{MethodCash, MethodTransfer, MethodCard, MethodMercadoPago}
+
+func (m PaymentMethod) Valid() bool {
+	for _, method := range PaymentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Payment is money taken at the register for an appointment.
+// Amount is what was charged for the service after Discount; Tip is paid on top of it.
+type Payment struct {
+	ID            uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppointmentID uuid.UUID     `gorm:"type:uuid;index" json:"appointment_id"`
+	Method        PaymentMethod `gorm:"index" json:"method"`
+	Amount        float64       `gorm:"type:numeric(12,2)" json:"amount"`
+	Tip           float64       `gorm:"type:numeric(12,2)" json:"tip"`
+	Discount      float64       `gorm:"type:numeric(12,2)" json:"discount"`
+	Note          string        `json:"note,omitempty"`
+	RecordedByID  *uuid.UUID    `gorm:"type:uuid" json:"recorded_by_id,omitempty"`
+	CreatedAt     time.Time     `gorm:"index" json:"created_at"`
+}
+
+// Total is what the client handed over: the charged amount plus the tip.
+func (p Payment) Total() float64 {
+	return p.Amount + p.Tip
+}
+
+// PaymentTotals sums payments, either overall or for a single method.
+type PaymentTotals struct {
+	Method    PaymentMethod `json:"method,omitempty"`
+	Payments  int64         `json:"payments"`
+	Amount    float64       `json:"amount"`
+	Tips      float64       `json:"tips"`
+	Discounts float64       `json:"discounts"`
+	Total     float64       `json:"total"`
+}
+
+// CashClose is the end-of-day register report.
+type CashClose struct {
+	Date     string          `json:"date"` // YYYY-MM-DD
+	ByMethod []PaymentTotals `json:"by_method"`
+	Totals   PaymentTotals   `json:"totals"`
+}
+
+// RevenueSummary aggregates payments over a period.
+type RevenueSummary struct {
+	Revenue   float64 // Sum of charged amounts, without tips
+	Tips      float64
+	Discounts float64
+	Tickets   int64 // Distinct appointments paid
+}
@@ -0,0 +1,54 @@
+package domain
+
+// Permission is something a role may do. Routes require permissions, never roles,
+// so what a role can do is decided here only.
+type Permission string
+
+const (
+	PermManageAppointments   Permission = "appointments:manage" // The whole agenda, waitlist and barber assignment
+	PermViewOwnAgenda        Permission = "agenda:own"          // Appointments assigned to oneself
+	PermManageClients        Permission = "clients:manage"      // Client records, reading notes and booking blocks
+	PermManageClientsPrivate Permission = "clients:private"     // Writing notes, and a client's email, phone and verification
+	PermUseRegister          Permission = "register:use"        // Record payments and close the day
+	PermViewStats            Permission = "stats:view"
+	PermManageSettings       Permission = "settings:manage" // Shop settings, opening days and hours
+	PermExportData           Permission = "data:export"     // Exports and imports
+	PermManageUsers          Permission = "users:manage"    // Roles, staff accounts, deactivation, merges and sessions
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermManageAppointments, PermViewOwnAgenda, PermManageClients, PermManageClientsPrivate, PermUseRegister,
+		PermViewStats, PermManageSettings, PermExportData, PermManageUsers,
+	},
+	RoleReceptionist: {PermManageAppointments, PermManageClients, PermUseRegister},
+	RoleBarber:       {PermViewOwnAgenda},
+	RoleClient:       {},
+}
+
+// Roles lists every role, for role pickers.
+var Roles = []Role{RoleAdmin, RoleReceptionist, RoleBarber, RoleClient}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns what the role may do; unknown roles may do nothing.
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff reports whether the role works at the shop, as opposed to a client.
+func (r Role) IsStaff() bool {
+	return r.Valid() && r != RoleClient
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// RateLimit allows Requests per Window. A zero Requests means no limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimits are the buckets of a route group: one per client IP, and one per
+// logged-in account so a user cannot get around the limit by changing networks.
+type RateLimits struct {
+	PerIP      RateLimit
+	PerAccount RateLimit
+}
+
+// RateCounter counts the hits of one bucket in a fixed window that ends at ResetAt.
+type RateCounter struct {
+	Key       string    `gorm:"primaryKey" json:"key"`
+	Count     int       `json:"count"`
+	LastHitAt time.Time `json:"last_hit_at"`
+	ResetAt   time.Time `gorm:"index" json:"reset_at"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PendingRegistration is a registration with the email of a guest, waiting for the
+// email to be verified. Until then the guest keeps its details, so registering with
+// someone else's email cannot take over their bookings or messages. Only the hash of
+// the emailed token is stored.
+type PendingRegistration struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index" json:"user_id"` // The guest being claimed
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Password  string    `json:"-"` // Bcrypt hash
+	TokenHash string    `gorm:"uniqueIndex" json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
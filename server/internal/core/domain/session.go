@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login on one device. Access tokens name their session, and the
+// refresh token, stored only as a hash, is replaced on every refresh.
+type Session struct {
+	ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID           uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"uniqueIndex" json:"-"`
+	UserAgent        string     `json:"user_agent"`
+	IP               string     `json:"ip"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"` // Pushed forward on every refresh
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason    string     `json:"revoked_reason,omitempty"` // logout, logout_all, admin, reuse
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+const (
+	RevokedByLogout    = "logout"
+	RevokedByLogoutAll = "logout_all"
+	RevokedByAdmin     = "admin"
+	// A refresh token was presented after it had been rotated, so it may have been stolen
+	RevokedByReuse = "reuse"
+)
+
+// Active reports whether the session can still be used at the given time.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// TokenPair is what a login or refresh returns to the client.
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// RestrictionMode is what unreliable clients must go through to book.
+type RestrictionMode string
+
+const (
+	RestrictApproval RestrictionMode = "approval" // The booking is flagged for the admin to approve
+	RestrictDeposit  RestrictionMode = "deposit"  // The client must pay a deposit before confirmation
+)
+
+// Valid reports whether m is one of the known restriction modes.
+func (m RestrictionMode) Valid() bool {
+	return m == RestrictApproval || m == RestrictDeposit
+}
+
+// SettingsID is the primary key of the single settings row.
+const SettingsID = 1
+
+// Settings holds shop-wide configuration editable from the admin panel.
+// There is only ever one row (ID = SettingsID).
+type Settings struct {
+	ID                   uint            `gorm:"primaryKey" json:"-"`
+	BusinessName         string          `json:"business_name"`
+	ClosedOnHolidays     bool            `json:"closed_on_holidays"`     // Treat national holidays as closed unless the day's Availability opts in
+	WaitlistClaimMinutes int             `json:"waitlist_claim_minutes"` // How long a waitlisted client has to claim a freed slot
+	SlotHoldMinutes      int             `json:"slot_hold_minutes"`      // How long a slot stays held while the client fills the booking form
+	LateCancelHours      int             `json:"late_cancel_hours"`      // Client cancellations closer than this to the start are late
+	BlockAfterStrikes    int             `json:"block_after_strikes"`    // Late cancellations + no-shows before online booking is blocked; 0 disables
+	RestrictAfterStrikes int             `json:"restrict_after_strikes"` // Lifetime late cancellations + no-shows before RestrictionMode applies; 0 disables
+	RestrictionMode      RestrictionMode `json:"restriction_mode"`
+	DepositAmount        float64         `gorm:"type:numeric(12,2)" json:"deposit_amount"` // Deposit charged when one is required
+	DepositServices      string          `json:"deposit_services"`                         // Comma-separated services that always require a deposit
+	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+// RequiresDeposit reports whether service is one of the DepositServices (case-insensitive).
+func (s *Settings) RequiresDeposit(service string) bool {
+	service = strings.TrimSpace(service)
+	if service == "" {
+		return false
+	}
+	for _, name := range strings.Split(s.DepositServices, ",") {
+		if strings.EqualFold(strings.TrimSpace(name), service) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSettings returns the configuration used before the admin saves any settings.
+func DefaultSettings() *Settings {
+	return &Settings{
+		ID:                   SettingsID,
+		ClosedOnHolidays:     true,
+		WaitlistClaimMinutes: 30,
+		SlotHoldMinutes:      10,
+		LateCancelHours:      24,
+		RestrictionMode:      RestrictApproval,
+	}
+}
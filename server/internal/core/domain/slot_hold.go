@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SlotHold reserves a slot for a few minutes while a client completes the booking form.
+// An exclusion constraint on [StartTime, EndTime) makes the database arbitrate between
+// server instances, so no two holds overlap.
+type SlotHold struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TokenHash string    `gorm:"uniqueIndex" json:"-"`
+	StartTime time.Time `gorm:"uniqueIndex" json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessClaims is what an access token says about its bearer.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Role      Role
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// JSONWebKey is a public verification key as published in the JWKS document (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
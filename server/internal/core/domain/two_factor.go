@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorLogin is a login waiting for its second factor: the user proved who they
+// are, but gets no session until they send a code from their authenticator app. The
+// challenge token handed to the client is only stored as a hash.
+type TwoFactorLogin struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	TokenHash string    `gorm:"uniqueIndex" json:"-"`
+	Attempts  int       `gorm:"default:0" json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecoveryCode stands in for the authenticator app once, e.g. after losing the phone.
+// Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_recovery_codes_user_hash" json:"user_id"`
+	CodeHash  string     `gorm:"uniqueIndex:idx_recovery_codes_user_hash" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorChallenge is returned instead of tokens when a login needs a second factor.
+// Users who must have two-factor authentication but never set it up enroll first,
+// with the same token.
+type TwoFactorChallenge struct {
+	Token              string    `json:"challenge_token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
+}
+
+// LoginResult is where every kind of login ends: a session, or a second factor to ask for.
+type LoginResult struct {
+	User      *User
+	Tokens    *TokenPair          // Nil while TwoFactor is pending
+	TwoFactor *TwoFactorChallenge // Set instead of Tokens
+}
+
+// TwoFactorEnrollment is what an authenticator app needs to be set up. URI is the
+// otpauth:// provisioning URI, to be shown as a QR code; Secret is for typing in.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorStatus tells a user how their account is protected.
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"` // Cannot be turned off for this role
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// RequiresTwoFactor reports whether users with the role must log in with a second factor.
+func (r Role) RequiresTwoFactor() bool {
+	return r == RoleAdmin
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,19 +11,158 @@ import (
 type Role string
 
 const (
-	RoleAdmin  Role = "admin"
-	RoleClient Role = "client"
+	RoleAdmin        Role = "admin"
+	RoleReceptionist Role = "receptionist" // Runs the agenda and the register, no stats or settings
+	RoleBarber       Role = "barber"       // Sees the appointments assigned to them
+	RoleClient       Role = "client"
 )
 
 type User struct {
-	ID                uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Name              string    `json:"name" binding:"required"`
-	Email             string    `gorm:"uniqueIndex" json:"email" binding:"required,email"`
-	Password          string    `json:"-"` // Stored hash, not exposed in JSON
-	Phone             string    `json:"phone" binding:"required"`
-	Role              Role      `gorm:"default:'client'" json:"role"`
-	IsVerified        bool      `gorm:"default:false" json:"is_verified"`
-	VerificationToken string    `json:"-"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name          string    `json:"name" binding:"required"`
+	Email         string    `json:"email" binding:"required,email"`
+	Password      string    `json:"-"` // Stored hash, not exposed in JSON
+	Phone         string    `json:"phone" binding:"required"`
+	Role          Role      `gorm:"default:'client'" json:"role"`
+	IsVerified    bool      `gorm:"default:false" json:"is_verified"`    // The user proved owning the email
+	PhoneVerified bool      `gorm:"default:false" json:"phone_verified"` // The user proved owning the phone, with a WhatsApp code
+	IsGuest       bool      `gorm:"default:false" json:"is_guest"`       // Created by a guest booking; becomes an account when the guest registers
+
+	// Verification links carry a token whose hash is stored here until used or expired
+	VerificationHash      string     `gorm:"index" json:"-"`
+	VerificationExpiresAt *time.Time `json:"-"`
+
+	// Password reset links carry a token whose hash is stored here until used or expired
+	PasswordResetHash      string     `gorm:"index" json:"-"`
+	PasswordResetExpiresAt *time.Time `json:"-"`
+	// SessionsRevokedAt invalidates every token issued before it, e.g. after a password reset
+	SessionsRevokedAt *time.Time `json:"-"`
+
+	// Two-factor authentication with an authenticator app (TOTP). The secrets never
+	// leave the server once the app is set up.
+	TOTPSecret         string     `json:"-"`
+	TOTPPendingSecret  string     `json:"-"`                               // Being enrolled, until confirmed with a first code
+	TOTPLastStep       int64      `gorm:"default:0" json:"-"`              // Time step of the last code used, so each code works once
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at,omitempty"` // Nil when two-factor authentication is off
+
+	// Reliability metrics and cancellation policy
+	LastVisitAt     *time.Time `json:"last_visit_at,omitempty"` // Start of the last completed appointment
+	LateCancelCount int        `gorm:"default:0" json:"late_cancel_count"`
+	NoShowCount     int        `gorm:"default:0" json:"no_show_count"`
+	PolicyStrikes   int        `gorm:"default:0" json:"policy_strikes"`      // Late cancellations + no-shows since the last admin clear
+	BookingBlocked  bool       `gorm:"default:false" json:"booking_blocked"` // Set when PolicyStrikes reaches the limit; cleared by an admin
+
+	// Deactivated users cannot log in or book; merged users also point at the survivor
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	MergedIntoID  *uuid.UUID `gorm:"type:uuid" json:"merged_into_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NormalizeEmail is the stored form of an email: emails are unique regardless of case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactorEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// Verified reports whether the user proved owning their email or phone, which they
+// must before logging in with a password.
+func (u *User) Verified() bool {
+	return u.IsVerified || u.PhoneVerified
+}
+
+// MarkEmailVerified records that the user proved owning the email. That also
+// completes a guest's registration.
+func (u *User) MarkEmailVerified() {
+	u.IsVerified = true
+	u.IsGuest = false
+	u.VerificationHash = ""
+	u.VerificationExpiresAt = nil
+}
+
+// ClaimEmail records that the owner of the email proved it on an account that had not:
+// whoever registered it may not be them, so the password and phone they set stop
+// working and their sessions end at at. A guest's phone is kept, as guests proved the
+// email when booking.
+func (u *User) ClaimEmail(at time.Time) {
+	if !u.IsGuest {
+		u.Phone = ""
+	}
+	u.Password = ""
+	u.PhoneVerified = false
+	u.SessionsRevokedAt = &at
+	u.MarkEmailVerified()
+}
+
+// Strikes returns the client's lifetime count of late cancellations and no-shows.
+func (u *User) Strikes() int {
+	return u.LateCancelCount + u.NoShowCount
+}
+
+// ClientFilter narrows down the admin client list. Zero values mean "no filter".
+type ClientFilter struct {
+	MinNoShows      int
+	MinLateCancels  int
+	BookingBlocked  *bool
+	Verified        *bool
+	HasUpcoming     *bool      // Has a pending or confirmed appointment in the future
+	LastVisitBefore *time.Time // Visited at least once, but not since this time
+	Active          *bool      // Merged users are never listed
+}
+
+type ClientSort string
+
+const (
+	SortByName      ClientSort = "name"
+	SortByCreatedAt ClientSort = "created_at"
+	SortByLastVisit ClientSort = "last_visit" // Clients who never visited come first
+)
+
+func (s ClientSort) Valid() bool {
+	return s == SortByName || s == SortByCreatedAt || s == SortByLastVisit
+}
+
+// ClientSearch is a page request over the client list.
+type ClientSearch struct {
+	ClientFilter
+	Query  string // Words matched, accent-insensitively, against name, email and phone
+	Sort   ClientSort
+	Desc   bool
+	Cursor string // NextCursor of the previous page; empty for the first page
+	Limit  int
+}
+
+// ErrInvalidCursor is returned for a page cursor that was not produced by a previous page.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type ClientPage struct {
+	Clients    []User `json:"clients"`
+	Total      int64  `json:"total"`                 // Matches across all pages
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// NewUser is what an admin fills in to create a user. Password is optional for clients.
+type NewUser struct {
+	Name     string
+	Email    string
+	Phone    string
+	Role     Role
+	Password string
+}
+
+// UserChanges is a partial update of a user; nil fields are left unchanged.
+type UserChanges struct {
+	Name       *string
+	Email      *string
+	Phone      *string
+	Role       *Role
+	IsVerified *bool
 }
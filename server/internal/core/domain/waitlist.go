@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"
+	WaitlistOffered   WaitlistStatus = "offered" // A freed slot was offered and can be claimed until OfferExpiresAt
+	WaitlistBooked    WaitlistStatus = "booked"
+	WaitlistExpired   WaitlistStatus = "expired" // The offer was not claimed in time
+	WaitlistCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry is a client waiting for a slot to free up on a fully booked day.
+type WaitlistEntry struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ClientName     string         `json:"client_name"`
+	ClientEmail    string         `json:"client_email"`
+	ClientPhone    string         `json:"client_phone"`
+	Date           string         `gorm:"index" json:"date"`      // YYYY-MM-DD
+	WindowStart    string         `json:"window_start,omitempty"` // Optional "HH:MM", inclusive
+	WindowEnd      string         `json:"window_end,omitempty"`   // Optional "HH:MM", inclusive
+	Service        string         `json:"service,omitempty"`      // Free text, e.g. "corte y barba"
+	Status         WaitlistStatus `gorm:"default:'waiting'" json:"status"`
+	OfferedStart   *time.Time     `json:"offered_start,omitempty"`
+	OfferExpiresAt *time.Time     `json:"offer_expires_at,omitempty"`
+	ClaimTokenHash string         `gorm:"index" json:"-"`
+	AppointmentID  *uuid.UUID     `gorm:"type:uuid" json:"appointment_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// Accepts reports whether a slot starting at the given "HH:MM" time falls in the entry's window.
+func (w *WaitlistEntry) Accepts(slotTime string) bool {
+	if w.WindowStart != "" && slotTime < w.WindowStart {
+		return false
+	}
+	if w.WindowEnd != "" && slotTime > w.WindowEnd {
+		return false
+	}
+	return true
+}
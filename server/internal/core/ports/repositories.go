@@ -8,13 +8,70 @@ import (
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 )
 
+// Transactor runs fn in a database transaction, which repository calls made with the
+// ctx given to fn take part in. It commits if fn returns nil and rolls back otherwise.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
-	GetByVerificationToken(ctx context.Context, token string) (*domain.User, error)
-	ListClients(ctx context.Context, limit, offset int) ([]domain.User, error)
+	// GetByPhone returns the first user whose phone, stripped to digits, is one of phones.
+	GetByPhone(ctx context.Context, phones ...string) (*domain.User, error)
+	GetByVerificationHash(ctx context.Context, hash string) (*domain.User, error)
+	GetByPasswordResetHash(ctx context.Context, hash string) (*domain.User, error)
+	// ResetPassword sets the password of the active user whose reset token hash is hash
+	// and unexpired at at, using the token up, verifying the email and ending every
+	// session. It reports false when no such user is left, e.g. when a concurrent reset won.
+	ResetPassword(ctx context.Context, hash, password string, at time.Time) (bool, error)
+	// SearchClients returns a page of clients, or domain.ErrInvalidCursor for a bad cursor.
+	SearchClients(ctx context.Context, search domain.ClientSearch) (*domain.ClientPage, error)
+	// ListStaff returns every user who is not a client, deactivated ones included, by name.
+	ListStaff(ctx context.Context) ([]domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	// UpdateVerificationToken replaces the user's email verification token hash and expiry only.
+	UpdateVerificationToken(ctx context.Context, id uuid.UUID, hash string, expiresAt time.Time) error
+	// MarkEmailVerified verifies the email of the user whose verification token hash is
+	// hash and unexpired at at, using the token up. It reports false when no such user is left.
+	MarkEmailVerified(ctx context.Context, hash string, at time.Time) (bool, error)
+	// MarkPhoneVerified verifies the user's phone, and reports false when it is no longer phone.
+	MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (bool, error)
+	// ClaimGuest turns the active guest into the verified account of the registration,
+	// and reports false when they are no longer a guest, e.g. when another registration won.
+	ClaimGuest(ctx context.Context, id uuid.UUID, registration *domain.PendingRegistration) (bool, error)
+	// ClaimEmail applies domain.User.ClaimEmail to the active user, and reports false when
+	// their email was already verified.
+	ClaimEmail(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// IncrementPolicyCounters atomically adds to the client's late cancellation and no-show
+	// counters, and to their policy strikes.
+	IncrementPolicyCounters(ctx context.Context, id uuid.UUID, lateCancels, noShows int) error
+	// BlockBooking blocks the client from booking online if they have at least minStrikes.
+	BlockBooking(ctx context.Context, id uuid.UUID, minStrikes int) error
+	// ClearBookingBlock unblocks the client and resets their policy strikes.
+	ClearBookingBlock(ctx context.Context, id uuid.UUID) error
+	// RecordVisit moves the client's LastVisitAt forward to at (never backwards).
+	RecordVisit(ctx context.Context, id uuid.UUID, at time.Time) error
+	// UseTOTPStep moves the user's TOTPLastStep forward to step, and reports false when
+	// it was already there or past it, so each authenticator code works only once.
+	UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	// StartTOTPEnrollment sets the user's TOTPPendingSecret, and reports false when two-factor
+	// authentication was turned on meanwhile.
+	StartTOTPEnrollment(ctx context.Context, id uuid.UUID, secret string) (bool, error)
+	// EnableTOTP makes pendingSecret the user's TOTPSecret, used up to step, and turns
+	// two-factor authentication on at at. It reports false when pendingSecret is no longer
+	// the one being enrolled or it was turned on meanwhile.
+	EnableTOTP(ctx context.Context, id uuid.UUID, pendingSecret string, step int64, at time.Time) (bool, error)
+	// DisableTOTP turns two-factor authentication off and forgets the user's secrets.
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+	// Merge moves the duplicate's appointments, notes, waitlist entries, history, provider
+	// identities, sessions and pending registrations to the survivor and deactivates the
+	// duplicate, in one transaction. check is called first
+	// with both users locked, and its error aborts the merge.
+	Merge(ctx context.Context, survivorID, duplicateID uuid.UUID, check func(survivor, duplicate *domain.User) error) error
+	// EachClientForExport calls fn for every client, ordered by name, without loading them all at once.
+	EachClientForExport(ctx context.Context, fn func(row *domain.ClientExportRow) error) error
 }
 
 type AvailabilityRepository interface {
@@ -28,8 +85,164 @@ type AppointmentRepository interface {
 	Create(ctx context.Context, appointment *domain.Appointment) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Appointment, error)
 	Update(ctx context.Context, appointment *domain.Appointment) error
+	// UpdateIf saves the appointment only if its stored status and payment status are
+	// still status and paymentStatus. It reports false otherwise, e.g. when a concurrent
+	// change won.
+	UpdateIf(ctx context.Context, appointment *domain.Appointment, status domain.AppointmentStatus, paymentStatus domain.PaymentStatus) (bool, error)
 	ListByDateRange(ctx context.Context, start, end time.Time) ([]domain.Appointment, error)
+	// ListByBarber returns the barber's appointments starting in [start, end), by start time.
+	ListByBarber(ctx context.Context, barberID uuid.UUID, start, end time.Time) ([]domain.Appointment, error)
+	// ListByClient returns all of a client's appointments, newest first.
+	ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.Appointment, error)
 	CountByMonth(ctx context.Context, month time.Month, year int) (int64, error)
 	CountCompletedByMonth(ctx context.Context, month time.Month, year int) (int64, error)
+	CountNoShowsByMonth(ctx context.Context, month time.Month, year int) (int64, error)
 	CountByStatus(ctx context.Context, status domain.AppointmentStatus) (int64, error)
+	// EachForExport calls fn for every matching appointment, ordered by start time,
+	// without loading them all at once.
+	EachForExport(ctx context.Context, filter domain.AppointmentExportFilter, fn func(row *domain.AppointmentExportRow) error) error
+}
+
+type SettingsRepository interface {
+	// Get returns the saved settings, or domain.DefaultSettings if none were saved yet.
+	Get(ctx context.Context) (*domain.Settings, error)
+	Save(ctx context.Context, settings *domain.Settings) error
+}
+
+type WaitlistRepository interface {
+	Create(ctx context.Context, entry *domain.WaitlistEntry) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.WaitlistEntry, error)
+	GetByClaimTokenHash(ctx context.Context, hash string) (*domain.WaitlistEntry, error)
+	// ListByDate returns entries for the date in the given statuses, oldest first.
+	ListByDate(ctx context.Context, date string, statuses ...domain.WaitlistStatus) ([]domain.WaitlistEntry, error)
+	ListExpiredOffers(ctx context.Context, now time.Time) ([]domain.WaitlistEntry, error)
+	Update(ctx context.Context, entry *domain.WaitlistEntry) error
+	// UpdateIf saves the entry only if its stored status is still status, and reports
+	// false otherwise, e.g. when a concurrent claim or offer won.
+	UpdateIf(ctx context.Context, entry *domain.WaitlistEntry, status domain.WaitlistStatus) (bool, error)
+}
+
+type SlotHoldRepository interface {
+	// Create stores the hold, replacing expired holds overlapping it.
+	// It returns gorm.ErrDuplicatedKey if any of its time is already held.
+	Create(ctx context.Context, hold *domain.SlotHold) error
+	GetByTokenHash(ctx context.Context, hash string) (*domain.SlotHold, error)
+	ListActiveByDateRange(ctx context.Context, start, end, now time.Time) ([]domain.SlotHold, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *domain.Session) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Session, error)
+	// ListByUser returns revoked and expired sessions too, most recently used first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Session, error)
+	// Rotate replaces the refresh token hash if it still is oldHash and the session is not
+	// revoked. It reports false otherwise, e.g. when a concurrent refresh won.
+	Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash string, usedAt, expiresAt time.Time, ip string) (bool, error)
+	Revoke(ctx context.Context, id uuid.UUID, reason string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID, reason string, at time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OneTimeCodeRepository interface {
+	Create(ctx context.Context, code *domain.OneTimeCode) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.OneTimeCode, error)
+	// IncrementAttempts counts a guess at the code and returns the attempts made so far.
+	IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error)
+	// Consume marks the code used and reports false if it already was, so only
+	// one of several concurrent redemptions wins.
+	Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	CountSince(ctx context.Context, purpose domain.CodePurpose, destination string, since time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PendingRegistrationRepository interface {
+	Create(ctx context.Context, registration *domain.PendingRegistration) error
+	// Consume deletes and returns the registration with this token hash, so a link
+	// can only be used once. It fails with gorm.ErrRecordNotFound.
+	Consume(ctx context.Context, tokenHash string) (*domain.PendingRegistration, error)
+	// LatestByUser returns the newest registration claiming the guest, or gorm.ErrRecordNotFound.
+	LatestByUser(ctx context.Context, userID uuid.UUID) (*domain.PendingRegistration, error)
+	UpdateToken(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OIDCLoginRepository interface {
+	Create(ctx context.Context, login *domain.OIDCLogin) error
+	// Consume deletes and returns the login with this state hash, so a provider
+	// callback can only be used once. It fails with gorm.ErrRecordNotFound.
+	Consume(ctx context.Context, stateHash string) (*domain.OIDCLogin, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type UserIdentityRepository interface {
+	GetBySubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	Create(ctx context.Context, identity *domain.UserIdentity) error
+}
+
+type TwoFactorLoginRepository interface {
+	Create(ctx context.Context, login *domain.TwoFactorLogin) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*domain.TwoFactorLogin, error)
+	// IncrementAttempts counts a wrong code and returns the attempts made so far.
+	IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type RecoveryCodeRepository interface {
+	// Replace deletes the user's recovery codes and stores the new hashes, in one transaction.
+	Replace(ctx context.Context, userID uuid.UUID, hashes []string) error
+	// Use marks the unused code with this hash as used, reporting whether there was one.
+	Use(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uuid.UUID) (int, error)
+	DeleteAll(ctx context.Context, userID uuid.UUID) error
+}
+
+// RateLimitStore keeps rate limit counters, in memory or shared between instances.
+type RateLimitStore interface {
+	// Increment counts a hit on key at now and returns the counter. A counter whose
+	// window has ended starts again from one, with a window ending at now + window.
+	Increment(ctx context.Context, key string, window time.Duration, now time.Time) (*domain.RateCounter, error)
+	// Get returns the counter of key, or nil when there is none or its window ended.
+	Get(ctx context.Context, key string, now time.Time) (*domain.RateCounter, error)
+	Delete(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type AppointmentEventRepository interface {
+	Create(ctx context.Context, event *domain.AppointmentEvent) error
+	ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.AppointmentEvent, error)
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *domain.Payment) error
+	ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.Payment, error)
+	// TotalsByMethod sums payments recorded in [start, end), one row per method used.
+	TotalsByMethod(ctx context.Context, start, end time.Time) ([]domain.PaymentTotals, error)
+	Summarize(ctx context.Context, start, end time.Time) (*domain.RevenueSummary, error)
+}
+
+// AnalyticsRepository aggregates in the database; each method is a single query
+// returning one row per period that has data.
+type AnalyticsRepository interface {
+	// AppointmentsByPeriod fills the appointment counters, booked minutes and new/returning
+	// clients, per period and for the whole range (clients are distinct over the range).
+	AppointmentsByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) (periods []domain.PeriodStats, total domain.PeriodStats, err error)
+	// AvailabilityByPeriod fills AvailableMinutes from the non-blocked availability.
+	AvailabilityByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) ([]domain.PeriodStats, error)
+	// RevenueByPeriod fills Revenue and Tips from the payments recorded in [start, end),
+	// grouped by their date in loc.
+	RevenueByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping, loc *time.Location) ([]domain.PeriodStats, error)
+	Heatmap(ctx context.Context, start, end time.Time) ([]domain.HeatmapCell, error)
+}
+
+type ClientNoteRepository interface {
+	Create(ctx context.Context, note *domain.ClientNote) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.ClientNote, error)
+	Update(ctx context.Context, note *domain.ClientNote) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// ListByClient returns the client's notes with their authors, newest first.
+	ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.ClientNote, error)
 }
@@ -2,6 +2,9 @@ package ports
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,18 +12,96 @@ import (
 )
 
 type AvailabilityService interface {
-	SetAvailability(ctx context.Context, date, start, end string, duration int) error
+	SetAvailability(ctx context.Context, date, start, end string, duration int, openOnHoliday bool) error
 	GetAvailability(ctx context.Context) ([]domain.Availability, error)
-	GetAvailableSlots(ctx context.Context, date time.Time) ([]time.Time, error)
+	// GetAvailableSlots excludes slots held by others; the slot held with holdToken (if any) is kept.
+	GetAvailableSlots(ctx context.Context, date time.Time, holdToken string) ([]time.Time, error)
+	// IsOpen reports whether the shop takes appointments on date: it has hours set and
+	// is neither blocked nor closed for a holiday.
+	IsOpen(ctx context.Context, date time.Time) (bool, error)
 	DeleteAvailability(ctx context.Context, id uuid.UUID) error
+	ListHolidays(ctx context.Context, from, to time.Time) ([]domain.Holiday, error)
 }
 
 type AppointmentService interface {
-	CreateAppointment(ctx context.Context, clientName, clientEmail, clientPhone string, startTime time.Time, notes string) (*domain.Appointment, error)
-	CreateAppointmentForClient(ctx context.Context, clientID uuid.UUID, startTime time.Time, notes string) (*domain.Appointment, error)
+	// CreateAppointment and CreateAppointmentForClient fail with ErrSlotHeld if another
+	// client holds the slot; set req.HoldToken to the token returned by SlotHoldService to book a held slot.
+	// When a deposit is required the returned appointment carries the payment checkout URL.
+	// CreateAppointment is for guests and fails with ErrUnverifiedGuest unless req.VerifiedVia is set.
+	CreateAppointment(ctx context.Context, req domain.BookingRequest) (*domain.Appointment, error)
+	CreateAppointmentForClient(ctx context.Context, clientID uuid.UUID, req domain.BookingRequest) (*domain.Appointment, error)
+	// Status changes fail with ErrInvalidTransition when not allowed from the current status.
 	ConfirmAppointment(ctx context.Context, appointmentID uuid.UUID) error
-	CancelAppointment(ctx context.Context, appointmentID uuid.UUID) error
+	CancelAppointment(ctx context.Context, appointmentID uuid.UUID, cancellation domain.Cancellation) error
+	// ApproveAppointment and RejectAppointment settle a booking with RequiresApproval set,
+	// and fail with ErrNoApprovalPending for any other.
+	ApproveAppointment(ctx context.Context, appointmentID uuid.UUID) error
+	RejectAppointment(ctx context.Context, appointmentID uuid.UUID, note string) error
+	// CancelOwnAppointment cancels on behalf of the client, who must own the appointment.
+	CancelOwnAppointment(ctx context.Context, clientID, appointmentID uuid.UUID, reason domain.CancellationReason, note string) error
+	CompleteAppointment(ctx context.Context, appointmentID uuid.UUID) error
+	MarkNoShow(ctx context.Context, appointmentID uuid.UUID) error
+	// RescheduleAppointment books the client at newStart and marks the original as rescheduled.
+	RescheduleAppointment(ctx context.Context, appointmentID uuid.UUID, newStart time.Time) (*domain.Appointment, error)
+	UpdateNotes(ctx context.Context, appointmentID uuid.UUID, notes string) error
+	// ApplyPayment records a verified deposit payment and confirms the appointment once paid.
+	ApplyPayment(ctx context.Context, notification *domain.PaymentNotification) error
+	// GetHistory returns the appointment's events, oldest first.
+	GetHistory(ctx context.Context, appointmentID uuid.UUID) ([]domain.AppointmentEvent, error)
 	ListAppointments(ctx context.Context, start, end time.Time) ([]domain.Appointment, error)
+	// AssignBarber sets who does the appointment; nil unassigns it. Fails with ErrNotABarber.
+	AssignBarber(ctx context.Context, appointmentID uuid.UUID, barberID *uuid.UUID) (*domain.Appointment, error)
+	ListBarberAgenda(ctx context.Context, barberID uuid.UUID, start, end time.Time) ([]domain.Appointment, error)
+}
+
+// GuestBookingService books for guests after they confirm a one-time code.
+type GuestBookingService interface {
+	Start(ctx context.Context, req domain.BookingRequest, channel domain.ContactChannel) (*domain.GuestBookingChallenge, error)
+	Confirm(ctx context.Context, challengeID uuid.UUID, code, holdToken string) (*domain.Appointment, error)
+}
+
+type SlotHoldService interface {
+	// HoldSlot holds an available slot and returns the hold with its raw token.
+	HoldSlot(ctx context.Context, start time.Time) (*domain.SlotHold, string, error)
+	ReleaseHold(ctx context.Context, token string) error
+	// ExpireHolds deletes expired holds, returning how many were removed.
+	ExpireHolds(ctx context.Context) (int64, error)
+}
+
+type WaitlistService interface {
+	Join(ctx context.Context, entry *domain.WaitlistEntry) error
+	ListByDate(ctx context.Context, date string) ([]domain.WaitlistEntry, error)
+	// OfferSlot offers a freed slot to the first waitlisted client whose window accepts it.
+	OfferSlot(ctx context.Context, start time.Time) error
+	Claim(ctx context.Context, token string) (*domain.Appointment, error)
+	// ExpireOffers expires unclaimed offers and passes their slots on to the next client in line.
+	ExpireOffers(ctx context.Context) error
+}
+
+type PolicyService interface {
+	// IsLateCancellation reports whether cancelling appt at now falls inside the late window.
+	IsLateCancellation(ctx context.Context, appt *domain.Appointment, now time.Time) (bool, error)
+	RecordLateCancellation(ctx context.Context, clientID uuid.UUID) error
+	RecordNoShow(ctx context.Context, clientID uuid.UUID) error
+	CheckCanBook(ctx context.Context, user *domain.User) error
+	BookingRestrictions(ctx context.Context, user *domain.User) (requiresApproval, depositRequired bool, err error)
+	ClearBlock(ctx context.Context, clientID uuid.UUID) error
+}
+
+// WebhookRequest is the raw HTTP request a payment provider sent to our webhook.
+type WebhookRequest struct {
+	Header http.Header
+	Query  url.Values
+	Body   []byte
+}
+
+type PaymentService interface {
+	// CreatePayment starts a deposit payment for the appointment.
+	CreatePayment(ctx context.Context, appt *domain.Appointment, amount float64) (*domain.PaymentIntent, error)
+	// VerifyWebhook authenticates a webhook and returns the payment it refers to.
+	// It returns nil, nil for notifications that are not about payments.
+	VerifyWebhook(ctx context.Context, req WebhookRequest) (*domain.PaymentNotification, error)
+	Refund(ctx context.Context, paymentID string, amount float64) error
 }
 
 type CalendarService interface {
@@ -28,8 +109,174 @@ type CalendarService interface {
 	DeleteEvent(ctx context.Context, eventID string) error
 }
 
+// HolidayProvider knows the public holidays of the shop's country.
+type HolidayProvider interface {
+	// GetHoliday returns the holiday on the given date, or nil if it is a regular day.
+	GetHoliday(ctx context.Context, date time.Time) (*domain.Holiday, error)
+	ListHolidays(ctx context.Context, from, to time.Time) ([]domain.Holiday, error)
+}
+
 type StatsService interface {
 	GetMonthlyStats(ctx context.Context) (map[string]interface{}, error)
+	// GetAnalytics reports on the days in [from, to), grouped by day, week or month.
+	GetAnalytics(ctx context.Context, from, to time.Time, groupBy domain.Grouping) (*domain.Analytics, error)
+}
+
+// SheetWriter streams a table to a file format such as CSV or XLSX.
+// Cells may be string, float64, int64 or bool.
+type SheetWriter interface {
+	WriteRow(cells ...interface{}) error
+	// Close flushes the remaining output; the writer must not be used afterwards.
+	Close() error
+}
+
+type ExportService interface {
+	ExportAppointments(ctx context.Context, filter domain.AppointmentExportFilter, out SheetWriter) error
+	ExportClients(ctx context.Context, out SheetWriter) error
+}
+
+// TokenIssuer signs and verifies access tokens, for both the auth handler and middleware.
+type TokenIssuer interface {
+	Sign(claims domain.AccessClaims) (string, error)
+	Verify(token string) (*domain.AccessClaims, error)
+	// JWKS returns the public keys to publish at /.well-known/jwks.json.
+	JWKS() []domain.JSONWebKey
+}
+
+type SessionService interface {
+	Start(ctx context.Context, user *domain.User, userAgent, ip string) (*domain.TokenPair, error)
+	// Refresh rotates the refresh token. Presenting an already rotated token revokes
+	// the session and fails with ErrRefreshTokenReused.
+	Refresh(ctx context.Context, refreshToken, ip string) (*domain.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	// Authenticate returns the user of an active session, for access token checks.
+	Authenticate(ctx context.Context, sessionID uuid.UUID) (*domain.User, error)
+}
+
+// OIDCProvider is the HTTP side of an OpenID Connect provider such as Google. ID tokens
+// are validated by the login service against Issuer, ClientID and Keys.
+type OIDCProvider interface {
+	Name() string
+	Issuer() string
+	ClientID() string
+	// AuthorizationURL is where the browser logs in, with an S256 PKCE challenge.
+	AuthorizationURL(state, nonce, codeChallenge string) string
+	// Exchange trades an authorization code for the raw ID token.
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	// Keys returns the provider's published signing keys.
+	Keys(ctx context.Context) ([]domain.JSONWebKey, error)
+}
+
+type OIDCLoginService interface {
+	// Begin returns the provider URL to send the browser to, and the state in it,
+	// which the browser must be tied to so the callback cannot be replayed elsewhere.
+	Begin(ctx context.Context) (authURL, state string, err error)
+	// Complete handles the provider redirect and logs the user in, like a password login.
+	Complete(ctx context.Context, state, code, userAgent, ip string) (*domain.OIDCCallback, error)
+}
+
+type PasswordlessLoginService interface {
+	// RequestCode sends a login code, and a magic link for emails, to the email or phone
+	// of an account. Unknown destinations get a challenge too, one that never works.
+	RequestCode(ctx context.Context, channel domain.ContactChannel, destination string) (*domain.LoginChallenge, error)
+	// VerifyCode redeems the code and logs the user in, like a password login.
+	VerifyCode(ctx context.Context, challengeID uuid.UUID, code, userAgent, ip string) (*domain.LoginResult, error)
+}
+
+// LoginStarter finishes a login once the user proved who they are: it opens a session,
+// or asks for a second factor first. Every kind of login goes through it.
+type LoginStarter interface {
+	StartLogin(ctx context.Context, user *domain.User, userAgent, ip string) (*domain.LoginResult, error)
+}
+
+type TwoFactorService interface {
+	LoginStarter
+	// CompleteLogin opens the session of a login challenge with a code from the
+	// authenticator app or a recovery code.
+	CompleteLogin(ctx context.Context, challengeToken, code, userAgent, ip string) (*domain.LoginResult, error)
+	// EnrollForLogin and ConfirmForLogin set up the authenticator app of a user who must
+	// have one, during the login that asked for it. Confirming returns the recovery codes.
+	EnrollForLogin(ctx context.Context, challengeToken string) (*domain.TwoFactorEnrollment, error)
+	ConfirmForLogin(ctx context.Context, challengeToken, code, userAgent, ip string) (*domain.LoginResult, []string, error)
+
+	Status(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorStatus, error)
+	// Enroll starts setting up an authenticator app; nothing changes until Confirm.
+	Enroll(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorEnrollment, error)
+	// Confirm turns two-factor authentication on and returns the recovery codes.
+	Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	// Disable fails with ErrTwoFactorRequired for roles that must keep it.
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type RateLimitService interface {
+	// Allow counts a request against the bucket key and returns how long to wait
+	// before retrying, or zero when the request is allowed.
+	Allow(ctx context.Context, key string, limit domain.RateLimit) (time.Duration, error)
+	// LoginLockedFor returns how long logins to account from ip stay locked after failures.
+	LoginLockedFor(ctx context.Context, account, ip string) (time.Duration, error)
+	// LoginFailed records a failed login and returns the lockout it triggers, if any.
+	LoginFailed(ctx context.Context, account, ip string) (time.Duration, error)
+	LoginSucceeded(ctx context.Context, account, ip string) error
+}
+
+type PasswordResetService interface {
+	// RequestReset never reveals whether the email belongs to a user.
+	RequestReset(ctx context.Context, email string) error
+	// ResetPassword fails with ErrInvalidResetToken for unknown, used or expired tokens.
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type EmailVerificationService interface {
+	// SendLink emails the user a verification link valid for a couple of days.
+	SendLink(ctx context.Context, user *domain.User) error
+	// ClaimGuest emails the guest a link that turns it into an account with the
+	// registration's name, phone and password hash; the guest is unchanged until then.
+	ClaimGuest(ctx context.Context, guest *domain.User, registration domain.PendingRegistration) error
+	// ResendLink is throttled and never reveals whether the email belongs to a user.
+	ResendLink(ctx context.Context, email string) error
+	// VerifyEmail fails with ErrInvalidVerificationToken for unknown, used or expired tokens.
+	VerifyEmail(ctx context.Context, token string) (*domain.User, error)
+	// SendPhoneCode sends a WhatsApp code to verify the account by its phone instead.
+	SendPhoneCode(ctx context.Context, email string) (*domain.LoginChallenge, error)
+	VerifyPhone(ctx context.Context, challengeID uuid.UUID, code string) (*domain.User, error)
+}
+
+type UserService interface {
+	Create(ctx context.Context, input domain.NewUser) (*domain.User, error)
+	// Update fails with ErrForbidden when a staff account is edited, or a client's
+	// email, phone or verification changed, by an actor not allowed to.
+	Update(ctx context.Context, id uuid.UUID, changes domain.UserChanges) (*domain.User, error)
+	// SetRole fails with ErrForbidden unless the actor in ctx may manage users.
+	SetRole(ctx context.Context, id uuid.UUID, role domain.Role) (*domain.User, error)
+	Deactivate(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	Reactivate(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	// Merge folds duplicateID into survivorID and returns the survivor.
+	Merge(ctx context.Context, survivorID, duplicateID uuid.UUID) (*domain.User, error)
+}
+
+type ClientService interface {
+	GetProfile(ctx context.Context, clientID uuid.UUID) (*domain.ClientProfile, error)
+	// AddNote records a private note authored by the actor in ctx.
+	AddNote(ctx context.Context, clientID uuid.UUID, body string) (*domain.ClientNote, error)
+	UpdateNote(ctx context.Context, clientID, noteID uuid.UUID, body string) (*domain.ClientNote, error)
+	DeleteNote(ctx context.Context, clientID, noteID uuid.UUID) error
+}
+
+type ImportService interface {
+	// ImportClients creates clients from a CSV file; with dryRun nothing is written.
+	ImportClients(ctx context.Context, r io.Reader, dryRun bool) (*domain.ImportReport, error)
+}
+
+// RegisterService records what clients pay at the shop (the point of sale).
+type RegisterService interface {
+	RecordPayment(ctx context.Context, appointmentID uuid.UUID, payment *domain.Payment) error
+	ListPayments(ctx context.Context, appointmentID uuid.UUID) ([]domain.Payment, error)
+	// CloseDay summarizes the payments recorded on the given day, by method.
+	CloseDay(ctx context.Context, date time.Time) (*domain.CashClose, error)
 }
 
 type MessagingService interface {
@@ -40,4 +287,8 @@ type MessagingService interface {
 
 type EmailService interface {
 	SendVerificationEmail(to, name, link string) error
+	SendOneTimeCode(to, name, code string) error
+	SendPasswordResetEmail(to, name, link string) error
+	// SendLoginLink sends a magic link that logs the user in, with its code for typing in.
+	SendLoginLink(to, name, link, code string) error
 }
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,118 +15,210 @@ import (
 )
 
 type AppointmentService struct {
-	apptRepo    ports.AppointmentRepository
-	availRepo   ports.AvailabilityRepository
-	userRepo    ports.UserRepository
-	calendarSvc ports.CalendarService
-	msgSvc      ports.MessagingService
+	apptRepo     ports.AppointmentRepository
+	availRepo    ports.AvailabilityRepository
+	holidays     ports.HolidayProvider
+	userRepo     ports.UserRepository
+	holdRepo     ports.SlotHoldRepository
+	eventRepo    ports.AppointmentEventRepository
+	tx           ports.Transactor
+	settingsRepo ports.SettingsRepository
+	policy       ports.PolicyService
+	payments     ports.PaymentService
+	calendarSvc  ports.CalendarService
+	msgSvc       ports.MessagingService
+	waitlist     ports.WaitlistService
 }
 
-func NewAppointmentService(apptRepo ports.AppointmentRepository, availRepo ports.AvailabilityRepository, userRepo ports.UserRepository, calendarSvc ports.CalendarService, msgSvc ports.MessagingService) *AppointmentService {
+func NewAppointmentService(apptRepo ports.AppointmentRepository, availRepo ports.AvailabilityRepository, holidays ports.HolidayProvider, userRepo ports.UserRepository, holdRepo ports.SlotHoldRepository, eventRepo ports.AppointmentEventRepository, tx ports.Transactor, settingsRepo ports.SettingsRepository, policy ports.PolicyService, payments ports.PaymentService, calendarSvc ports.CalendarService, msgSvc ports.MessagingService) *AppointmentService {
 	return &AppointmentService{
-		apptRepo:    apptRepo,
-		availRepo:   availRepo,
-		userRepo:    userRepo,
-		calendarSvc: calendarSvc,
-		msgSvc:      msgSvc,
+		apptRepo:     apptRepo,
+		availRepo:    availRepo,
+		holidays:     holidays,
+		userRepo:     userRepo,
+		holdRepo:     holdRepo,
+		eventRepo:    eventRepo,
+		tx:           tx,
+		settingsRepo: settingsRepo,
+		policy:       policy,
+		payments:     payments,
+		calendarSvc:  calendarSvc,
+		msgSvc:       msgSvc,
 	}
 }
 
-func (s *AppointmentService) CreateAppointment(ctx context.Context, clientName, clientEmail, clientPhone string, startTime time.Time, notes string) (*domain.Appointment, error) {
-	// 1. Find or Create User
-	user, err := s.userRepo.GetByEmail(ctx, clientEmail)
-	if err != nil && err != gorm.ErrRecordNotFound {
-		// Proceed if not found (to create), error if DB error
-		// Wait, userRepo implementation of GetByEmail returns error if not found.
-		// Let's assume we handle "not found" by creating.
-		// NOTE: user_repo implementation uses First(), which returns ErrRecordNotFound.
-	}
-
-	if user == nil || user.ID == uuid.Nil {
-		// Create new user
-		newUser := &domain.User{
-			Name:  clientName,
-			Email: clientEmail,
-			Phone: clientPhone,
-			Role:  domain.RoleClient,
-		}
-		if err := s.userRepo.Create(ctx, newUser); err != nil {
-			return nil, err
-		}
-		user = newUser
-	}
+// SetWaitlist registers the waitlist that is offered slots freed by cancellations.
+// It is set after construction because the waitlist books through this service.
+func (s *AppointmentService) SetWaitlist(waitlist ports.WaitlistService) {
+	s.waitlist = waitlist
+}
 
-	// 2. Check if slot is within working hours (Availability)
-	dateStr := startTime.Format("2006-01-02")
-	avail, err := s.availRepo.GetByDate(ctx, dateStr)
+// CreateAppointment books for a guest who proved they own the contact in req.VerifiedVia.
+// If an account already uses that contact the appointment is booked under it, without
+// changing the account unless its email was never verified, which the guest then
+// claims; otherwise it goes to a guest user that is turned into an account when the
+// guest registers with the same email.
+func (s *AppointmentService) CreateAppointment(ctx context.Context, req domain.BookingRequest) (*domain.Appointment, error) {
+	user, err := s.guestUser(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	if avail == nil || avail.IsBlocked {
-		return nil, errors.New("barber is not available at this time")
-	}
+	return s.book(ctx, user, req)
+}
 
-	// 3. Create Appointment
-	endTime := startTime.Add(1 * time.Hour)
-	appt := &domain.Appointment{
-		ClientID:  user.ID,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Status:    domain.StatusPending,
-		Notes:     notes,
+func (s *AppointmentService) guestUser(ctx context.Context, req domain.BookingRequest) (*domain.User, error) {
+	var user *domain.User
+	var err error
+	switch req.VerifiedVia {
+	case domain.ChannelEmail:
+		user, err = s.userRepo.GetByEmail(ctx, req.ClientEmail)
+	case domain.ChannelWhatsApp:
+		user, err = s.userRepo.GetByPhone(ctx, phoneVariants(normalizePhone(req.ClientPhone))...)
+	default:
+		return nil, ErrUnverifiedGuest
 	}
-
-	if err := s.apptRepo.Create(ctx, appt); err != nil {
+	if err == nil {
+		// The booker proved owning the email, which whoever registered it never did
+		if req.VerifiedVia == domain.ChannelEmail && !user.IsVerified && !user.IsGuest {
+			if err := claimEmail(ctx, s.userRepo, user); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	// 4. Calendar Sync
-	eventID, err := s.calendarSvc.CreateEvent(ctx, appt)
-	if err == nil {
-		appt.GoogleEventID = eventID
-		_ = s.apptRepo.Update(ctx, appt)
+	// Only the verified email is stored: an unverified one could belong to someone else
+	guest := &domain.User{
+		Name:    req.ClientName,
+		Phone:   normalizePhone(req.ClientPhone),
+		Role:    domain.RoleClient,
+		IsGuest: true,
 	}
-
-	// Send immediate WhatsApp confirmation upon booking (best-effort)
-	// CHANGED: Do NOT send confirmation yet. Wait for Admin confirmation.
-	// if s.msgSvc != nil && appt.Client.Phone != "" {
-	// 	msg := "Tu turno fue registrado para " + appt.StartTime.Format("2006-01-02 15:04") + ". Pronto te confirmaremos."
-	// 	_ = s.msgSvc.SendWhatsApp(ctx, appt.Client.Phone, msg)
-	// }
-
-	return appt, nil
+	if req.VerifiedVia == domain.ChannelEmail {
+		guest.Email = req.ClientEmail
+	}
+	if err := s.userRepo.Create(ctx, guest); err != nil {
+		return nil, err
+	}
+	return guest, nil
 }
 
 // CreateAppointmentForClient creates an appointment for an existing client ID
-func (s *AppointmentService) CreateAppointmentForClient(ctx context.Context, clientID uuid.UUID, startTime time.Time, notes string) (*domain.Appointment, error) {
+func (s *AppointmentService) CreateAppointmentForClient(ctx context.Context, clientID uuid.UUID, req domain.BookingRequest) (*domain.Appointment, error) {
 	// 1. Get user
 	user, err := s.userRepo.GetByID(ctx, clientID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Check availability
-	dateStr := startTime.Format("2006-01-02")
-	avail, err := s.availRepo.GetByDate(ctx, dateStr)
+	return s.book(ctx, user, req)
+}
+
+// book creates the appointment for an already resolved user.
+func (s *AppointmentService) book(ctx context.Context, user *domain.User, req domain.BookingRequest) (*domain.Appointment, error) {
+	appt, ownHold, err := s.createBooking(ctx, user, req, false)
 	if err != nil {
 		return nil, err
 	}
-	if avail == nil || avail.IsBlocked {
-		return nil, errors.New("barber is not available at this time")
+	s.finishBooking(ctx, appt, ownHold)
+	return appt, nil
+}
+
+// createBooking stores the appointment and starts its deposit. It returns the caller's
+// hold on the slot, if any, for finishBooking. moving is true when staff move an existing
+// booking: the client's booking policy was applied to it already, and its deposit,
+// approval and status are carried over by the caller.
+func (s *AppointmentService) createBooking(ctx context.Context, user *domain.User, req domain.BookingRequest, moving bool) (*domain.Appointment, *domain.SlotHold, error) {
+	if !user.Active() {
+		return nil, nil, ErrAccountInactive
 	}
+	startTime := req.StartTime
 
-	// 3. Create Appointment
+	if s.policy != nil && !moving {
+		if err := s.policy.CheckCanBook(ctx, user); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 1. Check if slot is within working hours, as the slot listing does
+	day, err := openingHours(ctx, s.availRepo, s.settingsRepo, s.holidays, startTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	if day == nil || !day.Includes(startTime) {
+		return nil, nil, errors.New("barber is not available at this time")
+	}
+
+	if req.BarberID != nil {
+		if err := s.checkBarber(ctx, *req.BarberID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 2. Make sure no other client is holding the slot
 	endTime := startTime.Add(1 * time.Hour)
+	ownHold, err := s.checkHolds(ctx, startTime, endTime, req.HoldToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 3. Create Appointment
 	appt := &domain.Appointment{
 		ClientID:  user.ID,
 		StartTime: startTime,
 		EndTime:   endTime,
 		Status:    domain.StatusPending,
-		Notes:     notes,
+		Service:   req.Service,
+		Notes:     req.Notes,
+		BarberID:  req.BarberID,
+	}
+	if s.policy != nil && !moving {
+		if appt.RequiresApproval, appt.DepositRequired, err = s.policy.BookingRestrictions(ctx, user); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	if err := s.apptRepo.Create(ctx, appt); err != nil {
-		return nil, err
+	// Deposits: charged to restricted clients and for selected services
+	if s.payments != nil && !moving {
+		settings, err := s.settingsRepo.Get(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		appt.DepositRequired = (appt.DepositRequired || settings.RequiresDeposit(req.Service)) && settings.DepositAmount > 0
+		if appt.DepositRequired {
+			appt.DepositAmount = settings.DepositAmount
+			appt.PaymentStatus = domain.PaymentPending
+		}
+	} else {
+		appt.DepositRequired = false
+	}
+
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.apptRepo.Create(ctx, appt); err != nil {
+			return err
+		}
+		return s.record(ctx, appt.ID, domain.EventCreated, "", appt.StartTime.Format(time.RFC3339))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if appt.DepositRequired {
+		if err := s.startDeposit(ctx, appt); err != nil {
+			return nil, nil, err
+		}
+	}
+	return appt, ownHold, nil
+}
+
+// finishBooking releases the hold and syncs a stored booking to the calendar (best-effort).
+func (s *AppointmentService) finishBooking(ctx context.Context, appt *domain.Appointment, ownHold *domain.SlotHold) {
+	// The hold served its purpose
+	if ownHold != nil {
+		_ = s.holdRepo.Delete(ctx, ownHold.ID)
 	}
 
 	// 4. Calendar Sync
@@ -140,42 +234,133 @@ func (s *AppointmentService) CreateAppointmentForClient(ctx context.Context, cli
 	// 	msg := "Tu turno fue registrado para " + appt.StartTime.Format("2006-01-02 15:04") + ". Pronto te confirmaremos."
 	// 	_ = s.msgSvc.SendWhatsApp(ctx, user.Phone, msg)
 	// }
+}
 
-	return appt, nil
+// checkHolds returns ErrSlotHeld if another client holds a slot overlapping [start, end).
+// It returns the caller's own hold, if holdToken matches one.
+func (s *AppointmentService) checkHolds(ctx context.Context, start, end time.Time, holdToken string) (*domain.SlotHold, error) {
+	if s.holdRepo == nil {
+		return nil, nil
+	}
+
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	holds, err := s.holdRepo.ListActiveByDateRange(ctx, day, day.Add(24*time.Hour), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var own *domain.SlotHold
+	for i := range holds {
+		h := &holds[i]
+		if !(h.StartTime.Before(end) && h.EndTime.After(start)) {
+			continue
+		}
+		if holdToken != "" && h.TokenHash == hashToken(holdToken) {
+			own = h
+			continue
+		}
+		return nil, ErrSlotHeld
+	}
+	return own, nil
+}
+
+// ErrInvalidTransition is returned when a status change is not allowed from the current status.
+var ErrInvalidTransition = errors.New("invalid appointment status transition")
+
+// transition moves the appointment to next if the transition table allows it.
+func (s *AppointmentService) transition(ctx context.Context, appt *domain.Appointment, next domain.AppointmentStatus) error {
+	if !appt.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: cannot change a %s appointment to %s", ErrInvalidTransition, appt.Status, next)
+	}
+	previous := appt.Status
+	appt.Status = next
+	err := inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.updateFrom(ctx, appt, previous); err != nil {
+			return err
+		}
+		return s.record(ctx, appt.ID, domain.EventForStatus(next), string(previous), string(next))
+	})
+	if err != nil {
+		appt.Status = previous
+	}
+	return err
+}
+
+// updateFrom saves appt only if it is still in status previous, with the payment status
+// read, so of two concurrent changes only one takes place and neither undoes the other.
+func (s *AppointmentService) updateFrom(ctx context.Context, appt *domain.Appointment, previous domain.AppointmentStatus) error {
+	updated, err := s.apptRepo.UpdateIf(ctx, appt, previous, appt.PaymentStatus)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("%w: the %s appointment changed meanwhile", ErrInvalidTransition, previous)
+	}
+	return nil
+}
+
+// record appends an event to the appointment's history, attributed to the
+// authenticated user in ctx. Call it in the transaction of the change it records,
+// so the history never misses a change nor has one that did not happen.
+func (s *AppointmentService) record(ctx context.Context, appointmentID uuid.UUID, eventType domain.AppointmentEventType, before, after string) error {
+	return recordEvent(ctx, s.eventRepo, appointmentID, eventType, before, after)
+}
+
+func recordEvent(ctx context.Context, eventRepo ports.AppointmentEventRepository, appointmentID uuid.UUID, eventType domain.AppointmentEventType, before, after string) error {
+	if eventRepo == nil {
+		return nil
+	}
+	event := &domain.AppointmentEvent{
+		AppointmentID: appointmentID,
+		Type:          eventType,
+		Before:        before,
+		After:         after,
+	}
+	if actor := domain.ActorFromContext(ctx); actor != nil {
+		event.ActorID = &actor.ID
+	}
+	if err := eventRepo.Create(ctx, event); err != nil {
+		return fmt.Errorf("recording %s event: %w", eventType, err)
+	}
+	return nil
 }
 
+// inTransaction runs fn in a transaction, or as is when there is no transactor.
+func inTransaction(ctx context.Context, tx ports.Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.InTransaction(ctx, fn)
+}
+
+var (
+	// ErrDepositPending is returned when confirming an appointment whose deposit was not paid yet.
+	ErrDepositPending = errors.New("the deposit for this appointment has not been paid yet")
+	// ErrApprovalPending is returned when confirming an appointment the admin has not approved yet.
+	ErrApprovalPending = errors.New("this appointment must be approved first")
+	// ErrNoApprovalPending is returned when approving or rejecting an appointment that needs no approval.
+	ErrNoApprovalPending = errors.New("this appointment is not waiting for approval")
+)
+
 func (s *AppointmentService) ConfirmAppointment(ctx context.Context, appointmentID uuid.UUID) error {
 	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
 	if err != nil {
 		return err
 	}
-	appt.Status = domain.StatusConfirmed
-	if err := s.apptRepo.Update(ctx, appt); err != nil {
+	if appt.RequiresApproval {
+		return ErrApprovalPending
+	}
+	if appt.DepositRequired && appt.PaymentStatus != domain.PaymentPaid {
+		return ErrDepositPending
+	}
+	if err := s.transition(ctx, appt, domain.StatusConfirmed); err != nil {
 		return err
 	}
 
 	// Send WhatsApp notification to client (best-effort)
 	if s.msgSvc != nil {
-		// Sanitize and Format phone number for WhatsApp (Argentina specific)
-		// Input: 03492-640018 -> Output: 5493492640018
 		rawPhone := appt.Client.Phone
-		sanitizedPhone := ""
-		for _, c := range rawPhone {
-			if c >= '0' && c <= '9' {
-				sanitizedPhone += string(c)
-			}
-		}
-
-		// Heuristic for Argentina:
-		// If starts with 0, replace with 549
-		if len(sanitizedPhone) > 1 && sanitizedPhone[0] == '0' {
-			sanitizedPhone = "549" + sanitizedPhone[1:]
-		} else if len(sanitizedPhone) > 0 && sanitizedPhone[0] != '5' {
-			// Assume local number without 0? e.g. 3492... -> 5493492...
-			// Or just leave it if we are unsure.
-			// Let's safe bet: if it doesn't start with 54, prepend 549?
-			// User example: +54 9 ... -> 549...
-		}
+		sanitizedPhone := normalizePhone(rawPhone)
 
 		log.Printf("[DEBUG] Confirming appointment. Client: %s, RawPhone: %s, Sanitized: %s", appt.Client.Name, rawPhone, sanitizedPhone)
 
@@ -190,20 +375,394 @@ func (s *AppointmentService) ConfirmAppointment(ctx context.Context, appointment
 	return nil
 }
 
+// ApproveAppointment accepts a booking by a client over the reliability threshold. It is
+// confirmed right away, or once paid if it also waits for a deposit.
+func (s *AppointmentService) ApproveAppointment(ctx context.Context, appointmentID uuid.UUID) error {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	if !appt.RequiresApproval || appt.Status != domain.StatusPending {
+		return ErrNoApprovalPending
+	}
+
+	appt.RequiresApproval = false
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.apptRepo.Update(ctx, appt); err != nil {
+			return err
+		}
+		return s.record(ctx, appt.ID, domain.EventApproved, "", "")
+	})
+	if err != nil {
+		return err
+	}
+	if appt.DepositRequired && appt.PaymentStatus != domain.PaymentPaid {
+		return nil
+	}
+	return s.ConfirmAppointment(ctx, appt.ID)
+}
+
+// RejectAppointment turns down a booking waiting for approval, cancelling it on behalf
+// of the shop. A deposit already paid is refunded.
+func (s *AppointmentService) RejectAppointment(ctx context.Context, appointmentID uuid.UUID, note string) error {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	if !appt.RequiresApproval || appt.Status != domain.StatusPending {
+		return ErrNoApprovalPending
+	}
+	return s.cancel(ctx, appt, domain.Cancellation{By: domain.CancelledByShop, Reason: domain.ReasonOther, Note: note})
+}
+
 func (s *AppointmentService) ListAppointments(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
 	return s.apptRepo.ListByDateRange(ctx, start, end)
 }
 
-func (s *AppointmentService) CancelAppointment(ctx context.Context, appointmentID uuid.UUID) error {
+func (s *AppointmentService) CancelAppointment(ctx context.Context, appointmentID uuid.UUID, cancellation domain.Cancellation) error {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	return s.cancel(ctx, appt, cancellation)
+}
+
+func (s *AppointmentService) CancelOwnAppointment(ctx context.Context, clientID, appointmentID uuid.UUID, reason domain.CancellationReason, note string) error {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	if appt.ClientID != clientID {
+		return gorm.ErrRecordNotFound // Do not reveal other clients' appointments
+	}
+	return s.cancel(ctx, appt, domain.Cancellation{By: domain.CancelledByClient, Reason: reason, Note: note})
+}
+
+func (s *AppointmentService) cancel(ctx context.Context, appt *domain.Appointment, cancellation domain.Cancellation) error {
+	if cancellation.By != domain.CancelledByClient && cancellation.By != domain.CancelledByShop {
+		return errors.New("cancelled_by must be client or shop")
+	}
+	if !cancellation.Reason.Valid() {
+		return fmt.Errorf("unknown cancellation reason %q", cancellation.Reason)
+	}
+
+	now := time.Now()
+	late := false
+	if cancellation.By == domain.CancelledByClient && s.policy != nil && appt.Status.CanTransitionTo(domain.StatusCancelled) {
+		var err error
+		if late, err = s.policy.IsLateCancellation(ctx, appt, now); err != nil {
+			return err
+		}
+	}
+
+	appt.CancelledBy = cancellation.By
+	appt.CancellationReason = cancellation.Reason
+	appt.CancellationNote = cancellation.Note
+	appt.CancelledAt = &now
+	appt.LateCancellation = late
+	// The late cancellation and the client's strike are recorded together, so neither is
+	// kept without the other
+	err := inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.transition(ctx, appt, domain.StatusCancelled); err != nil {
+			return err
+		}
+		if !late {
+			return nil
+		}
+		return s.policy.RecordLateCancellation(ctx, appt.ClientID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !late {
+		// Cancelled within policy: give the deposit back
+		s.refundDeposit(ctx, appt)
+	}
+
+	s.releaseSlot(ctx, appt)
+	return nil
+}
+
+func (s *AppointmentService) CompleteAppointment(ctx context.Context, appointmentID uuid.UUID) error {
+	appt, err := s.closeAppointment(ctx, appointmentID, domain.StatusCompleted)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.RecordVisit(ctx, appt.ClientID, appt.StartTime)
+}
+
+// MarkNoShow records the no-show and the client's strike together, so neither is
+// kept without the other.
+func (s *AppointmentService) MarkNoShow(ctx context.Context, appointmentID uuid.UUID) error {
+	return inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		appt, err := s.closeAppointment(ctx, appointmentID, domain.StatusNoShow)
+		if err != nil {
+			return err
+		}
+		if s.policy == nil {
+			return nil
+		}
+		return s.policy.RecordNoShow(ctx, appt.ClientID)
+	})
+}
+
+// closeAppointment records the outcome (completed / no-show) of an appointment that already started.
+func (s *AppointmentService) closeAppointment(ctx context.Context, appointmentID uuid.UUID, outcome domain.AppointmentStatus) (*domain.Appointment, error) {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(appt.StartTime) {
+		return nil, fmt.Errorf("%w: appointment has not started yet", ErrInvalidTransition)
+	}
+	if err := s.transition(ctx, appt, outcome); err != nil {
+		return nil, err
+	}
+	return appt, nil
+}
+
+func (s *AppointmentService) RescheduleAppointment(ctx context.Context, appointmentID uuid.UUID, newStart time.Time) (*domain.Appointment, error) {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	previous := appt.Status
+	if !previous.CanTransitionTo(domain.StatusRescheduled) {
+		return nil, fmt.Errorf("%w: cannot reschedule a %s appointment", ErrInvalidTransition, previous)
+	}
+
+	// The old appointment is only moved once the new one is booked, so neither is
+	// kept without the other
+	var newAppt *domain.Appointment
+	var ownHold *domain.SlotHold
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		// Free the old slot first so the new time may overlap it
+		appt.Status = domain.StatusRescheduled
+		if err := s.updateFrom(ctx, appt, previous); err != nil {
+			return err
+		}
+
+		// The deposit, if any, moves over from the original appointment instead of being charged again
+		var err error
+		newAppt, ownHold, err = s.createBooking(ctx, &appt.Client, domain.BookingRequest{
+			StartTime: newStart,
+			Service:   appt.Service,
+			Notes:     appt.Notes,
+			BarberID:  appt.BarberID,
+		}, true)
+		if err != nil {
+			return err
+		}
+
+		if err := s.record(ctx, appt.ID, domain.EventRescheduled, appt.StartTime.Format(time.RFC3339), newAppt.StartTime.Format(time.RFC3339)); err != nil {
+			return err
+		}
+
+		newAppt.RescheduledFromID = &appt.ID
+		newAppt.Status = previous // Keep the confirmation or pending approval, if any
+		newAppt.RequiresApproval = appt.RequiresApproval
+		newAppt.DepositRequired = appt.DepositRequired
+		newAppt.DepositAmount = appt.DepositAmount
+		newAppt.PaymentStatus = appt.PaymentStatus
+		newAppt.PaymentProviderID = appt.PaymentProviderID
+		newAppt.PaymentID = appt.PaymentID
+		newAppt.PaymentCheckoutURL = appt.PaymentCheckoutURL
+		return s.apptRepo.Update(ctx, newAppt)
+	})
+	if err != nil {
+		appt.Status = previous
+		return nil, err
+	}
+
+	s.finishBooking(ctx, newAppt, ownHold)
+	s.releaseSlot(ctx, appt)
+	return newAppt, nil
+}
+
+func (s *AppointmentService) UpdateNotes(ctx context.Context, appointmentID uuid.UUID, notes string) error {
 	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
 	if err != nil {
 		return err
 	}
-	appt.Status = domain.StatusCancelled
+	if appt.Notes == notes {
+		return nil
+	}
+
+	previous := appt.Notes
+	appt.Notes = notes
+	return inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.apptRepo.Update(ctx, appt); err != nil {
+			return err
+		}
+		return s.record(ctx, appt.ID, domain.EventNotesChanged, previous, notes)
+	})
+}
 
+// ErrNotABarber is returned when assigning an appointment to someone without an agenda.
+var ErrNotABarber = errors.New("this user does not take appointments")
+
+func (s *AppointmentService) AssignBarber(ctx context.Context, appointmentID uuid.UUID, barberID *uuid.UUID) (*domain.Appointment, error) {
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if barberID != nil {
+		if err := s.checkBarber(ctx, *barberID); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := appt.BarberID
+	appt.BarberID = barberID
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.apptRepo.Update(ctx, appt); err != nil {
+			return err
+		}
+		return s.record(ctx, appt.ID, domain.EventBarberAssigned, uuidString(previous), uuidString(barberID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appt, nil
+}
+
+func (s *AppointmentService) ListBarberAgenda(ctx context.Context, barberID uuid.UUID, start, end time.Time) ([]domain.Appointment, error) {
+	return s.apptRepo.ListByBarber(ctx, barberID, start, end)
+}
+
+// checkBarber makes sure appointments can be assigned to the user: an active
+// member of staff with an agenda of their own.
+func (s *AppointmentService) checkBarber(ctx context.Context, barberID uuid.UUID) error {
+	barber, err := s.userRepo.GetByID(ctx, barberID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotABarber
+	}
+	if err != nil {
+		return err
+	}
+	if !barber.Active() || !barber.Role.Can(domain.PermViewOwnAgenda) {
+		return ErrNotABarber
+	}
+	return nil
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func (s *AppointmentService) GetHistory(ctx context.Context, appointmentID uuid.UUID) ([]domain.AppointmentEvent, error) {
+	if _, err := s.apptRepo.GetByID(ctx, appointmentID); err != nil {
+		return nil, err
+	}
+	if s.eventRepo == nil {
+		return []domain.AppointmentEvent{}, nil
+	}
+	return s.eventRepo.ListByAppointment(ctx, appointmentID)
+}
+
+// startDeposit creates the payment at the provider and stores its checkout URL.
+// If the provider fails the booking is cancelled, since it could never be confirmed.
+func (s *AppointmentService) startDeposit(ctx context.Context, appt *domain.Appointment) error {
+	intent, err := s.payments.CreatePayment(ctx, appt, appt.DepositAmount)
+	if err != nil {
+		appt.Status = domain.StatusCancelled
+		appt.CancelledBy = domain.CancelledByShop
+		appt.CancellationReason = domain.ReasonOther
+		appt.CancellationNote = "could not start deposit payment"
+		_ = s.apptRepo.Update(ctx, appt)
+		return fmt.Errorf("starting deposit payment: %w", err)
+	}
+
+	appt.PaymentProviderID = intent.ProviderID
+	appt.PaymentCheckoutURL = intent.CheckoutURL
+	return s.apptRepo.Update(ctx, appt)
+}
+
+var (
+	// ErrPaymentMismatch is returned for a payment notification that is not for the
+	// deposit of its appointment, e.g. a different amount paid against the same reference.
+	ErrPaymentMismatch = errors.New("the payment does not match the deposit of the appointment")
+	// ErrAppointmentChanged is returned when the appointment changed while a payment was
+	// applied to it. Providers retry the webhook, which then sees the change.
+	ErrAppointmentChanged = errors.New("the appointment changed meanwhile, please try again")
+)
+
+func (s *AppointmentService) ApplyPayment(ctx context.Context, notification *domain.PaymentNotification) error {
+	appt, err := s.apptRepo.GetByID(ctx, notification.AppointmentID)
+	if err != nil {
+		return err
+	}
+	if !appt.DepositRequired || appt.PaymentStatus == notification.Status {
+		return nil // Providers retry webhooks; nothing new
+	}
+	if appt.PaymentStatus == domain.PaymentRefunded {
+		return nil
+	}
+	if math.Abs(notification.Amount-appt.DepositAmount) >= 0.01 || notification.Currency != domain.DepositCurrency {
+		return fmt.Errorf("%w: got %.2f %s, expected %.2f %s", ErrPaymentMismatch,
+			notification.Amount, notification.Currency, appt.DepositAmount, domain.DepositCurrency)
+	}
+
+	// Only over the state just read, so a cancellation landing meanwhile is not undone
+	previous := appt.PaymentStatus
+	appt.PaymentStatus = notification.Status
+	if notification.PaymentID != "" {
+		appt.PaymentID = notification.PaymentID
+	}
+	updated, err := s.apptRepo.UpdateIf(ctx, appt, appt.Status, previous)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrAppointmentChanged
+	}
+
+	if notification.Status != domain.PaymentPaid {
+		return nil
+	}
+
+	// Paid after the appointment was cancelled: give it back
+	if appt.Status == domain.StatusCancelled {
+		s.refundDeposit(ctx, appt)
+		return nil
+	}
+	// Bookings waiting for approval are confirmed when approved instead
+	if appt.Status.CanTransitionTo(domain.StatusConfirmed) && !appt.RequiresApproval {
+		return s.ConfirmAppointment(ctx, appt.ID)
+	}
+	return nil
+}
+
+// refundDeposit refunds a paid deposit (best-effort: failures are logged and
+// the appointment stays marked as paid so the admin can refund manually).
+func (s *AppointmentService) refundDeposit(ctx context.Context, appt *domain.Appointment) {
+	if s.payments == nil || appt.PaymentStatus != domain.PaymentPaid || appt.PaymentID == "" {
+		return
+	}
+	if err := s.payments.Refund(ctx, appt.PaymentID, appt.DepositAmount); err != nil {
+		log.Printf("Failed to refund deposit of appointment %s: %v", appt.ID, err)
+		return
+	}
+	appt.PaymentStatus = domain.PaymentRefunded
+	if updated, err := s.apptRepo.UpdateIf(ctx, appt, appt.Status, domain.PaymentPaid); err != nil || !updated {
+		log.Printf("Failed to mark deposit of appointment %s as refunded: %v", appt.ID, err)
+	}
+}
+
+// releaseSlot cleans up after an appointment stopped occupying its slot (best-effort).
+func (s *AppointmentService) releaseSlot(ctx context.Context, appt *domain.Appointment) {
 	if appt.GoogleEventID != "" {
 		_ = s.calendarSvc.DeleteEvent(ctx, appt.GoogleEventID)
 	}
 
-	return s.apptRepo.Update(ctx, appt)
+	// Offer the freed slot to the waitlist
+	if s.waitlist != nil {
+		if err := s.waitlist.OfferSlot(ctx, appt.StartTime); err != nil {
+			log.Printf("Failed to offer freed slot %s to waitlist: %v", appt.StartTime, err)
+		}
+	}
 }
@@ -0,0 +1,535 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+func TestAppointmentService_AssignBarber(t *testing.T) {
+	ctx := context.Background()
+	barber := &domain.User{ID: uuid.New(), Name: "Beto", Role: domain.RoleBarber}
+	receptionist := &domain.User{ID: uuid.New(), Name: "Rocío", Role: domain.RoleReceptionist}
+	deactivatedAt := time.Now()
+	former := &domain.User{ID: uuid.New(), Name: "Juan", Role: domain.RoleBarber, DeactivatedAt: &deactivatedAt}
+	appt := &domain.Appointment{ID: uuid.New(), StartTime: time.Now().Add(24 * time.Hour), Status: domain.StatusConfirmed}
+	apptRepo := &MockAppointmentRepo{GetByIDFunc: func(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+		return appt, nil
+	}}
+	svc := NewAppointmentService(apptRepo, nil, nil, NewMockUserRepo(barber, receptionist, former), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, user := range []*domain.User{receptionist, former, {ID: uuid.New()}} {
+		if _, err := svc.AssignBarber(ctx, appt.ID, &user.ID); !errors.Is(err, ErrNotABarber) {
+			t.Errorf("%s: expected ErrNotABarber, got %v", user.Name, err)
+		}
+	}
+
+	assigned, err := svc.AssignBarber(ctx, appt.ID, &barber.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assigned.BarberID == nil || *assigned.BarberID != barber.ID {
+		t.Fatalf("expected the appointment to be assigned to the barber, got %v", assigned.BarberID)
+	}
+	if unassigned, err := svc.AssignBarber(ctx, appt.ID, nil); err != nil || unassigned.BarberID != nil {
+		t.Errorf("expected the appointment to be unassigned, got %v, %v", unassigned, err)
+	}
+}
+
+func TestAppointmentService_BarberAgendaIsTheirsOnly(t *testing.T) {
+	barber, other := uuid.New(), uuid.New()
+	day := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	apptRepo := &MockAppointmentRepo{BarberAppointments: []domain.Appointment{
+		{ID: uuid.New(), StartTime: day.Add(10 * time.Hour), BarberID: &barber},
+		{ID: uuid.New(), StartTime: day.Add(11 * time.Hour), BarberID: &other},
+		{ID: uuid.New(), StartTime: day.Add(12 * time.Hour)},
+		{ID: uuid.New(), StartTime: day.Add(34 * time.Hour), BarberID: &barber},
+	}}
+	svc := NewAppointmentService(apptRepo, nil, nil, NewMockUserRepo(), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	agenda, err := svc.ListBarberAgenda(context.Background(), barber, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(agenda) != 1 || agenda[0].ID != apptRepo.BarberAppointments[0].ID {
+		t.Errorf("expected only the barber's appointment that day, got %+v", agenda)
+	}
+}
+
+// MockPayments records deposits started and refunds made.
+type MockPayments struct {
+	Created  map[uuid.UUID]float64
+	Refunded map[string]float64
+}
+
+func (m *MockPayments) CreatePayment(ctx context.Context, appt *domain.Appointment, amount float64) (*domain.PaymentIntent, error) {
+	m.Created[appt.ID] = amount
+	return &domain.PaymentIntent{ProviderID: "pref-" + appt.ID.String(), CheckoutURL: "http://pay/" + appt.ID.String()}, nil
+}
+func (m *MockPayments) VerifyWebhook(ctx context.Context, req ports.WebhookRequest) (*domain.PaymentNotification, error) {
+	return nil, nil
+}
+func (m *MockPayments) Refund(ctx context.Context, paymentID string, amount float64) error {
+	m.Refunded[paymentID] += amount
+	return nil
+}
+
+func newDepositFixture(t *testing.T) (*AppointmentService, *MockPayments, *domain.Appointment) {
+	t.Helper()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Phone: "1155550000", Role: domain.RoleClient, IsVerified: true}
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60}, nil
+	}}
+	settings := domain.DefaultSettings()
+	settings.DepositAmount = 5000
+	settings.DepositServices = "Corte"
+	payments := &MockPayments{Created: make(map[uuid.UUID]float64), Refunded: make(map[string]float64)}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	svc := NewAppointmentService(apptRepo, avail, nil, NewMockUserRepo(client), nil, nil, nil, &MockSettingsRepo{Settings: settings}, nil, payments, &MockCalendar{}, &MockSender{})
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	appt, err := svc.CreateAppointmentForClient(context.Background(), client.ID, domain.BookingRequest{StartTime: start, Service: "corte"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc, payments, appt
+}
+
+func TestApplyPayment_Deposit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("approved", func(t *testing.T) {
+		svc, payments, appt := newDepositFixture(t)
+		if !appt.DepositRequired || appt.PaymentStatus != domain.PaymentPending || payments.Created[appt.ID] != 5000 || appt.PaymentCheckoutURL == "" {
+			t.Fatalf("expected a pending deposit of 5000, got %+v", appt)
+		}
+		if err := svc.ConfirmAppointment(ctx, appt.ID); !errors.Is(err, ErrDepositPending) {
+			t.Errorf("expected ErrDepositPending before paying, got %v", err)
+		}
+
+		paid := &domain.PaymentNotification{PaymentID: "pay-1", AppointmentID: appt.ID, Status: domain.PaymentPaid, Amount: 5000, Currency: domain.DepositCurrency}
+		if err := svc.ApplyPayment(ctx, paid); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := svc.apptRepo.GetByID(ctx, appt.ID)
+		if got.Status != domain.StatusConfirmed || got.PaymentStatus != domain.PaymentPaid || got.PaymentID != "pay-1" {
+			t.Fatalf("expected a confirmed, paid appointment, got %s / %s", got.Status, got.PaymentStatus)
+		}
+
+		// Providers retry: the same notification again changes nothing
+		if err := svc.ApplyPayment(ctx, paid); err != nil {
+			t.Fatalf("unexpected error on a duplicate notification: %v", err)
+		}
+		if again, _ := svc.apptRepo.GetByID(ctx, appt.ID); again.Status != domain.StatusConfirmed || len(payments.Refunded) != 0 {
+			t.Errorf("expected the duplicate to be ignored, got %s and refunds %v", again.Status, payments.Refunded)
+		}
+
+		// Cancelled within policy: the deposit is given back
+		if err := svc.CancelAppointment(ctx, appt.ID, domain.Cancellation{By: domain.CancelledByShop, Reason: domain.ReasonOther}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if payments.Refunded["pay-1"] != 5000 {
+			t.Errorf("expected the deposit to be refunded, got %v", payments.Refunded)
+		}
+		if got, _ := svc.apptRepo.GetByID(ctx, appt.ID); got.PaymentStatus != domain.PaymentRefunded {
+			t.Errorf("expected the deposit marked refunded, got %s", got.PaymentStatus)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		svc, _, appt := newDepositFixture(t)
+		failed := &domain.PaymentNotification{PaymentID: "pay-2", AppointmentID: appt.ID, Status: domain.PaymentFailed, Amount: 5000, Currency: domain.DepositCurrency}
+		if err := svc.ApplyPayment(ctx, failed); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, _ := svc.apptRepo.GetByID(ctx, appt.ID); got.Status != domain.StatusPending || got.PaymentStatus != domain.PaymentFailed {
+			t.Errorf("expected a pending appointment with a failed deposit, got %s / %s", got.Status, got.PaymentStatus)
+		}
+	})
+
+	t.Run("wrong amount or currency", func(t *testing.T) {
+		svc, _, appt := newDepositFixture(t)
+		for _, n := range []domain.PaymentNotification{
+			{PaymentID: "pay-3", AppointmentID: appt.ID, Status: domain.PaymentPaid, Amount: 1, Currency: domain.DepositCurrency},
+			{PaymentID: "pay-4", AppointmentID: appt.ID, Status: domain.PaymentPaid, Amount: 5000, Currency: "USD"},
+		} {
+			if err := svc.ApplyPayment(ctx, &n); !errors.Is(err, ErrPaymentMismatch) {
+				t.Errorf("%s: expected ErrPaymentMismatch, got %v", n.PaymentID, err)
+			}
+		}
+		if got, _ := svc.apptRepo.GetByID(ctx, appt.ID); got.Status != domain.StatusPending || got.PaymentStatus != domain.PaymentPending {
+			t.Errorf("expected the appointment untouched, got %s / %s", got.Status, got.PaymentStatus)
+		}
+	})
+
+	t.Run("cancelled while applying", func(t *testing.T) {
+		svc, _, appt := newDepositFixture(t)
+		repo := svc.apptRepo.(*MockAppointmentRepo)
+		stale := *repo.Stored[appt.ID]
+		// The webhook read the appointment just before the client cancelled it
+		repo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+			copy := stale
+			return &copy, nil
+		}
+		repo.Stored[appt.ID].Status = domain.StatusCancelled
+
+		paid := &domain.PaymentNotification{PaymentID: "pay-5", AppointmentID: appt.ID, Status: domain.PaymentPaid, Amount: 5000, Currency: domain.DepositCurrency}
+		if err := svc.ApplyPayment(ctx, paid); !errors.Is(err, ErrAppointmentChanged) {
+			t.Fatalf("expected ErrAppointmentChanged, got %v", err)
+		}
+		if got := repo.Stored[appt.ID]; got.Status != domain.StatusCancelled || got.PaymentStatus != domain.PaymentPending {
+			t.Errorf("expected the cancellation kept, got %s / %s", got.Status, got.PaymentStatus)
+		}
+	})
+}
+
+type MockAppointmentEventRepo struct {
+	Events []domain.AppointmentEvent
+	Err    error
+}
+
+func (m *MockAppointmentEventRepo) Create(ctx context.Context, event *domain.AppointmentEvent) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Events = append(m.Events, *event)
+	return nil
+}
+func (m *MockAppointmentEventRepo) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.AppointmentEvent, error) {
+	var events []domain.AppointmentEvent
+	for _, e := range m.Events {
+		if e.AppointmentID == appointmentID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// MockTransactor runs fn as is, counting the transactions.
+type MockTransactor struct {
+	Count int
+}
+
+func (m *MockTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Count++
+	return fn(ctx)
+}
+
+func TestAppointmentHistory_RecordsChanges(t *testing.T) {
+	admin := domain.Actor{ID: uuid.New(), Role: domain.RoleAdmin}
+	ctx := domain.ContextWithActor(context.Background(), admin)
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true}
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60}, nil
+	}}
+	events := &MockAppointmentEventRepo{}
+	tx := &MockTransactor{}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	svc := NewAppointmentService(apptRepo, avail, nil, NewMockUserRepo(client), nil, events, tx, nil, nil, nil, &MockCalendar{}, nil)
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	appt, err := svc.CreateAppointmentForClient(ctx, client.ID, domain.BookingRequest{StartTime: start})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	moved, err := svc.RescheduleAppointment(ctx, appt.ID, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.CancelAppointment(ctx, moved.ID, domain.Cancellation{By: domain.CancelledByShop, Reason: domain.ReasonOther}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type want struct {
+		appointmentID uuid.UUID
+		eventType     domain.AppointmentEventType
+		before, after string
+	}
+	wants := []want{
+		{appt.ID, domain.EventCreated, "", start.Format(time.RFC3339)},
+		{moved.ID, domain.EventCreated, "", start.Add(2 * time.Hour).Format(time.RFC3339)},
+		{appt.ID, domain.EventRescheduled, start.Format(time.RFC3339), start.Add(2 * time.Hour).Format(time.RFC3339)},
+		{moved.ID, domain.EventCancelled, string(domain.StatusPending), string(domain.StatusCancelled)},
+	}
+	if len(events.Events) != len(wants) {
+		t.Fatalf("expected %d events, got %+v", len(wants), events.Events)
+	}
+	for i, w := range wants {
+		e := events.Events[i]
+		if e.AppointmentID != w.appointmentID || e.Type != w.eventType || e.Before != w.before || e.After != w.after {
+			t.Errorf("event %d: expected %+v, got %+v", i, w, e)
+		}
+		if e.ActorID == nil || *e.ActorID != admin.ID {
+			t.Errorf("event %d: expected to be attributed to the admin, got %v", i, e.ActorID)
+		}
+	}
+	if tx.Count == 0 {
+		t.Error("expected the changes to be written in transactions")
+	}
+
+	history, err := svc.GetHistory(ctx, appt.ID)
+	if err != nil || len(history) != 2 {
+		t.Errorf("expected the original appointment's two events, got %+v, %v", history, err)
+	}
+}
+
+func TestAppointmentHistory_FailedEventFailsTheChange(t *testing.T) {
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	appt := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, StartTime: time.Now().Add(48 * time.Hour), Status: domain.StatusPending}
+	apptRepo.Stored[appt.ID] = appt
+	events := &MockAppointmentEventRepo{Err: errors.New("database is down")}
+	svc := NewAppointmentService(apptRepo, nil, nil, NewMockUserRepo(client), nil, events, &MockTransactor{}, nil, nil, nil, nil, nil)
+
+	if err := svc.UpdateNotes(context.Background(), appt.ID, "Trae a su hijo"); err == nil {
+		t.Error("expected the change to fail when its event cannot be recorded")
+	}
+}
+
+func TestAppointmentHistory_WithoutEventRepository(t *testing.T) {
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	appt := &domain.Appointment{ID: uuid.New()}
+	apptRepo.Stored[appt.ID] = appt
+	svc := NewAppointmentService(apptRepo, nil, nil, NewMockUserRepo(), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	if history, err := svc.GetHistory(context.Background(), appt.ID); err != nil || len(history) != 0 {
+		t.Errorf("expected an empty history, got %+v, %v", history, err)
+	}
+}
+
+func TestAppointmentService_MarkNoShowRecordsTheStrike(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Role: domain.RoleClient}
+	users := NewMockUserRepo(client)
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	appt := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, StartTime: time.Now().Add(-time.Hour), Status: domain.StatusConfirmed}
+	apptRepo.Stored[appt.ID] = appt
+	policy := NewPolicyService(users, &MockSettingsRepo{Settings: &domain.Settings{BlockAfterStrikes: 1}})
+	tx := &MockTransactor{}
+	svc := NewAppointmentService(apptRepo, nil, nil, users, nil, nil, tx, nil, policy, nil, nil, nil)
+
+	if err := svc.MarkNoShow(ctx, appt.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apptRepo.Stored[appt.ID].Status != domain.StatusNoShow {
+		t.Errorf("expected a no-show, got %s", apptRepo.Stored[appt.ID].Status)
+	}
+	if u := users.Users[client.ID]; u.NoShowCount != 1 || !u.BookingBlocked {
+		t.Errorf("expected the strike to block the client, got %+v", u)
+	}
+	if tx.Count != 2 {
+		t.Errorf("expected the status change nested in the no-show transaction, got %d transactions", tx.Count)
+	}
+}
+
+func TestAppointmentService_Approval(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true, NoShowCount: 2}
+	users := NewMockUserRepo(client)
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60}, nil
+	}}
+	settings := &MockSettingsRepo{Settings: &domain.Settings{RestrictAfterStrikes: 2, RestrictionMode: domain.RestrictApproval}}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	svc := NewAppointmentService(apptRepo, avail, nil, users, nil, nil, nil, settings, NewPolicyService(users, settings), nil, &MockCalendar{}, nil)
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	approved, err := svc.CreateAppointmentForClient(ctx, client.ID, domain.BookingRequest{StartTime: start})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !approved.RequiresApproval || approved.Status != domain.StatusPending {
+		t.Fatalf("expected a booking waiting for approval, got %+v", approved)
+	}
+	if err := svc.ConfirmAppointment(ctx, approved.ID); !errors.Is(err, ErrApprovalPending) {
+		t.Errorf("expected ErrApprovalPending, got %v", err)
+	}
+	if err := svc.ApproveAppointment(ctx, approved.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := apptRepo.Stored[approved.ID]; got.RequiresApproval || got.Status != domain.StatusConfirmed {
+		t.Errorf("expected the approved booking confirmed, got %+v", got)
+	}
+	if err := svc.ApproveAppointment(ctx, approved.ID); !errors.Is(err, ErrNoApprovalPending) {
+		t.Errorf("expected ErrNoApprovalPending, got %v", err)
+	}
+
+	rejected, err := svc.CreateAppointmentForClient(ctx, client.ID, domain.BookingRequest{StartTime: start.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.RejectAppointment(ctx, rejected.ID, "demasiadas ausencias"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := apptRepo.Stored[rejected.ID]; got.Status != domain.StatusCancelled || got.CancelledBy != domain.CancelledByShop {
+		t.Errorf("expected the rejected booking cancelled by the shop, got %+v", got)
+	}
+}
+
+// snapshotTransactor rolls the stored appointments back when fn fails.
+type snapshotTransactor struct {
+	repo  *MockAppointmentRepo
+	count int
+}
+
+func (m *snapshotTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.count++
+	snapshot := make(map[uuid.UUID]*domain.Appointment, len(m.repo.Stored))
+	for id, appt := range m.repo.Stored {
+		copy := *appt
+		snapshot[id] = &copy
+	}
+	if err := fn(ctx); err != nil {
+		m.repo.Stored = snapshot
+		return err
+	}
+	return nil
+}
+
+func TestAppointmentService_RescheduleInOneTransaction(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true}
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60, IsBlocked: date != start.Format("2006-01-02")}, nil
+	}}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	appt := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, Client: *client, StartTime: start, EndTime: start.Add(time.Hour), Status: domain.StatusConfirmed}
+	apptRepo.Stored[appt.ID] = appt
+	tx := &snapshotTransactor{repo: apptRepo}
+	svc := NewAppointmentService(apptRepo, avail, nil, NewMockUserRepo(client), nil, nil, tx, nil, nil, nil, &MockCalendar{}, nil)
+
+	// The new day is closed: the original appointment is left as it was
+	if _, err := svc.RescheduleAppointment(ctx, appt.ID, start.Add(24*time.Hour)); err == nil {
+		t.Fatal("expected the reschedule to fail")
+	}
+	if tx.count != 1 || apptRepo.Stored[appt.ID].Status != domain.StatusConfirmed {
+		t.Errorf("expected the reschedule rolled back as one transaction, got %d transactions and %s", tx.count, apptRepo.Stored[appt.ID].Status)
+	}
+
+	moved, err := svc.RescheduleAppointment(ctx, appt.ID, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moved.Status != domain.StatusConfirmed || *moved.RescheduledFromID != appt.ID || apptRepo.Stored[appt.ID].Status != domain.StatusRescheduled {
+		t.Errorf("expected the confirmation carried over, got %+v", moved)
+	}
+}
+
+func TestAppointmentService_RescheduleKeepsTheBooking(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true, NoShowCount: 2, BookingBlocked: true}
+	users := NewMockUserRepo(client)
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60}, nil
+	}}
+	settings := &MockSettingsRepo{Settings: &domain.Settings{RestrictAfterStrikes: 2, RestrictionMode: domain.RestrictApproval}}
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	svc := NewAppointmentService(apptRepo, avail, nil, users, nil, nil, nil, settings, NewPolicyService(users, settings), nil, &MockCalendar{}, nil)
+
+	// Booked before the client was blocked, one confirmed and one still waiting for approval
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	confirmed := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, Client: *client, StartTime: start, EndTime: start.Add(time.Hour), Status: domain.StatusConfirmed}
+	waiting := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, Client: *client, StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour), Status: domain.StatusPending, RequiresApproval: true}
+	apptRepo.Stored[confirmed.ID] = confirmed
+	apptRepo.Stored[waiting.ID] = waiting
+
+	moved, err := svc.RescheduleAppointment(ctx, confirmed.ID, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("expected staff to move a blocked client's booking, got %v", err)
+	}
+	if moved.Status != domain.StatusConfirmed || moved.RequiresApproval {
+		t.Errorf("expected the confirmation carried over without approval, got %+v", moved)
+	}
+	moved, err = svc.RescheduleAppointment(ctx, waiting.ID, start.Add(26*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moved.Status != domain.StatusPending || !moved.RequiresApproval {
+		t.Errorf("expected the booking still waiting for approval, got %+v", moved)
+	}
+}
+
+func TestAppointmentService_ConcurrentTransition(t *testing.T) {
+	ctx := context.Background()
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	appt := &domain.Appointment{ID: uuid.New(), StartTime: time.Now().Add(48 * time.Hour), Status: domain.StatusPending}
+	stale := *appt
+	apptRepo.Stored[appt.ID] = appt
+	svc := NewAppointmentService(apptRepo, nil, nil, nil, nil, nil, nil, nil, nil, nil, &MockCalendar{}, nil)
+
+	// Read as pending, but cancelled before the confirmation is written
+	apptRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+		copy := stale
+		return &copy, nil
+	}
+	appt.Status = domain.StatusCancelled
+
+	if err := svc.ConfirmAppointment(ctx, appt.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if got := apptRepo.Stored[appt.ID].Status; got != domain.StatusCancelled {
+		t.Errorf("expected the cancellation kept, got %s", got)
+	}
+}
+
+func TestAppointmentService_BookingFollowsOpeningHours(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Role: domain.RoleClient, IsVerified: true}
+	holidays := &MockHolidayProvider{Holidays: map[string]domain.Holiday{
+		"2026-07-09": {Date: "2026-07-09", Name: "Día de la Independencia", Type: domain.HolidayFixed},
+	}}
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "09:00", EndTime: "18:00", SlotDuration: 60}, nil
+	}}
+	settings := &MockSettingsRepo{Settings: &domain.Settings{ClosedOnHolidays: true}}
+	svc := NewAppointmentService(&MockAppointmentRepo{}, avail, holidays, NewMockUserRepo(client), nil, nil, nil, settings, nil, nil, &MockCalendar{}, nil)
+
+	for _, start := range []time.Time{
+		time.Date(2026, 7, 9, 10, 0, 0, 0, time.UTC),  // Holiday
+		time.Date(2026, 7, 10, 8, 0, 0, 0, time.UTC),  // Before opening
+		time.Date(2026, 7, 10, 19, 0, 0, 0, time.UTC), // After closing
+	} {
+		if _, err := svc.CreateAppointmentForClient(ctx, client.ID, domain.BookingRequest{StartTime: start}); err == nil {
+			t.Errorf("%s: expected the booking to be rejected", start)
+		}
+	}
+	if _, err := svc.CreateAppointmentForClient(ctx, client.ID, domain.BookingRequest{StartTime: time.Date(2026, 7, 10, 10, 0, 0, 0, time.UTC)}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAppointmentService_LateCancellationRecordsTheStrike(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Role: domain.RoleClient}
+	users := NewMockUserRepo(client)
+	apptRepo := &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)}
+	soon := time.Now().Add(2 * time.Hour)
+	appt := &domain.Appointment{ID: uuid.New(), ClientID: client.ID, StartTime: soon, EndTime: soon.Add(time.Hour), Status: domain.StatusConfirmed}
+	apptRepo.Stored[appt.ID] = appt
+	policy := NewPolicyService(users, &MockSettingsRepo{Settings: &domain.Settings{LateCancelHours: 24}})
+	tx := &snapshotTransactor{repo: apptRepo}
+	svc := NewAppointmentService(apptRepo, nil, nil, users, nil, nil, tx, nil, policy, nil, &MockCalendar{}, nil)
+
+	// The strike cannot be stored: the cancellation is not kept either
+	delete(users.Users, client.ID)
+	if err := svc.CancelOwnAppointment(ctx, client.ID, appt.ID, domain.ReasonOther, ""); err == nil {
+		t.Fatal("expected the cancellation to fail with its strike")
+	}
+	if got := apptRepo.Stored[appt.ID]; got.Status != domain.StatusConfirmed {
+		t.Errorf("expected the cancellation rolled back, got %s", got.Status)
+	}
+
+	users.Users[client.ID] = client
+	if err := svc.CancelOwnAppointment(ctx, client.ID, appt.ID, domain.ReasonOther, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := apptRepo.Stored[appt.ID]; got.Status != domain.StatusCancelled || !got.LateCancellation || client.LateCancelCount != 1 {
+		t.Errorf("expected a late cancellation with its strike, got %+v and %d", got, client.LateCancelCount)
+	}
+}
@@ -11,22 +11,28 @@ import (
 )
 
 type AvailabilityService struct {
-	repo     ports.AvailabilityRepository
-	apptRepo ports.AppointmentRepository
+	repo         ports.AvailabilityRepository
+	apptRepo     ports.AppointmentRepository
+	holdRepo     ports.SlotHoldRepository
+	settingsRepo ports.SettingsRepository
+	holidays     ports.HolidayProvider
 }
 
-func NewAvailabilityService(repo ports.AvailabilityRepository, apptRepo ports.AppointmentRepository) *AvailabilityService {
-	return &AvailabilityService{repo: repo, apptRepo: apptRepo}
+// NewAvailabilityService builds the service. holdRepo, settingsRepo and holidays may be nil,
+// in which case slot holds and holidays are not taken into account.
+func NewAvailabilityService(repo ports.AvailabilityRepository, apptRepo ports.AppointmentRepository, holdRepo ports.SlotHoldRepository, settingsRepo ports.SettingsRepository, holidays ports.HolidayProvider) *AvailabilityService {
+	return &AvailabilityService{repo: repo, apptRepo: apptRepo, holdRepo: holdRepo, settingsRepo: settingsRepo, holidays: holidays}
 }
 
-func (s *AvailabilityService) SetAvailability(ctx context.Context, date, start, end string, duration int) error {
+func (s *AvailabilityService) SetAvailability(ctx context.Context, date, start, end string, duration int, openOnHoliday bool) error {
 	// TODO: Validate date format YYYY-MM-DD
 	availability := &domain.Availability{
-		Date:         date,
-		StartTime:    start,
-		EndTime:      end,
-		SlotDuration: duration,
-		IsBlocked:    false,
+		Date:          date,
+		StartTime:     start,
+		EndTime:       end,
+		SlotDuration:  duration,
+		IsBlocked:     false,
+		OpenOnHoliday: openOnHoliday,
 	}
 	return s.repo.Save(ctx, availability)
 }
@@ -35,18 +41,37 @@ func (s *AvailabilityService) GetAvailability(ctx context.Context) ([]domain.Ava
 	return s.repo.ListAll(ctx)
 }
 
-func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.Time) ([]time.Time, error) {
-	// 1. Get availability for specific date
-	dateStr := date.Format("2006-01-02")
-	avail, err := s.repo.GetByDate(ctx, dateStr)
+// workingDay is when the shop takes appointments on a day, as wall clock labelled UTC.
+type workingDay struct {
+	Start, End   time.Time // Slots may start from Start up to End, both included
+	SlotDuration time.Duration
+}
+
+// Includes reports whether a slot may start at t.
+func (d *workingDay) Includes(t time.Time) bool {
+	return !t.Before(d.Start) && !t.After(d.End)
+}
+
+// openingHours returns the working day of date, or nil when the shop does not open
+// then: no hours were set, the day is blocked or it is closed for a holiday.
+// Both the slot listing and bookings go through it, so they always agree.
+func openingHours(ctx context.Context, repo ports.AvailabilityRepository, settingsRepo ports.SettingsRepository, holidays ports.HolidayProvider, date time.Time) (*workingDay, error) {
+	avail, err := repo.GetByDate(ctx, date.Format("2006-01-02"))
 	if err != nil {
 		return nil, err
 	}
 	if avail == nil || avail.IsBlocked {
-		return []time.Time{}, nil
+		return nil, nil
+	}
+
+	closed, err := closedForHoliday(ctx, settingsRepo, holidays, date, avail)
+	if err != nil {
+		return nil, err
+	}
+	if closed {
+		return nil, nil
 	}
 
-	// 2. Parse start and end times
 	start, err := time.Parse("15:04", avail.StartTime)
 	if err != nil {
 		return nil, errors.New("invalid start time format configured")
@@ -56,12 +81,31 @@ func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.T
 		return nil, errors.New("invalid end time format configured")
 	}
 
-	// 3. Generate 1 hour slots
-	var slots []time.Time
+	day := &workingDay{
+		Start:        time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC),
+		End:          time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), 0, 0, time.UTC),
+		SlotDuration: time.Duration(avail.SlotDuration) * time.Minute,
+	}
+	if avail.SlotDuration == 0 {
+		day.SlotDuration = 60 * time.Minute
+	}
+	return day, nil
+}
+
+func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.Time, holdToken string) ([]time.Time, error) {
+	// 1. Get the working hours of the date
+	day, err := openingHours(ctx, s.repo, s.settingsRepo, s.holidays, date)
+	if err != nil {
+		return nil, err
+	}
+	if day == nil {
+		return []time.Time{}, nil
+	}
 
-	// Create start time on the specific date in UTC
-	current := time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
-	endTime := time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), 0, 0, time.UTC)
+	// 2. Generate the slots
+	var slots []time.Time
+	current := day.Start
+	endTime := day.End
 
 	// fetch appointments for the day to exclude (in UTC)
 	appts, err := s.apptRepo.ListByDateRange(ctx, time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, time.UTC))
@@ -69,18 +113,28 @@ func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.T
 		return nil, err
 	}
 
-	// Determine slot duration
-	slotDuration := time.Duration(avail.SlotDuration) * time.Minute
-	if avail.SlotDuration == 0 {
-		slotDuration = 60 * time.Minute
+	// fetch slots held by other clients that are still filling the booking form
+	var holds []domain.SlotHold
+	if s.holdRepo != nil {
+		dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		holds, err = s.holdRepo.ListActiveByDateRange(ctx, dayStart, dayStart.Add(24*time.Hour), time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
 	}
+	ownHold := ""
+	if holdToken != "" {
+		ownHold = hashToken(holdToken)
+	}
+
+	slotDuration := day.SlotDuration
 
 	// Generate slots: include any slot whose START time is before the end time
 	// This ensures if end time is 13:00, we include the 12:00-13:00 slot
 	for !current.After(endTime) {
 		occupied := false
 		for _, a := range appts {
-			if a.Status == domain.StatusCancelled {
+			if !a.Status.OccupiesSlot() {
 				continue
 			}
 			// if appointment overlaps this slot (any overlap), mark occupied
@@ -90,6 +144,15 @@ func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.T
 				break
 			}
 		}
+		for _, hold := range holds {
+			if hold.TokenHash == ownHold {
+				continue
+			}
+			if hold.StartTime.Before(current.Add(slotDuration)) && hold.EndTime.After(current) {
+				occupied = true
+				break
+			}
+		}
 		if !occupied {
 			slots = append(slots, current)
 		}
@@ -99,6 +162,40 @@ func (s *AvailabilityService) GetAvailableSlots(ctx context.Context, date time.T
 	return slots, nil
 }
 
+func (s *AvailabilityService) IsOpen(ctx context.Context, date time.Time) (bool, error) {
+	day, err := openingHours(ctx, s.repo, s.settingsRepo, s.holidays, date)
+	return day != nil, err
+}
+
+// closedForHoliday reports whether the date is a public holiday that should be
+// treated as closed: the admin setting is on and the day's Availability does not opt in.
+func closedForHoliday(ctx context.Context, settingsRepo ports.SettingsRepository, holidays ports.HolidayProvider, date time.Time, avail *domain.Availability) (bool, error) {
+	if holidays == nil || settingsRepo == nil || avail.OpenOnHoliday {
+		return false, nil
+	}
+
+	settings, err := settingsRepo.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !settings.ClosedOnHolidays {
+		return false, nil
+	}
+
+	holiday, err := holidays.GetHoliday(ctx, date)
+	if err != nil {
+		return false, err
+	}
+	return holiday != nil, nil
+}
+
+func (s *AvailabilityService) ListHolidays(ctx context.Context, from, to time.Time) ([]domain.Holiday, error) {
+	if s.holidays == nil {
+		return []domain.Holiday{}, nil
+	}
+	return s.holidays.ListHolidays(ctx, from, to)
+}
+
 func (s *AvailabilityService) DeleteAvailability(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
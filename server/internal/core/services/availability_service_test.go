@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
 )
 
 // Manual mocks since we don't need full gomock power for this simple case
@@ -29,35 +30,91 @@ func (m *MockAvailabilityRepo) Delete(ctx context.Context, id uuid.UUID) error {
 
 type MockAppointmentRepo struct {
 	ListByDateRangeFunc func(ctx context.Context, start, end time.Time) ([]domain.Appointment, error)
+	GetByIDFunc         func(ctx context.Context, id uuid.UUID) (*domain.Appointment, error)
+	ExportRows          []domain.AppointmentExportRow
+	ClientAppointments  []domain.Appointment
+	BarberAppointments  []domain.Appointment
+	// Stored keeps created and updated appointments for GetByID, when not nil
+	Stored map[uuid.UUID]*domain.Appointment
 }
 
 func (m *MockAppointmentRepo) Create(ctx context.Context, appointment *domain.Appointment) error {
-	return nil
+	if appointment.ID == uuid.Nil {
+		appointment.ID = uuid.New()
+	}
+	return m.Update(ctx, appointment)
 }
 func (m *MockAppointmentRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(ctx, id)
+	}
+	if m.Stored != nil {
+		appt, ok := m.Stored[id]
+		if !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+		copy := *appt
+		return &copy, nil
+	}
 	return nil, nil
 }
 func (m *MockAppointmentRepo) Update(ctx context.Context, appointment *domain.Appointment) error {
+	if m.Stored != nil {
+		copy := *appointment
+		m.Stored[appointment.ID] = &copy
+	}
 	return nil
 }
+func (m *MockAppointmentRepo) UpdateIf(ctx context.Context, appointment *domain.Appointment, status domain.AppointmentStatus, paymentStatus domain.PaymentStatus) (bool, error) {
+	if m.Stored != nil {
+		stored, ok := m.Stored[appointment.ID]
+		if !ok || stored.Status != status || stored.PaymentStatus != paymentStatus {
+			return false, nil
+		}
+	}
+	return true, m.Update(ctx, appointment)
+}
 func (m *MockAppointmentRepo) ListByDateRange(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
 	return m.ListByDateRangeFunc(ctx, start, end)
 }
+func (m *MockAppointmentRepo) ListByBarber(ctx context.Context, barberID uuid.UUID, start, end time.Time) ([]domain.Appointment, error) {
+	var appts []domain.Appointment
+	for _, appt := range m.BarberAppointments {
+		if appt.BarberID != nil && *appt.BarberID == barberID && !appt.StartTime.Before(start) && appt.StartTime.Before(end) {
+			appts = append(appts, appt)
+		}
+	}
+	return appts, nil
+}
+func (m *MockAppointmentRepo) ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.Appointment, error) {
+	return m.ClientAppointments, nil
+}
 func (m *MockAppointmentRepo) CountByMonth(ctx context.Context, month time.Month, year int) (int64, error) {
 	return 0, nil
 }
 func (m *MockAppointmentRepo) CountCompletedByMonth(ctx context.Context, month time.Month, year int) (int64, error) {
 	return 0, nil
 }
+func (m *MockAppointmentRepo) CountNoShowsByMonth(ctx context.Context, month time.Month, year int) (int64, error) {
+	return 0, nil
+}
 func (m *MockAppointmentRepo) CountByStatus(ctx context.Context, status domain.AppointmentStatus) (int64, error) {
 	return 0, nil
 }
+func (m *MockAppointmentRepo) EachForExport(ctx context.Context, filter domain.AppointmentExportFilter, fn func(row *domain.AppointmentExportRow) error) error {
+	for i := range m.ExportRows {
+		if err := fn(&m.ExportRows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 func TestGetAvailableSlots_EndTimeIncluded(t *testing.T) {
 	// Setup
 	mockAvailRepo := &MockAvailabilityRepo{}
 	mockApptRepo := &MockAppointmentRepo{}
-	svc := NewAvailabilityService(mockAvailRepo, mockApptRepo)
+	svc := NewAvailabilityService(mockAvailRepo, mockApptRepo, nil, nil, nil)
 
 	ctx := context.Background()
 	date := time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC)
@@ -82,7 +139,7 @@ func TestGetAvailableSlots_EndTimeIncluded(t *testing.T) {
 	}
 
 	// Execute
-	slots, err := svc.GetAvailableSlots(ctx, date)
+	slots, err := svc.GetAvailableSlots(ctx, date, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -107,7 +164,7 @@ func TestGetAvailableSlots_30MinuteSlots(t *testing.T) {
 	// Setup
 	mockAvailRepo := &MockAvailabilityRepo{}
 	mockApptRepo := &MockAppointmentRepo{}
-	svc := NewAvailabilityService(mockAvailRepo, mockApptRepo)
+	svc := NewAvailabilityService(mockAvailRepo, mockApptRepo, nil, nil, nil)
 
 	ctx := context.Background()
 	date := time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC)
@@ -133,7 +190,7 @@ func TestGetAvailableSlots_30MinuteSlots(t *testing.T) {
 	}
 
 	// Execute
-	slots, err := svc.GetAvailableSlots(ctx, date)
+	slots, err := svc.GetAvailableSlots(ctx, date, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -153,3 +210,153 @@ func TestGetAvailableSlots_30MinuteSlots(t *testing.T) {
 		}
 	}
 }
+
+type MockSettingsRepo struct {
+	Settings *domain.Settings
+}
+
+func (m *MockSettingsRepo) Get(ctx context.Context) (*domain.Settings, error) {
+	if m.Settings == nil {
+		return domain.DefaultSettings(), nil
+	}
+	return m.Settings, nil
+}
+func (m *MockSettingsRepo) Save(ctx context.Context, settings *domain.Settings) error {
+	m.Settings = settings
+	return nil
+}
+
+type MockHolidayProvider struct {
+	Holidays map[string]domain.Holiday
+}
+
+func (m *MockHolidayProvider) GetHoliday(ctx context.Context, date time.Time) (*domain.Holiday, error) {
+	h, ok := m.Holidays[date.Format("2006-01-02")]
+	if !ok {
+		return nil, nil
+	}
+	return &h, nil
+}
+func (m *MockHolidayProvider) ListHolidays(ctx context.Context, from, to time.Time) ([]domain.Holiday, error) {
+	return nil, nil
+}
+
+func TestGetAvailableSlots_Holidays(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2026, 7, 9, 0, 0, 0, 0, time.UTC)
+	holidays := &MockHolidayProvider{Holidays: map[string]domain.Holiday{
+		"2026-07-09": {Date: "2026-07-09", Name: "Día de la Independencia", Type: domain.HolidayFixed},
+	}}
+	noAppts := &MockAppointmentRepo{ListByDateRangeFunc: func(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
+		return nil, nil
+	}}
+
+	tests := []struct {
+		name          string
+		closed        bool
+		openOnHoliday bool
+		wantSlots     int
+	}{
+		{"closed on holidays", true, false, 0},
+		{"availability overrides holiday", true, true, 2},
+		{"setting disabled", false, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			availRepo := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, d string) (*domain.Availability, error) {
+				return &domain.Availability{Date: d, StartTime: "09:00", EndTime: "10:00", OpenOnHoliday: tt.openOnHoliday}, nil
+			}}
+			settings := &MockSettingsRepo{Settings: &domain.Settings{ClosedOnHolidays: tt.closed}}
+			svc := NewAvailabilityService(availRepo, noAppts, nil, settings, holidays)
+
+			slots, err := svc.GetAvailableSlots(ctx, date, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(slots) != tt.wantSlots {
+				t.Errorf("expected %d slots, got %d", tt.wantSlots, len(slots))
+			}
+		})
+	}
+}
+
+type MockSlotHoldRepo struct {
+	Holds []domain.SlotHold
+}
+
+// Create stores the hold, failing like the database when it overlaps an active one.
+func (m *MockSlotHoldRepo) Create(ctx context.Context, hold *domain.SlotHold) error {
+	for _, h := range m.Holds {
+		if h.StartTime.Before(hold.EndTime) && h.EndTime.After(hold.StartTime) && (h.ExpiresAt.IsZero() || h.ExpiresAt.After(time.Now())) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	hold.ID = uuid.New()
+	m.Holds = append(m.Holds, *hold)
+	return nil
+}
+func (m *MockSlotHoldRepo) GetByTokenHash(ctx context.Context, hash string) (*domain.SlotHold, error) {
+	for _, h := range m.Holds {
+		if h.TokenHash == hash {
+			copy := h
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockSlotHoldRepo) ListActiveByDateRange(ctx context.Context, start, end, now time.Time) ([]domain.SlotHold, error) {
+	return m.Holds, nil
+}
+func (m *MockSlotHoldRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	for i, h := range m.Holds {
+		if h.ID == id {
+			m.Holds = append(m.Holds[:i], m.Holds[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+func (m *MockSlotHoldRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestGetAvailableSlots_HeldSlots(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC)
+	heldStart := time.Date(2024, 1, 24, 10, 0, 0, 0, time.UTC)
+
+	availRepo := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, d string) (*domain.Availability, error) {
+		return &domain.Availability{Date: d, StartTime: "09:00", EndTime: "11:00"}, nil
+	}}
+	apptRepo := &MockAppointmentRepo{ListByDateRangeFunc: func(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
+		return nil, nil
+	}}
+	holdRepo := &MockSlotHoldRepo{Holds: []domain.SlotHold{
+		{TokenHash: hashToken("holder-token"), StartTime: heldStart, EndTime: heldStart.Add(time.Hour)},
+	}}
+	svc := NewAvailabilityService(availRepo, apptRepo, holdRepo, nil, nil)
+
+	// Other clients do not see the held slot
+	slots, err := svc.GetAvailableSlots(ctx, date, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, slot := range slots {
+		if slot.Equal(heldStart) {
+			t.Errorf("held slot %s should not be offered to other clients", heldStart.Format("15:04"))
+		}
+	}
+	if len(slots) != 2 {
+		t.Errorf("expected 2 slots, got %d", len(slots))
+	}
+
+	// The holder still sees it
+	slots, err = svc.GetAvailableSlots(ctx, date, "holder-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 3 {
+		t.Errorf("expected 3 slots for the holder, got %d", len(slots))
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+var ErrEmptyNote = errors.New("note body is required")
+
+// ClientService builds client profiles and manages the shop's private notes on clients.
+type ClientService struct {
+	userRepo ports.UserRepository
+	apptRepo ports.AppointmentRepository
+	noteRepo ports.ClientNoteRepository
+}
+
+func NewClientService(userRepo ports.UserRepository, apptRepo ports.AppointmentRepository, noteRepo ports.ClientNoteRepository) *ClientService {
+	return &ClientService{userRepo: userRepo, apptRepo: apptRepo, noteRepo: noteRepo}
+}
+
+func (s *ClientService) GetProfile(ctx context.Context, clientID uuid.UUID) (*domain.ClientProfile, error) {
+	client, err := s.userRepo.GetByID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	appts, err := s.apptRepo.ListByClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.noteRepo.ListByClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &domain.ClientProfile{
+		Client:       *client,
+		LastVisitAt:  client.LastVisitAt,
+		Appointments: appts,
+		Notes:        notes,
+	}
+	// Appointments come newest first: the first completed one is the last visit
+	for _, appt := range appts {
+		if appt.Status != domain.StatusCompleted {
+			continue
+		}
+		if profile.TotalVisits == 0 {
+			profile.LastService = appt.Service
+		}
+		profile.TotalVisits++
+	}
+	if profile.Appointments == nil {
+		profile.Appointments = []domain.Appointment{}
+	}
+	if profile.Notes == nil {
+		profile.Notes = []domain.ClientNote{}
+	}
+	return profile, nil
+}
+
+func (s *ClientService) AddNote(ctx context.Context, clientID uuid.UUID, body string) (*domain.ClientNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyNote
+	}
+	if _, err := s.userRepo.GetByID(ctx, clientID); err != nil {
+		return nil, err
+	}
+
+	note := &domain.ClientNote{ClientID: clientID, Body: body}
+	if actor := domain.ActorFromContext(ctx); actor != nil {
+		note.AuthorID = &actor.ID
+	}
+	if err := s.noteRepo.Create(ctx, note); err != nil {
+		return nil, err
+	}
+	return s.noteRepo.GetByID(ctx, note.ID)
+}
+
+// UpdateNote replaces the body of a note. The note must belong to clientID.
+func (s *ClientService) UpdateNote(ctx context.Context, clientID, noteID uuid.UUID, body string) (*domain.ClientNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyNote
+	}
+	note, err := s.clientNote(ctx, clientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	note.Body = body
+	if err := s.noteRepo.Update(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (s *ClientService) DeleteNote(ctx context.Context, clientID, noteID uuid.UUID) error {
+	if _, err := s.clientNote(ctx, clientID, noteID); err != nil {
+		return err
+	}
+	return s.noteRepo.Delete(ctx, noteID)
+}
+
+func (s *ClientService) clientNote(ctx context.Context, clientID, noteID uuid.UUID) (*domain.ClientNote, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.ClientID != clientID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return note, nil
+}
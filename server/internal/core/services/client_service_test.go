@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+// MockClientNoteRepo keeps notes in memory, in creation order.
+type MockClientNoteRepo struct {
+	Notes []domain.ClientNote
+}
+
+func (m *MockClientNoteRepo) Create(ctx context.Context, note *domain.ClientNote) error {
+	note.ID = uuid.New()
+	m.Notes = append(m.Notes, *note)
+	return nil
+}
+func (m *MockClientNoteRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.ClientNote, error) {
+	for _, n := range m.Notes {
+		if n.ID == id {
+			return &n, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockClientNoteRepo) Update(ctx context.Context, note *domain.ClientNote) error {
+	for i := range m.Notes {
+		if m.Notes[i].ID == note.ID {
+			m.Notes[i] = *note
+		}
+	}
+	return nil
+}
+func (m *MockClientNoteRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	for i := range m.Notes {
+		if m.Notes[i].ID == id {
+			m.Notes = append(m.Notes[:i], m.Notes[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+func (m *MockClientNoteRepo) ListByClient(ctx context.Context, clientID uuid.UUID) ([]domain.ClientNote, error) {
+	var out []domain.ClientNote
+	for _, n := range m.Notes {
+		if n.ClientID == clientID {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func TestGetProfile_VisitsAndLastService(t *testing.T) {
+	client := &domain.User{ID: uuid.New(), Name: "Lucía"}
+	now := time.Now()
+	apptRepo := &MockAppointmentRepo{ClientAppointments: []domain.Appointment{
+		{Status: domain.StatusConfirmed, Service: "Barba", StartTime: now.Add(48 * time.Hour)},
+		{Status: domain.StatusCompleted, Service: "Corte y barba", StartTime: now.Add(-24 * time.Hour)},
+		{Status: domain.StatusNoShow, Service: "Corte", StartTime: now.Add(-240 * time.Hour)},
+		{Status: domain.StatusCompleted, Service: "Corte", StartTime: now.Add(-720 * time.Hour)},
+	}}
+	svc := NewClientService(NewMockUserRepo(client), apptRepo, &MockClientNoteRepo{})
+
+	profile, err := svc.GetProfile(context.Background(), client.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.TotalVisits != 2 || profile.LastService != "Corte y barba" {
+		t.Errorf("expected 2 visits, last 'Corte y barba'; got %d, %q", profile.TotalVisits, profile.LastService)
+	}
+	if len(profile.Appointments) != 4 || profile.Notes == nil {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+}
+
+func TestClientNotes_AuthorAndOwnership(t *testing.T) {
+	client := &domain.User{ID: uuid.New()}
+	other := &domain.User{ID: uuid.New()}
+	admin := domain.Actor{ID: uuid.New(), Role: domain.RoleAdmin}
+	notes := &MockClientNoteRepo{}
+	svc := NewClientService(NewMockUserRepo(client, other), &MockAppointmentRepo{}, notes)
+	ctx := domain.ContextWithActor(context.Background(), admin)
+
+	note, err := svc.AddNote(ctx, client.ID, "  Prefiere tijera  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Body != "Prefiere tijera" || note.AuthorID == nil || *note.AuthorID != admin.ID {
+		t.Errorf("unexpected note: %+v", note)
+	}
+
+	if _, err := svc.AddNote(ctx, client.ID, "   "); !errors.Is(err, ErrEmptyNote) {
+		t.Errorf("expected ErrEmptyNote, got %v", err)
+	}
+
+	// A note can only be edited through the client it belongs to
+	if _, err := svc.UpdateNote(ctx, other.ID, note.ID, "x"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected not found through another client, got %v", err)
+	}
+	if err := svc.DeleteNote(ctx, client.ID, note.ID); err != nil || len(notes.Notes) != 0 {
+		t.Errorf("expected the note deleted, got %v (%d left)", err, len(notes.Notes))
+	}
+}
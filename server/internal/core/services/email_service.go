@@ -60,7 +60,7 @@ const verificationTemplate = `
             <p>Hola <strong>{{.Name}}</strong>,</p>
             <p>Gracias por registrarte. Para completar tu cuenta y reservar tu primer turno, por favor verifica tu dirección de correo electrónico.</p>
             <a href="{{.Link}}" class="btn">Verificar Email</a>
-            <p style="margin-top: 30px; font-size: 14px;">Si no creaste esta cuenta, puedes ignorar este mensaje.</p>
+            <p style="margin-top: 30px; font-size: 14px;">El enlace vence en 48 horas. Si no creaste esta cuenta, puedes ignorar este mensaje.</p>
         </div>
         <div class="footer">
             <p>&copy; 2026 Barbería TON. Todos los derechos reservados.</p>
@@ -101,3 +101,210 @@ func (s *EmailService) SendVerificationEmail(to, name, link string) error {
 
 	return nil
 }
+
+const oneTimeCodeTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Tu código - Barbería TON</title>
+    <style>
+        body { font-family: 'Arial', sans-serif; background-color: #f4f4f4; margin: 0; padding: 0; color: #333; }
+        .container { max-width: 600px; margin: 40px auto; background-color: #ffffff; border-radius: 8px; overflow: hidden; box-shadow: 0 4px 10px rgba(0,0,0,0.1); }
+        .header { background-color: #000; color: #fff; padding: 30px; text-align: center; }
+        .header h1 { margin: 0; font-size: 28px; letter-spacing: 2px; text-transform: uppercase; }
+        .content { padding: 40px 30px; text-align: center; }
+        .content p { font-size: 16px; line-height: 1.6; color: #555; }
+        .code { display: inline-block; font-size: 32px; font-weight: bold; letter-spacing: 8px; background-color: #f4f4f4; padding: 14px 28px; border-radius: 4px; margin-top: 20px; }
+        .footer { background-color: #f9f9f9; padding: 20px; text-align: center; font-size: 12px; color: #999; border-top: 1px solid #eee; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>TON</h1>
+        </div>
+        <div class="content">
+            <p>Hola <strong>{{.Name}}</strong>,</p>
+            <p>Usá este código para confirmar tu turno. Vence en {{.Minutes}} minutos.</p>
+            <div class="code">{{.Code}}</div>
+            <p style="margin-top: 30px; font-size: 14px;">Si no pediste este código, puedes ignorar este mensaje.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2026 Barbería TON. Todos los derechos reservados.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+func (s *EmailService) SendOneTimeCode(to, name, code string) error {
+	t, err := template.New("email").Parse(oneTimeCodeTemplate)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	data := map[string]interface{}{"Name": name, "Code": code, "Minutes": int(codeTTL.Minutes())}
+	if err := t.Execute(&body, data); err != nil {
+		return err
+	}
+
+	subject := "Tu código - Barbería TON"
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body.String())
+
+	if s.isDev {
+		log.Printf("=== [DEV EMAIL] To: %s ===\nSubject: %s\nCode: %s\n==============================\n", to, subject, code)
+		return nil
+	}
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		log.Printf("Failed to send email to %s: %v", to, err)
+		return err
+	}
+
+	return nil
+}
+
+const passwordResetTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Restablecé tu contraseña - Barbería TON</title>
+    <style>
+        body { font-family: 'Arial', sans-serif; background-color: #f4f4f4; margin: 0; padding: 0; color: #333; }
+        .container { max-width: 600px; margin: 40px auto; background-color: #ffffff; border-radius: 8px; overflow: hidden; box-shadow: 0 4px 10px rgba(0,0,0,0.1); }
+        .header { background-color: #000; color: #fff; padding: 30px; text-align: center; }
+        .header h1 { margin: 0; font-size: 28px; letter-spacing: 2px; text-transform: uppercase; }
+        .content { padding: 40px 30px; text-align: center; }
+        .content p { font-size: 16px; line-height: 1.6; color: #555; }
+        .btn { display: inline-block; background-color: #000; color: #fff; padding: 14px 28px; text-decoration: none; border-radius: 4px; font-weight: bold; margin-top: 20px; }
+        .footer { background-color: #f9f9f9; padding: 20px; text-align: center; font-size: 12px; color: #999; border-top: 1px solid #eee; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>TON</h1>
+        </div>
+        <div class="content">
+            <p>Hola <strong>{{.Name}}</strong>,</p>
+            <p>Recibimos un pedido para restablecer tu contraseña. El link vence en {{.Minutes}} minutos y solo puede usarse una vez.</p>
+            <a href="{{.Link}}" class="btn">Restablecer contraseña</a>
+            <p style="margin-top: 30px; font-size: 14px;">Si no lo pediste, puedes ignorar este mensaje: tu contraseña no cambiará.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2026 Barbería TON. Todos los derechos reservados.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+func (s *EmailService) SendPasswordResetEmail(to, name, link string) error {
+	t, err := template.New("email").Parse(passwordResetTemplate)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	data := map[string]interface{}{"Name": name, "Link": link, "Minutes": int(passwordResetTTL.Minutes())}
+	if err := t.Execute(&body, data); err != nil {
+		return err
+	}
+
+	subject := "Restablecé tu contraseña - Barbería TON"
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body.String())
+
+	if s.isDev {
+		log.Printf("=== [DEV EMAIL] To: %s ===\nSubject: %s\nLink: %s\n==============================\n", to, subject, link)
+		return nil
+	}
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		log.Printf("Failed to send email to %s: %v", to, err)
+		return err
+	}
+
+	return nil
+}
+
+const loginLinkTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Ingresá a tu cuenta - Barbería TON</title>
+    <style>
+        body { font-family: 'Arial', sans-serif; background-color: #f4f4f4; margin: 0; padding: 0; color: #333; }
+        .container { max-width: 600px; margin: 40px auto; background-color: #ffffff; border-radius: 8px; overflow: hidden; box-shadow: 0 4px 10px rgba(0,0,0,0.1); }
+        .header { background-color: #000; color: #fff; padding: 30px; text-align: center; }
+        .header h1 { margin: 0; font-size: 28px; letter-spacing: 2px; text-transform: uppercase; }
+        .content { padding: 40px 30px; text-align: center; }
+        .content p { font-size: 16px; line-height: 1.6; color: #555; }
+        .btn { display: inline-block; background-color: #000; color: #fff; padding: 14px 28px; text-decoration: none; border-radius: 4px; font-weight: bold; margin-top: 20px; }
+        .code { display: inline-block; font-size: 32px; font-weight: bold; letter-spacing: 8px; background-color: #f4f4f4; padding: 14px 28px; border-radius: 4px; margin-top: 20px; }
+        .footer { background-color: #f9f9f9; padding: 20px; text-align: center; font-size: 12px; color: #999; border-top: 1px solid #eee; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>TON</h1>
+        </div>
+        <div class="content">
+            <p>Hola <strong>{{.Name}}</strong>,</p>
+            <p>Tocá el botón para ingresar a tu cuenta, sin contraseña. El link vence en {{.Minutes}} minutos y solo puede usarse una vez.</p>
+            <a href="{{.Link}}" class="btn">Ingresar</a>
+            <p>O escribí este código en la página donde lo pediste:</p>
+            <div class="code">{{.Code}}</div>
+            <p style="margin-top: 30px; font-size: 14px;">Si no lo pediste, puedes ignorar este mensaje.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; 2026 Barbería TON. Todos los derechos reservados.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+func (s *EmailService) SendLoginLink(to, name, link, code string) error {
+	t, err := template.New("email").Parse(loginLinkTemplate)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	data := map[string]interface{}{"Name": name, "Link": link, "Code": code, "Minutes": int(codeTTL.Minutes())}
+	if err := t.Execute(&body, data); err != nil {
+		return err
+	}
+
+	subject := "Ingresá a tu cuenta - Barbería TON"
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body.String())
+
+	if s.isDev {
+		log.Printf("=== [DEV EMAIL] To: %s ===\nSubject: %s\nLink: %s\nCode: %s\n==============================\n", to, subject, link, code)
+		return nil
+	}
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		log.Printf("Failed to send email to %s: %v", to, err)
+		return err
+	}
+
+	return nil
+}
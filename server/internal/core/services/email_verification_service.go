@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationTTL = 48 * time.Hour
+	// A new verification email is not sent while the previous one is younger than this
+	verificationThrottle = time.Minute
+)
+
+var ErrInvalidVerificationToken = errors.New("the verification link is invalid or has expired, please request a new one")
+
+// verifyPhonePayload is what a phone verification code is redeemed for.
+type verifyPhonePayload struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// EmailVerificationService proves new users own the email they registered with, through
+// an expiring link, or the phone, through a WhatsApp code. Either lets them log in.
+// Registrations with a guest's email can only be verified by the link.
+type EmailVerificationService struct {
+	userRepo    ports.UserRepository
+	pending     ports.PendingRegistrationRepository
+	codes       ports.OneTimeCodeRepository
+	sender      codeSender
+	frontendURL string
+}
+
+func NewEmailVerificationService(userRepo ports.UserRepository, pending ports.PendingRegistrationRepository, codes ports.OneTimeCodeRepository, email ports.EmailService, msg ports.MessagingService, frontendURL string) *EmailVerificationService {
+	return &EmailVerificationService{userRepo: userRepo, pending: pending, codes: codes, sender: codeSender{email: email, msg: msg}, frontendURL: frontendURL}
+}
+
+// SendLink emails the user a new verification link; the previous one stops working.
+func (s *EmailVerificationService) SendLink(ctx context.Context, user *domain.User) error {
+	raw, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	// Only the token columns: the user may be a stale copy, e.g. from a goroutine
+	expiresAt := time.Now().Add(verificationTTL).UTC()
+	if err := s.userRepo.UpdateVerificationToken(ctx, user.ID, hash, expiresAt); err != nil {
+		return err
+	}
+	user.VerificationHash = hash
+	user.VerificationExpiresAt = &expiresAt
+
+	return s.sender.email.SendVerificationEmail(user.Email, user.Name, s.link(raw))
+}
+
+// ClaimGuest stores the registration apart from the guest and emails the guest a link
+// that applies it. Several registrations may wait for the same guest; the first one
+// verified wins.
+func (s *EmailVerificationService) ClaimGuest(ctx context.Context, guest *domain.User, registration domain.PendingRegistration) error {
+	raw, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	registration.ID = uuid.New()
+	registration.UserID = guest.ID
+	registration.TokenHash = hash
+	registration.ExpiresAt = time.Now().Add(verificationTTL).UTC()
+	if err := s.pending.Create(ctx, &registration); err != nil {
+		return err
+	}
+	return s.sender.email.SendVerificationEmail(guest.Email, registration.Name, s.link(raw))
+}
+
+func (s *EmailVerificationService) link(token string) string {
+	return s.frontendURL + "/verify-email?token=" + token
+}
+
+// ResendLink emails a new link if an unverified account has this email. It returns nil
+// when there is no such account, or one was sent a moment ago, so callers cannot tell.
+func (s *EmailVerificationService) ResendLink(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, domain.NormalizeEmail(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Active() && user.IsGuest {
+		return s.resendClaimLink(ctx, user)
+	}
+	if user, err = s.pendingAccount(ctx, email); err != nil || user == nil {
+		return err
+	}
+	if user.VerificationExpiresAt != nil && user.VerificationExpiresAt.Add(-verificationTTL).After(time.Now().Add(-verificationThrottle)) {
+		return nil
+	}
+	return s.SendLink(ctx, user)
+}
+
+// resendClaimLink sends a new link for the latest registration claiming the guest,
+// with the same throttle as ResendLink.
+func (s *EmailVerificationService) resendClaimLink(ctx context.Context, guest *domain.User) error {
+	registration, err := s.pending.LatestByUser(ctx, guest.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if registration.ExpiresAt.Add(-verificationTTL).After(time.Now().Add(-verificationThrottle)) {
+		return nil
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	if err := s.pending.UpdateToken(ctx, registration.ID, hash, time.Now().Add(verificationTTL).UTC()); err != nil {
+		return err
+	}
+	return s.sender.email.SendVerificationEmail(guest.Email, registration.Name, s.link(raw))
+}
+
+func (s *EmailVerificationService) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+	user, err := s.userRepo.GetByVerificationHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.verifyClaim(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Only the verification columns, and only while the token is still there
+	verified, err := s.userRepo.MarkEmailVerified(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, ErrInvalidVerificationToken
+	}
+	user.MarkEmailVerified()
+	return user, nil
+}
+
+// verifyClaim turns the guest into the account of the registration the token was sent for.
+func (s *EmailVerificationService) verifyClaim(ctx context.Context, token string) (*domain.User, error) {
+	registration, err := s.pending.Consume(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(registration.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	guest, err := s.userRepo.GetByID(ctx, registration.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !guest.IsGuest || !guest.Active() {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	claimed, err := s.userRepo.ClaimGuest(ctx, guest.ID, registration)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrInvalidVerificationToken
+	}
+	guest.Name = registration.Name
+	guest.Phone = registration.Phone
+	guest.Password = registration.Password
+	guest.PhoneVerified = false
+	guest.MarkEmailVerified()
+	// Other registrations for the same guest lost
+	if err := s.pending.DeleteByUser(ctx, guest.ID); err != nil {
+		log.Printf("Failed to delete pending registrations of user %s: %v", guest.ID, err)
+	}
+	return guest, nil
+}
+
+// DeleteExpired removes registrations whose link was never used.
+func (s *EmailVerificationService) DeleteExpired(ctx context.Context) error {
+	_, err := s.pending.DeleteExpired(ctx, time.Now())
+	return err
+}
+
+func (s *EmailVerificationService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Pending registration sweeper", s.DeleteExpired)
+}
+
+// SendPhoneCode sends a WhatsApp code to the phone of the unverified account with this
+// email. Like a passwordless login, unknown emails get a challenge that never works, and
+// the destination is left out so it does not tell them apart. Registrations claiming a
+// guest's bookings can only be verified by email, as the phone is whatever the
+// registration typed in.
+func (s *EmailVerificationService) SendPhoneCode(ctx context.Context, email string) (*domain.LoginChallenge, error) {
+	user, err := s.pendingAccount(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	phone := ""
+	if user != nil && !user.IsGuest {
+		phone = normalizeDestination(domain.ChannelWhatsApp, normalizePhone(user.Phone))
+	}
+	if phone == "" {
+		return &domain.LoginChallenge{
+			ID:        uuid.New(),
+			Channel:   domain.ChannelWhatsApp,
+			ExpiresAt: time.Now().Add(codeTTL).UTC(),
+		}, nil
+	}
+
+	code, raw, err := issueCode(ctx, s.codes, domain.PurposeVerifyPhone, domain.ChannelWhatsApp, phone, verifyPhonePayload{UserID: user.ID})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sender.send(ctx, domain.ChannelWhatsApp, code.Destination, user.Name, raw); err != nil {
+		return nil, err
+	}
+	return &domain.LoginChallenge{
+		ID:        code.ID,
+		Channel:   domain.ChannelWhatsApp,
+		ExpiresAt: code.ExpiresAt,
+	}, nil
+}
+
+func (s *EmailVerificationService) VerifyPhone(ctx context.Context, challengeID uuid.UUID, code string) (*domain.User, error) {
+	var payload verifyPhonePayload
+	issued, err := redeemCode(ctx, s.codes, challengeID, domain.PurposeVerifyPhone, code, &payload)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, payload.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The phone may have changed since the code was sent, e.g. when the owner of the
+	// email claimed the account and cleared it
+	if normalizeDestination(domain.ChannelWhatsApp, normalizePhone(user.Phone)) != issued.Destination {
+		return nil, ErrInvalidCode
+	}
+	verified, err := s.userRepo.MarkPhoneVerified(ctx, user.ID, user.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, ErrInvalidCode
+	}
+	user.PhoneVerified = true
+	return user, nil
+}
+
+// claimEmail verifies the email of the unverified user for its owner, who proved it
+// other than with the account's verification link, e.g. with a code or at an identity
+// provider. See domain.User.ClaimEmail.
+func claimEmail(ctx context.Context, userRepo ports.UserRepository, user *domain.User) error {
+	now := time.Now().UTC()
+	claimed, err := userRepo.ClaimEmail(ctx, user.ID, now)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		user.ClaimEmail(now)
+		return nil
+	}
+	// Verified meanwhile
+	current, err := userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	*user = *current
+	return nil
+}
+
+// pendingAccount returns the active, unverified account with this email, or nil.
+func (s *EmailVerificationService) pendingAccount(ctx context.Context, email string) (*domain.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, domain.NormalizeEmail(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() || user.Verified() || user.Password == "" {
+		return nil, nil
+	}
+	return user, nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+// MockVerificationSender records the verification links sent, on top of MockSender.
+type MockVerificationSender struct {
+	MockSender
+	VerifyLinks []string
+}
+
+func (m *MockVerificationSender) SendVerificationEmail(to, name, link string) error {
+	m.VerifyLinks = append(m.VerifyLinks, link)
+	return nil
+}
+
+type MockPendingRegistrationRepo struct {
+	Registrations []domain.PendingRegistration
+}
+
+func (m *MockPendingRegistrationRepo) Create(ctx context.Context, registration *domain.PendingRegistration) error {
+	registration.CreatedAt = time.Now()
+	m.Registrations = append(m.Registrations, *registration)
+	return nil
+}
+func (m *MockPendingRegistrationRepo) Consume(ctx context.Context, tokenHash string) (*domain.PendingRegistration, error) {
+	for i, r := range m.Registrations {
+		if r.TokenHash == tokenHash {
+			m.Registrations = append(m.Registrations[:i], m.Registrations[i+1:]...)
+			return &r, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockPendingRegistrationRepo) LatestByUser(ctx context.Context, userID uuid.UUID) (*domain.PendingRegistration, error) {
+	for i := len(m.Registrations) - 1; i >= 0; i-- {
+		if m.Registrations[i].UserID == userID {
+			r := m.Registrations[i]
+			return &r, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockPendingRegistrationRepo) UpdateToken(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	for i := range m.Registrations {
+		if m.Registrations[i].ID == id {
+			m.Registrations[i].TokenHash, m.Registrations[i].ExpiresAt = tokenHash, expiresAt
+		}
+	}
+	return nil
+}
+func (m *MockPendingRegistrationRepo) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	kept := m.Registrations[:0]
+	for _, r := range m.Registrations {
+		if r.UserID != userID {
+			kept = append(kept, r)
+		}
+	}
+	m.Registrations = kept
+	return nil
+}
+func (m *MockPendingRegistrationRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newVerificationFixture(users ...*domain.User) (*EmailVerificationService, *MockVerificationSender, *MockUserRepo) {
+	userRepo := NewMockUserRepo(users...)
+	sender := &MockVerificationSender{}
+	return NewEmailVerificationService(userRepo, &MockPendingRegistrationRepo{}, &MockOneTimeCodeRepo{}, sender, sender, "http://front"), sender, userRepo
+}
+
+// linkToken returns the token of the last verification link sent.
+func linkToken(t *testing.T, sender *MockVerificationSender) string {
+	t.Helper()
+	if len(sender.VerifyLinks) == 0 {
+		t.Fatal("expected a verification link to be sent")
+	}
+	link, err := url.Parse(sender.VerifyLinks[len(sender.VerifyLinks)-1])
+	if err != nil || link.Host != "front" || link.Path != "/verify-email" {
+		t.Fatalf("unexpected link %q", sender.VerifyLinks[len(sender.VerifyLinks)-1])
+	}
+	return link.Query().Get("token")
+}
+
+func TestEmailVerification_LinkIsHashedAndExpires(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Password: "hash", Role: domain.RoleClient}
+	svc, sender, userRepo := newVerificationFixture(user)
+
+	if err := svc.SendLink(ctx, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := linkToken(t, sender)
+	stored := userRepo.Users[user.ID]
+	if stored.VerificationHash == "" || stored.VerificationHash == token || stored.VerificationExpiresAt == nil {
+		t.Fatalf("expected only the token hash to be stored, got %+v", stored)
+	}
+
+	// A link asked for a moment ago is not sent again
+	if err := svc.ResendLink(ctx, " ANA@example.com"); err != nil || len(sender.VerifyLinks) != 1 {
+		t.Fatalf("expected the resend to be throttled, got %v and %d links", err, len(sender.VerifyLinks))
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	stored.VerificationExpiresAt = &expired
+	if _, err := svc.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected an expired link to be rejected, got %v", err)
+	}
+
+	if err := svc.ResendLink(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fresh := linkToken(t, sender)
+	if _, err := svc.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected the previous link to stop working, got %v", err)
+	}
+	verified, err := svc.VerifyEmail(ctx, fresh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verified.IsVerified || verified.IsGuest || userRepo.Users[user.ID].VerificationHash != "" {
+		t.Errorf("expected a verified account, got %+v", verified)
+	}
+	if _, err := svc.VerifyEmail(ctx, fresh); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected a used link to be rejected, got %v", err)
+	}
+
+	// Verified accounts and unknown emails get nothing, without an error
+	for _, email := range []string{"ana@example.com", "nobody@example.com"} {
+		if err := svc.ResendLink(ctx, email); err != nil || len(sender.VerifyLinks) != 2 {
+			t.Errorf("%s: expected no link, got %v", email, err)
+		}
+	}
+}
+
+func TestEmailVerification_WhatsAppCode(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Name: "Juan", Email: "juan@example.com", Phone: "03492-640018", Password: "hash", Role: domain.RoleClient}
+	claimed := &domain.User{ID: uuid.New(), Name: "Eva", Email: "eva@example.com", Phone: "1155550000", IsGuest: true}
+	svc, sender, userRepo := newVerificationFixture(user, claimed)
+
+	challenge, err := svc.SendPhoneCode(ctx, "juan@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(sender.WhatsApp)
+	if code == "" || challenge.Destination != "" {
+		t.Fatalf("expected a code by WhatsApp and no destination, got %q and %q", sender.WhatsApp, challenge.Destination)
+	}
+	if _, err := svc.VerifyPhone(ctx, challenge.ID, "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode, got %v", err)
+	}
+	if _, err := svc.VerifyPhone(ctx, challenge.ID, code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := userRepo.Users[user.ID]; !u.PhoneVerified || u.IsVerified || !u.Verified() {
+		t.Errorf("expected only the phone to be verified, got %+v", u)
+	}
+
+	// A code sent to a phone the account no longer has does not verify it
+	userRepo.Users[user.ID].PhoneVerified = false
+	challenge, _ = svc.SendPhoneCode(ctx, "juan@example.com")
+	code = regexp.MustCompile(`\d{6}`).FindString(sender.WhatsApp)
+	userRepo.Users[user.ID].Phone = ""
+	if _, err := svc.VerifyPhone(ctx, challenge.ID, code); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode for a changed phone, got %v", err)
+	}
+	userRepo.Users[user.ID].Phone = "03492-640018"
+	userRepo.Users[user.ID].PhoneVerified = true
+
+	// Unknown emails, verified accounts and guests get a challenge that never works
+	for _, email := range []string{"nobody@example.com", "juan@example.com", "eva@example.com"} {
+		sender.WhatsApp = ""
+		challenge, err := svc.SendPhoneCode(ctx, email)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", email, err)
+		}
+		if sender.WhatsApp != "" || challenge.Destination != "" {
+			t.Errorf("%s: expected no message to be sent", email)
+		}
+	}
+}
+
+func TestEmailVerification_GuestClaimAppliedOnlyOnceVerified(t *testing.T) {
+	ctx := context.Background()
+	guest := &domain.User{ID: uuid.New(), Name: "Eva", Email: "eva@example.com", Phone: "1155550000", Role: domain.RoleClient, IsGuest: true}
+	svc, sender, userRepo := newVerificationFixture(guest)
+
+	// Someone else registers with the guest's email first
+	if err := svc.ClaimGuest(ctx, guest, domain.PendingRegistration{Name: "Mallory", Phone: "1199990000", Password: "evil"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attackerLink := linkToken(t, sender)
+	if err := svc.ClaimGuest(ctx, guest, domain.PendingRegistration{Name: "Eva García", Phone: "1155550000", Password: "hash"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	evaLink := linkToken(t, sender)
+	if u := userRepo.Users[guest.ID]; u.Name != "Eva" || u.Phone != "1155550000" || u.Password != "" || !u.IsGuest {
+		t.Fatalf("expected the guest untouched before verification, got %+v", u)
+	}
+
+	verified, err := svc.VerifyEmail(ctx, evaLink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verified.ID != guest.ID || verified.Name != "Eva García" || verified.Password != "hash" || verified.IsGuest || !verified.IsVerified {
+		t.Errorf("expected the guest to become Eva's account, got %+v", verified)
+	}
+	if _, err := svc.VerifyEmail(ctx, attackerLink); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected the other registration to stop working, got %v", err)
+	}
+	if u := userRepo.Users[guest.ID]; u.Name != "Eva García" {
+		t.Errorf("expected the account to keep Eva's details, got %+v", u)
+	}
+}
+
+func TestEmailVerification_SendLinkKeepsOtherChanges(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Password: "hash", Role: domain.RoleClient}
+	svc, _, userRepo := newVerificationFixture(user)
+
+	// The copy handed to SendLink goes stale while the account changes meanwhile
+	stale := *user
+	userRepo.Users[user.ID].Name = "Ana María"
+	if err := svc.SendLink(ctx, &stale); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored := userRepo.Users[user.ID]; stored.Name != "Ana María" || stored.VerificationHash == "" {
+		t.Errorf("expected only the verification token to change, got %+v", stored)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// ExportService writes appointments and clients as spreadsheets for the accountant.
+// Appointment times are the shop's wall clock stored as UTC, so they are written as
+// stored; sign-up and visit dates are real instants, shown in the shop's timezone.
+// Headers are in Spanish.
+type ExportService struct {
+	apptRepo ports.AppointmentRepository
+	userRepo ports.UserRepository
+	loc      *time.Location
+}
+
+func NewExportService(apptRepo ports.AppointmentRepository, userRepo ports.UserRepository, loc *time.Location) *ExportService {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &ExportService{apptRepo: apptRepo, userRepo: userRepo, loc: loc}
+}
+
+func (s *ExportService) ExportAppointments(ctx context.Context, filter domain.AppointmentExportFilter, out ports.SheetWriter) error {
+	err := out.WriteRow("Fecha", "Hora", "Fin", "Estado", "Servicio", "Cliente", "Email", "Teléfono",
+		"Seña", "Estado seña", "Cobrado", "Propina", "Descuento", "Notas", "ID")
+	if err != nil {
+		return err
+	}
+
+	err = s.apptRepo.EachForExport(ctx, filter, func(row *domain.AppointmentExportRow) error {
+		start := row.StartTime.UTC()
+		return out.WriteRow(
+			start.Format("02/01/2006"),
+			start.Format("15:04"),
+			row.EndTime.UTC().Format("15:04"),
+			string(row.Status),
+			row.Service,
+			row.ClientName,
+			row.ClientEmail,
+			row.ClientPhone,
+			row.DepositAmount,
+			string(row.PaymentStatus),
+			row.Paid,
+			row.Tips,
+			row.Discounts,
+			row.Notes,
+			row.ID.String(),
+		)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func (s *ExportService) ExportClients(ctx context.Context, out ports.SheetWriter) error {
+	err := out.WriteRow("Nombre", "Email", "Teléfono", "Verificado", "Alta", "Última visita",
+		"Visitas", "Ausencias", "Cancelaciones tardías", "Bloqueado", "ID")
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.EachClientForExport(ctx, func(row *domain.ClientExportRow) error {
+		lastVisit := ""
+		if row.LastVisitAt != nil {
+			lastVisit = row.LastVisitAt.In(s.loc).Format("02/01/2006")
+		}
+		return out.WriteRow(
+			row.Name,
+			row.Email,
+			row.Phone,
+			row.IsVerified,
+			row.CreatedAt.In(s.loc).Format("02/01/2006"),
+			lastVisit,
+			row.Visits,
+			row.NoShowCount,
+			row.LateCancelCount,
+			row.BookingBlocked,
+			row.ID.String(),
+		)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/adapters/export"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+// readCSV reads an export as Excel in Spanish would.
+func readCSV(data string) ([][]string, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+	r.Comma = ';'
+	return r.ReadAll()
+}
+
+func TestExportAppointments_WritesStoredWallClock(t *testing.T) {
+	loc, err := time.LoadLocation("America/Argentina/Buenos_Aires")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	// A 10:30 booking is stored as 10:30 UTC and must not be shifted to 07:30
+	start := time.Date(2026, 5, 2, 10, 30, 0, 0, time.UTC)
+	apptRepo := &MockAppointmentRepo{ExportRows: []domain.AppointmentExportRow{{
+		ID:         uuid.New(),
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		Status:     domain.StatusCompleted,
+		ClientName: "José Ñúñez",
+		Paid:       9000,
+	}}}
+	svc := NewExportService(apptRepo, NewMockUserRepo(), loc)
+
+	var buf bytes.Buffer
+	if err := svc.ExportAppointments(context.Background(), domain.AppointmentExportFilter{}, export.NewCSVWriter(&buf)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := readCSV(buf.String())
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected header and one row, got %d rows", len(rows))
+	}
+	row := rows[1]
+	if row[0] != "02/05/2026" || row[1] != "10:30" || row[2] != "11:30" {
+		t.Errorf("expected the booked date and times, got %q %q %q", row[0], row[1], row[2])
+	}
+	if row[3] != "completed" || row[5] != "José Ñúñez" || row[10] != "9000,00" {
+		t.Errorf("unexpected row: %q", row)
+	}
+}
+
+func TestExportClients_FormatsDatesInShopTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Argentina/Buenos_Aires")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	// Sign-up and visit times are real instants: 01:30 UTC on the 2nd is the 1st in Buenos Aires
+	instant := time.Date(2026, 5, 2, 1, 30, 0, 0, time.UTC)
+	svc := NewExportService(&MockAppointmentRepo{}, NewMockUserRepo(&domain.User{
+		ID:          uuid.New(),
+		Name:        "Ana",
+		CreatedAt:   instant,
+		LastVisitAt: &instant,
+	}), loc)
+
+	var buf bytes.Buffer
+	if err := svc.ExportClients(context.Background(), export.NewCSVWriter(&buf)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := readCSV(buf.String())
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected header and one row, got %d rows", len(rows))
+	}
+	if row := rows[1]; row[0] != "Ana" || row[4] != "01/05/2026" || row[5] != "01/05/2026" {
+		t.Errorf("unexpected row: %q", row)
+	}
+}
+
+func TestExportClients_EscapesFormulas(t *testing.T) {
+	svc := NewExportService(&MockAppointmentRepo{}, NewMockUserRepo(
+		&domain.User{ID: uuid.New(), Name: `=HYPERLINK("https://evil.test","Ana")`, Phone: "+5491155550000", CreatedAt: time.Now()},
+	), nil)
+
+	var buf bytes.Buffer
+	if err := svc.ExportClients(context.Background(), export.NewCSVWriter(&buf)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := readCSV(buf.String())
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if row := rows[1]; row[0] != `'=HYPERLINK("https://evil.test","Ana")` || !strings.HasPrefix(row[2], "'+549") {
+		t.Errorf("expected formulas kept as text, got %q", row)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+var (
+	// ErrUnverifiedGuest is returned for guest bookings that did not go through a code or link.
+	ErrUnverifiedGuest     = errors.New("guest bookings must be confirmed with the code we send you")
+	ErrInvalidGuestBooking = errors.New("invalid booking")
+)
+
+// GuestBookingService books for clients without an account. The booking is only
+// made once the guest enters the code sent to their email or WhatsApp.
+type GuestBookingService struct {
+	codes   ports.OneTimeCodeRepository
+	apptSvc ports.AppointmentService
+	sender  codeSender
+}
+
+func NewGuestBookingService(codes ports.OneTimeCodeRepository, apptSvc ports.AppointmentService, email ports.EmailService, msg ports.MessagingService) *GuestBookingService {
+	return &GuestBookingService{codes: codes, apptSvc: apptSvc, sender: codeSender{email: email, msg: msg}}
+}
+
+// Start validates the booking and sends a code to the chosen channel. When channel is
+// empty the email is used if there is one, otherwise WhatsApp.
+func (s *GuestBookingService) Start(ctx context.Context, req domain.BookingRequest, channel domain.ContactChannel) (*domain.GuestBookingChallenge, error) {
+	req.ClientName = strings.TrimSpace(req.ClientName)
+	req.ClientEmail = domain.NormalizeEmail(req.ClientEmail)
+	// One form per number, so it is rate limited and matched to a client however it is typed
+	req.ClientPhone = normalizePhone(req.ClientPhone)
+	if req.ClientName == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidGuestBooking)
+	}
+	if req.ClientEmail != "" {
+		if addr, err := mail.ParseAddress(req.ClientEmail); err != nil || addr.Address != req.ClientEmail {
+			return nil, fmt.Errorf("%w: invalid email", ErrInvalidGuestBooking)
+		}
+	}
+	if !req.StartTime.After(time.Now()) {
+		return nil, ErrSlotUnavailable
+	}
+
+	if channel == "" {
+		channel = domain.ChannelEmail
+		if req.ClientEmail == "" {
+			channel = domain.ChannelWhatsApp
+		}
+	}
+	var destination string
+	switch channel {
+	case domain.ChannelEmail:
+		destination = req.ClientEmail
+	case domain.ChannelWhatsApp:
+		destination = req.ClientPhone
+	default:
+		return nil, fmt.Errorf("%w: verify_via must be email or whatsapp", ErrInvalidGuestBooking)
+	}
+	if destination == "" {
+		return nil, fmt.Errorf("%w: an email or phone is required to send the confirmation code", ErrInvalidGuestBooking)
+	}
+
+	// The hold token is sent again on confirmation rather than stored with the code
+	req.HoldToken = ""
+	req.VerifiedVia = ""
+	code, raw, err := issueCode(ctx, s.codes, domain.PurposeGuestBooking, channel, destination, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sender.send(ctx, channel, code.Destination, req.ClientName, raw); err != nil {
+		return nil, err
+	}
+
+	return &domain.GuestBookingChallenge{
+		ID:          code.ID,
+		Channel:     channel,
+		Destination: maskDestination(channel, code.Destination),
+		ExpiresAt:   code.ExpiresAt,
+	}, nil
+}
+
+// Confirm books the appointment of a challenge once its code is correct.
+func (s *GuestBookingService) Confirm(ctx context.Context, challengeID uuid.UUID, code, holdToken string) (*domain.Appointment, error) {
+	var req domain.BookingRequest
+	issued, err := redeemCode(ctx, s.codes, challengeID, domain.PurposeGuestBooking, code, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	req.VerifiedVia = issued.Channel
+	req.HoldToken = holdToken
+	return s.apptSvc.CreateAppointment(ctx, req)
+}
+
+// DeleteExpiredCodes removes codes that can no longer be redeemed nor count
+// towards the hourly limit.
+func (s *GuestBookingService) DeleteExpiredCodes(ctx context.Context) error {
+	_, err := s.codes.DeleteExpired(ctx, time.Now().Add(-time.Hour).UTC())
+	return err
+}
+
+func (s *GuestBookingService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Guest code sweeper", s.DeleteExpiredCodes)
+}
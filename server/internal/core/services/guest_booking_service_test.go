@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+type MockOneTimeCodeRepo struct {
+	Codes map[uuid.UUID]*domain.OneTimeCode
+}
+
+func (m *MockOneTimeCodeRepo) Create(ctx context.Context, code *domain.OneTimeCode) error {
+	if m.Codes == nil {
+		m.Codes = make(map[uuid.UUID]*domain.OneTimeCode)
+	}
+	code.CreatedAt = time.Now()
+	copy := *code
+	m.Codes[code.ID] = &copy
+	return nil
+}
+func (m *MockOneTimeCodeRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.OneTimeCode, error) {
+	code, ok := m.Codes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *code
+	return &copy, nil
+}
+func (m *MockOneTimeCodeRepo) IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	m.Codes[id].Attempts++
+	return m.Codes[id].Attempts, nil
+}
+func (m *MockOneTimeCodeRepo) Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	code := m.Codes[id]
+	if code.ConsumedAt != nil {
+		return false, nil
+	}
+	code.ConsumedAt = &at
+	return true, nil
+}
+func (m *MockOneTimeCodeRepo) CountSince(ctx context.Context, purpose domain.CodePurpose, destination string, since time.Time) (int64, error) {
+	var n int64
+	for _, code := range m.Codes {
+		if code.Purpose == purpose && code.Destination == destination && !code.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+func (m *MockOneTimeCodeRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// MockSender records the last email code, reset link, login link and WhatsApp message sent.
+type MockSender struct {
+	EmailCode string
+	ResetLink string
+	LoginLink string
+	WhatsApp  string
+}
+
+func (m *MockSender) SendVerificationEmail(to, name, link string) error { return nil }
+func (m *MockSender) SendPasswordResetEmail(to, name, link string) error {
+	m.ResetLink = link
+	return nil
+}
+func (m *MockSender) SendOneTimeCode(to, name, code string) error {
+	m.EmailCode = code
+	return nil
+}
+func (m *MockSender) SendLoginLink(to, name, link, code string) error {
+	m.LoginLink = link
+	m.EmailCode = code
+	return nil
+}
+func (m *MockSender) SendWhatsApp(ctx context.Context, phone string, message string) error {
+	m.WhatsApp = message
+	return nil
+}
+
+type MockCalendar struct{}
+
+func (m *MockCalendar) CreateEvent(ctx context.Context, appointment *domain.Appointment) (string, error) {
+	return "", nil
+}
+func (m *MockCalendar) DeleteEvent(ctx context.Context, eventID string) error { return nil }
+
+func newGuestBookingFixture(users *MockUserRepo) (*GuestBookingService, *MockSender) {
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "00:00", EndTime: "23:59", SlotDuration: 60}, nil
+	}}
+	sender := &MockSender{}
+	appts := NewAppointmentService(&MockAppointmentRepo{}, avail, nil, users, nil, nil, nil, &MockSettingsRepo{}, nil, nil, &MockCalendar{}, sender)
+	return NewGuestBookingService(&MockOneTimeCodeRepo{}, appts, sender, sender), sender
+}
+
+func TestGuestBooking_ConfirmWithCode(t *testing.T) {
+	users := NewMockUserRepo()
+	svc, sender := newGuestBookingFixture(users)
+	ctx := context.Background()
+
+	challenge, err := svc.Start(ctx, domain.BookingRequest{
+		ClientName:  "Ana",
+		ClientEmail: "Ana@Example.com",
+		ClientPhone: "1155550000",
+		StartTime:   time.Now().Add(48 * time.Hour),
+	}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if challenge.Channel != domain.ChannelEmail || challenge.Destination != "a***@example.com" {
+		t.Errorf("unexpected challenge %+v", challenge)
+	}
+	if len(users.Users) != 0 {
+		t.Fatal("expected no user before the code is confirmed")
+	}
+
+	if _, err := svc.Confirm(ctx, challenge.ID, "not-the-code", ""); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode, got %v", err)
+	}
+	appt, err := svc.Confirm(ctx, challenge.ID, sender.EmailCode, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	guest := users.Users[appt.ClientID]
+	if guest == nil || !guest.IsGuest || guest.Email != "ana@example.com" {
+		t.Errorf("expected appointment for a new guest user, got %+v", guest)
+	}
+
+	if _, err := svc.Confirm(ctx, challenge.ID, sender.EmailCode, ""); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected the code to be single use, got %v", err)
+	}
+}
+
+func TestGuestBooking_ExistingAccountIsNotModified(t *testing.T) {
+	account := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Phone: "1155550000", Role: domain.RoleClient, IsVerified: true}
+	users := NewMockUserRepo(account)
+	svc, sender := newGuestBookingFixture(users)
+	ctx := context.Background()
+
+	challenge, err := svc.Start(ctx, domain.BookingRequest{
+		ClientName:  "Someone Else",
+		ClientEmail: "someone@example.com",
+		ClientPhone: "1155550000",
+		StartTime:   time.Now().Add(48 * time.Hour),
+	}, domain.ChannelWhatsApp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(sender.WhatsApp)
+
+	appt, err := svc.Confirm(ctx, challenge.ID, code, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if appt.ClientID != account.ID {
+		t.Errorf("expected booking under the account owning the verified phone")
+	}
+	if got := users.Users[account.ID]; got.Name != "Ana" || got.Email != "ana@example.com" {
+		t.Errorf("account was modified: %+v", got)
+	}
+}
+
+func TestGuestBooking_ClaimsAnUnverifiedAccount(t *testing.T) {
+	// Someone registered Ana's email and verified their own phone
+	squatter := &domain.User{ID: uuid.New(), Name: "Eve", Email: "ana@example.com", Phone: "1166660000", PhoneVerified: true, Password: "hash", Role: domain.RoleClient}
+	users := NewMockUserRepo(squatter)
+	svc, sender := newGuestBookingFixture(users)
+	ctx := context.Background()
+
+	challenge, err := svc.Start(ctx, domain.BookingRequest{
+		ClientName:  "Ana",
+		ClientEmail: "ana@example.com",
+		ClientPhone: "1155550000",
+		StartTime:   time.Now().Add(48 * time.Hour),
+	}, domain.ChannelEmail)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	appt, err := svc.Confirm(ctx, challenge.ID, sender.EmailCode, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := users.Users[squatter.ID]
+	if appt.ClientID != squatter.ID || !got.IsVerified || got.Password != "" || got.Phone != "" || got.PhoneVerified || got.SessionsRevokedAt == nil {
+		t.Errorf("expected the account claimed by the email's owner, got %+v", got)
+	}
+}
+
+func TestGuestBooking_CodeAttemptsAndRateLimit(t *testing.T) {
+	svc, sender := newGuestBookingFixture(NewMockUserRepo())
+	ctx := context.Background()
+	req := domain.BookingRequest{ClientName: "Ana", ClientEmail: "ana@example.com", StartTime: time.Now().Add(48 * time.Hour)}
+
+	challenge, err := svc.Start(ctx, req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < codeMaxAttempts; i++ {
+		_, _ = svc.Confirm(ctx, challenge.ID, "000000x", "")
+	}
+	if _, err := svc.Confirm(ctx, challenge.ID, sender.EmailCode, ""); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected the code to be locked after too many attempts, got %v", err)
+	}
+
+	for i := 1; i < codesPerHour; i++ {
+		if _, err := svc.Start(ctx, req, ""); err != nil {
+			t.Fatalf("unexpected error on code %d: %v", i+1, err)
+		}
+	}
+	if _, err := svc.Start(ctx, req, ""); !errors.Is(err, ErrTooManyCodes) {
+		t.Errorf("expected ErrTooManyCodes, got %v", err)
+	}
+}
+
+func TestCreateAppointment_RequiresVerifiedGuest(t *testing.T) {
+	account := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient}
+	appts := NewAppointmentService(&MockAppointmentRepo{}, nil, nil, NewMockUserRepo(account), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	_, err := appts.CreateAppointment(context.Background(), domain.BookingRequest{
+		ClientName:  "Impostor",
+		ClientEmail: "ana@example.com",
+		StartTime:   time.Now().Add(48 * time.Hour),
+	})
+	if !errors.Is(err, ErrUnverifiedGuest) {
+		t.Fatalf("expected ErrUnverifiedGuest, got %v", err)
+	}
+}
+
+func TestGuestBooking_PhoneFormatsAreOneNumber(t *testing.T) {
+	account := &domain.User{ID: uuid.New(), Name: "Ana", Phone: "01155550000", Role: domain.RoleClient, IsVerified: true}
+	svc, sender := newGuestBookingFixture(NewMockUserRepo(account))
+	ctx := context.Background()
+	formats := []string{"+54 9 11 5555-0000", "11 5555 0000", "54 11 5555 0000"}
+
+	for i := 0; i < codesPerHour; i++ {
+		req := domain.BookingRequest{ClientName: "Ana", ClientPhone: formats[i%len(formats)], StartTime: time.Now().Add(48 * time.Hour)}
+		challenge, err := svc.Start(ctx, req, domain.ChannelWhatsApp)
+		if err != nil {
+			t.Fatalf("unexpected error on code %d: %v", i+1, err)
+		}
+		if i > 0 {
+			continue
+		}
+		appt, err := svc.Confirm(ctx, challenge.ID, regexp.MustCompile(`\d{6}`).FindString(sender.WhatsApp), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if appt.ClientID != account.ID {
+			t.Errorf("expected booking under the account with the same number")
+		}
+	}
+
+	req := domain.BookingRequest{ClientName: "Ana", ClientPhone: "1155550000", StartTime: time.Now().Add(48 * time.Hour)}
+	if _, err := svc.Start(ctx, req, domain.ChannelWhatsApp); !errors.Is(err, ErrTooManyCodes) {
+		t.Errorf("expected every format to count towards the same limit, got %v", err)
+	}
+}
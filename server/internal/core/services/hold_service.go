@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSlotHeld        = errors.New("slot is being booked by another client, please pick another one")
+	ErrSlotUnavailable = errors.New("slot is not available")
+)
+
+type HoldService struct {
+	repo         ports.SlotHoldRepository
+	availSvc     ports.AvailabilityService
+	settingsRepo ports.SettingsRepository
+}
+
+func NewHoldService(repo ports.SlotHoldRepository, availSvc ports.AvailabilityService, settingsRepo ports.SettingsRepository) *HoldService {
+	return &HoldService{repo: repo, availSvc: availSvc, settingsRepo: settingsRepo}
+}
+
+func (s *HoldService) HoldSlot(ctx context.Context, start time.Time) (*domain.SlotHold, string, error) {
+	start = start.UTC()
+	if !start.After(time.Now()) {
+		return nil, "", ErrSlotUnavailable
+	}
+
+	slots, err := s.availSvc.GetAvailableSlots(ctx, start, "")
+	if err != nil {
+		return nil, "", err
+	}
+	available := false
+	for _, slot := range slots {
+		if slot.Equal(start) {
+			available = true
+			break
+		}
+	}
+	if !available {
+		return nil, "", ErrSlotUnavailable
+	}
+
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	ttl := settings.SlotHoldMinutes
+	if ttl <= 0 {
+		ttl = domain.DefaultSettings().SlotHoldMinutes
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	hold := &domain.SlotHold{
+		TokenHash: hash,
+		StartTime: start,
+		EndTime:   start.Add(1 * time.Hour),
+		ExpiresAt: time.Now().UTC().Add(time.Duration(ttl) * time.Minute),
+	}
+	if err := s.repo.Create(ctx, hold); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, "", ErrSlotHeld
+		}
+		return nil, "", err
+	}
+
+	return hold, raw, nil
+}
+
+func (s *HoldService) ReleaseHold(ctx context.Context, token string) error {
+	hold, err := s.repo.GetByTokenHash(ctx, hashToken(token))
+	if err == gorm.ErrRecordNotFound {
+		return nil // Already expired or used
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, hold.ID)
+}
+
+func (s *HoldService) ExpireHolds(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, time.Now().UTC())
+}
+
+// RunSweeper calls ExpireHolds every interval until ctx is cancelled.
+// Running it on several instances is safe: each expired row is deleted once.
+func (s *HoldService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Slot hold sweeper", func(ctx context.Context) error {
+		_, err := s.ExpireHolds(ctx)
+		return err
+	})
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidImport is returned when the file itself cannot be imported (as opposed to single rows).
+var ErrInvalidImport = errors.New("invalid import file")
+
+const maxImportRows = 5000
+
+// importColumns maps accepted header names (lowercase, without accents) to our fields.
+var importColumns = map[string]string{
+	"name": "name", "nombre": "name", "nombre y apellido": "name", "cliente": "name",
+	"email": "email", "e-mail": "email", "mail": "email", "correo": "email",
+	"phone": "phone", "telefono": "phone", "tel": "phone", "celular": "phone", "whatsapp": "phone",
+	"notes": "notes", "notas": "notes", "nota": "notes", "observaciones": "notes",
+}
+
+// ImportService creates clients in bulk from a CSV file.
+type ImportService struct {
+	userRepo ports.UserRepository
+	noteRepo ports.ClientNoteRepository
+}
+
+func NewImportService(userRepo ports.UserRepository, noteRepo ports.ClientNoteRepository) *ImportService {
+	return &ImportService{userRepo: userRepo, noteRepo: noteRepo}
+}
+
+// ImportClients reads a CSV with a header row (name, email, phone, notes; comma or
+// semicolon separated) and creates a verified client per valid, new row. Rows are
+// imported one by one: a failure midway keeps the clients already created.
+func (s *ImportService) ImportClients(ctx context.Context, r io.Reader, dryRun bool) (*domain.ImportReport, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff")) // Excel's UTF-8 BOM
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read the header row: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if field, ok := importColumns[foldHeader(name)]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: missing a name column", ErrInvalidImport)
+	}
+	_, hasEmail := columns["email"]
+	_, hasPhone := columns["phone"]
+	if !hasEmail && !hasPhone {
+		return nil, fmt.Errorf("%w: missing an email or phone column", ErrInvalidImport)
+	}
+
+	report := &domain.ImportReport{DryRun: dryRun, Rows: []domain.ImportRow{}}
+	seenEmails := make(map[string]int) // Value: line of the first occurrence
+	seenPhones := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(report.Rows) == maxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, maxImportRows)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := domain.ImportRow{
+			Line:  line,
+			Name:  strings.Join(strings.Fields(field("name")), " "),
+			Email: strings.ToLower(field("email")),
+		}
+		if raw := field("phone"); raw != "" {
+			row.Phone = normalizePhone(raw)
+		}
+
+		if reason := validateImportRow(row); reason != "" {
+			row.Status, row.Reason = domain.ImportInvalid, reason
+		} else if first, ok := seenEmails[row.Email]; ok && row.Email != "" {
+			row.Status, row.Reason = domain.ImportDuplicate, fmt.Sprintf("same email as line %d", first)
+		} else if first, ok := seenPhones[row.Phone]; ok && row.Phone != "" {
+			row.Status, row.Reason = domain.ImportDuplicate, fmt.Sprintf("same phone as line %d", first)
+		} else if err := s.importRow(ctx, &row, field("notes"), dryRun); err != nil {
+			return nil, err
+		}
+
+		if row.Status != domain.ImportInvalid {
+			if row.Email != "" {
+				seenEmails[row.Email] = row.Line
+			}
+			if row.Phone != "" {
+				seenPhones[row.Phone] = row.Line
+			}
+		}
+
+		switch row.Status {
+		case domain.ImportCreated, domain.ImportWouldAdd:
+			report.Created++
+		case domain.ImportDuplicate:
+			report.Duplicates++
+		case domain.ImportInvalid:
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	return report, nil
+}
+
+// importRow matches the row against existing clients and creates it if it is new.
+func (s *ImportService) importRow(ctx context.Context, row *domain.ImportRow, notes string, dryRun bool) error {
+	existing, err := s.findExisting(ctx, row)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		row.Status, row.Reason, row.UserID = domain.ImportDuplicate, "client already exists", &existing.ID
+		return nil
+	}
+
+	if dryRun {
+		row.Status = domain.ImportWouldAdd
+		return nil
+	}
+
+	// Imported clients never verified an email, but the shop vouches for them
+	user := &domain.User{
+		Name:       row.Name,
+		Email:      row.Email,
+		Phone:      row.Phone,
+		Role:       domain.RoleClient,
+		IsVerified: true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			row.Status, row.Reason = domain.ImportDuplicate, "client already exists"
+			return nil
+		}
+		return err
+	}
+	row.Status, row.UserID = domain.ImportCreated, &user.ID
+
+	// Notes from the old agenda become a private note on the client
+	if notes != "" {
+		if err := s.noteRepo.Create(ctx, &domain.ClientNote{ClientID: user.ID, Body: notes}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ImportService) findExisting(ctx context.Context, row *domain.ImportRow) (*domain.User, error) {
+	if row.Email != "" {
+		user, err := s.userRepo.GetByEmail(ctx, row.Email)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if row.Phone != "" {
+		user, err := s.userRepo.GetByPhone(ctx, phoneVariants(row.Phone)...)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func validateImportRow(row domain.ImportRow) string {
+	if row.Name == "" {
+		return "name is required"
+	}
+	if row.Email == "" && row.Phone == "" {
+		return "email or phone is required"
+	}
+	if row.Email != "" {
+		if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+			return "invalid email"
+		}
+	}
+	if row.Phone != "" && (len(row.Phone) < 10 || len(row.Phone) > 13) {
+		return "invalid phone"
+	}
+	return ""
+}
+
+// detectDelimiter picks ';' when the header uses it, as Excel does in Spanish locales.
+func detectDelimiter(data []byte) rune {
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+var headerAccents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u")
+
+func foldHeader(name string) string {
+	return headerAccents.Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func TestImportClients_ValidatesNormalizesAndDeduplicates(t *testing.T) {
+	existing := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Phone: "5493492111111"}
+	repo := NewMockUserRepo(existing)
+	notes := &MockClientNoteRepo{}
+	svc := NewImportService(repo, notes)
+
+	csv := "\ufeffNombre;Correo;Teléfono;Notas\n" +
+		"  José   Ñúñez ;JOSE@Example.com;03492-640018;Corte clásico\n" + // 2: created
+		"Ana Bis;ana@example.com;;\n" + // 3: existing email
+		"Ana Tel;;(03492) 111111;\n" + // 4: existing phone, stored with 549
+		"Pepe;jose@example.com;;\n" + // 5: repeats line 2
+		";sin-nombre@example.com;;\n" + // 6: invalid
+		"Malo;no-es-un-email;;\n" + // 7: invalid
+		";;;\n" + // blank, skipped
+		"Solo Teléfono;;3492 555 555;\n" // 9: created without email
+
+	report, err := svc.ImportClients(context.Background(), strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []domain.ImportRowStatus{
+		domain.ImportCreated, domain.ImportDuplicate, domain.ImportDuplicate, domain.ImportDuplicate,
+		domain.ImportInvalid, domain.ImportInvalid, domain.ImportCreated,
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), report.Rows)
+	}
+	for i, status := range want {
+		if report.Rows[i].Status != status {
+			t.Errorf("line %d: expected %s, got %s (%s)", report.Rows[i].Line, status, report.Rows[i].Status, report.Rows[i].Reason)
+		}
+	}
+	if report.Created != 2 || report.Duplicates != 3 || report.Invalid != 2 {
+		t.Errorf("unexpected counters: %+v", report)
+	}
+
+	first := report.Rows[0]
+	if first.Line != 2 || first.Name != "José Ñúñez" || first.Email != "jose@example.com" || first.Phone != "5493492640018" {
+		t.Errorf("row not normalized: %+v", first)
+	}
+	if *report.Rows[2].UserID != existing.ID {
+		t.Errorf("expected the phone duplicate to point at the existing client")
+	}
+
+	created := repo.Users[*first.UserID]
+	if !created.IsVerified || created.Role != domain.RoleClient {
+		t.Errorf("unexpected created user: %+v", created)
+	}
+	if len(notes.Notes) != 1 || notes.Notes[0].ClientID != created.ID || notes.Notes[0].Body != "Corte clásico" {
+		t.Errorf("expected the row notes as a private note, got %+v", notes.Notes)
+	}
+}
+
+func TestImportClients_DryRunCreatesNothing(t *testing.T) {
+	repo := NewMockUserRepo()
+	svc := NewImportService(repo, &MockClientNoteRepo{})
+
+	report, err := svc.ImportClients(context.Background(), strings.NewReader("name,email\nLuis,luis@example.com\n"), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.DryRun || report.Created != 1 || report.Rows[0].Status != domain.ImportWouldAdd {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(repo.Users) != 0 {
+		t.Errorf("dry run created %d users", len(repo.Users))
+	}
+
+	if _, err := svc.ImportClients(context.Background(), strings.NewReader("email\nx@example.com\n"), true); !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("expected ErrInvalidImport without a name column, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+const (
+	// Time the user has to log in at the provider and come back
+	oidcLoginTTL = 10 * time.Minute
+	// Clock skew tolerated on the ID token's iat and exp
+	oidcClockSkew = time.Minute
+)
+
+var (
+	ErrInvalidOIDCLogin    = errors.New("the login could not be completed, please try again")
+	ErrOIDCEmailUnverified = errors.New("the email of this account is not verified by the provider")
+)
+
+// OIDCLoginService logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE. Provider users are linked to an account by
+// their verified email the first time, and by their subject afterwards.
+type OIDCLoginService struct {
+	provider   ports.OIDCProvider
+	logins     ports.OIDCLoginRepository
+	identities ports.UserIdentityRepository
+	userRepo   ports.UserRepository
+	starter    ports.LoginStarter
+}
+
+func NewOIDCLoginService(provider ports.OIDCProvider, logins ports.OIDCLoginRepository, identities ports.UserIdentityRepository, userRepo ports.UserRepository, starter ports.LoginStarter) *OIDCLoginService {
+	return &OIDCLoginService{provider: provider, logins: logins, identities: identities, userRepo: userRepo, starter: starter}
+}
+
+func (s *OIDCLoginService) Begin(ctx context.Context) (string, string, error) {
+	state, stateHash, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, _, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC()
+	login := &domain.OIDCLogin{
+		Provider:     s.provider.Name(),
+		StateHash:    stateHash,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    now.Add(oidcLoginTTL),
+		CreatedAt:    now,
+	}
+	if err := s.logins.Create(ctx, login); err != nil {
+		return "", "", err
+	}
+	return s.provider.AuthorizationURL(state, nonce, pkceChallenge(verifier)), state, nil
+}
+
+func (s *OIDCLoginService) Complete(ctx context.Context, state, code, userAgent, ip string) (*domain.OIDCCallback, error) {
+	login, err := s.logins.Consume(ctx, hashToken(state))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidOIDCLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if login.Provider != s.provider.Name() || time.Now().After(login.ExpiresAt) {
+		return nil, ErrInvalidOIDCLogin
+	}
+
+	idToken, err := s.provider.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOIDCLogin, err)
+	}
+	claims, err := s.verifyIDToken(ctx, idToken, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOIDCLogin, err)
+	}
+
+	user, created, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, ErrAccountInactive
+	}
+
+	result, err := s.starter.StartLogin(ctx, user, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OIDCCallback{LoginResult: *result, Created: created}, nil
+}
+
+// resolveUser finds the user behind the provider account, linking or creating one
+// the first time. Linking by email is only trusted when the provider verified it.
+func (s *OIDCLoginService) resolveUser(ctx context.Context, claims *domain.OIDCClaims) (*domain.User, bool, error) {
+	identity, err := s.identities.GetBySubject(ctx, s.provider.Name(), claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(ctx, identity.UserID)
+		return user, false, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, false, ErrOIDCEmailUnverified
+	}
+
+	created := false
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !user.IsVerified {
+			// Whoever registered this email never proved owning it, so what they set
+			// must not keep working now that the real owner has
+			if err := claimEmail(ctx, s.userRepo, user); err != nil {
+				return nil, false, err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user = &domain.User{
+			Name:       claims.Name,
+			Email:      claims.Email,
+			Role:       domain.RoleClient,
+			IsVerified: true,
+		}
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return nil, false, err
+		}
+		created = true
+	default:
+		return nil, false, err
+	}
+
+	identity = &domain.UserIdentity{
+		UserID:    user.ID,
+		Provider:  s.provider.Name(),
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.identities.Create(ctx, identity); err != nil {
+		return nil, false, err
+	}
+	return user, created, nil
+}
+
+// verifyIDToken checks the signature against the provider keys, then the issuer,
+// audience, expiry and the nonce of this login.
+func (s *OIDCLoginService) verifyIDToken(ctx context.Context, idToken, nonce string) (*domain.OIDCClaims, error) {
+	jwks, err := s.provider.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*signingKey, len(jwks))
+	for _, jwk := range jwks {
+		// Keys we cannot use are skipped, the token may be signed by another one
+		if key, err := parseJWK(jwk); err == nil {
+			keys[key.kid] = key
+		}
+	}
+
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	},
+		jwt.WithIssuer(s.provider.Issuer()),
+		jwt.WithAudience(s.provider.ClientID()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if got, _ := mapClaims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	// A token issued to several audiences must name us as the authorized party
+	if aud, _ := mapClaims.GetAudience(); len(aud) > 1 {
+		if azp, _ := mapClaims["azp"].(string); azp != s.provider.ClientID() {
+			return nil, errors.New("unexpected authorized party")
+		}
+	}
+
+	claims := &domain.OIDCClaims{}
+	claims.Subject, _ = mapClaims.GetSubject()
+	if claims.Subject == "" {
+		return nil, errors.New("missing subject")
+	}
+	email, _ := mapClaims["email"].(string)
+	claims.Email = domain.NormalizeEmail(email)
+	claims.Name, _ = mapClaims["name"].(string)
+	// Some providers send the flag as a string
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+	return claims, nil
+}
+
+// DeleteExpired removes logins that were started but never came back.
+func (s *OIDCLoginService) DeleteExpired(ctx context.Context) error {
+	_, err := s.logins.DeleteExpired(ctx, time.Now().UTC())
+	return err
+}
+
+func (s *OIDCLoginService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "OIDC login sweeper", s.DeleteExpired)
+}
+
+// pkceChallenge is the S256 code challenge of a PKCE verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
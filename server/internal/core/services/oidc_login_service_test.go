@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+type MockOIDCLoginRepo struct {
+	Logins map[string]*domain.OIDCLogin
+}
+
+func (m *MockOIDCLoginRepo) Create(ctx context.Context, login *domain.OIDCLogin) error {
+	login.ID = uuid.New()
+	copy := *login
+	m.Logins[login.StateHash] = &copy
+	return nil
+}
+func (m *MockOIDCLoginRepo) Consume(ctx context.Context, stateHash string) (*domain.OIDCLogin, error) {
+	login, ok := m.Logins[stateHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(m.Logins, stateHash)
+	return login, nil
+}
+func (m *MockOIDCLoginRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	for hash, login := range m.Logins {
+		if login.ExpiresAt.Before(before) {
+			delete(m.Logins, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type MockUserIdentityRepo struct {
+	Identities []domain.UserIdentity
+}
+
+func (m *MockUserIdentityRepo) GetBySubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	for _, identity := range m.Identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copy := identity
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockUserIdentityRepo) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	m.Identities = append(m.Identities, *identity)
+	return nil
+}
+
+// fakeOIDCProvider plays the provider: it signs ID tokens with its own key and only
+// hands them out for the code verifier matching the challenge it was sent.
+type fakeOIDCProvider struct {
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	// Claims of the next ID token; nonce, iss, aud, iat and exp are filled in unless set
+	claims jwt.MapClaims
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeOIDCProvider{key: key}
+}
+
+func (p *fakeOIDCProvider) Name() string     { return "google" }
+func (p *fakeOIDCProvider) Issuer() string   { return "https://issuer.test" }
+func (p *fakeOIDCProvider) ClientID() string { return "client-id" }
+
+func (p *fakeOIDCProvider) AuthorizationURL(state, nonce, codeChallenge string) string {
+	p.challenge, p.nonce = codeChallenge, nonce
+	return "https://issuer.test/auth?" + url.Values{"state": {state}}.Encode()
+}
+
+func (p *fakeOIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	if code != "good-code" || pkceChallenge(codeVerifier) != p.challenge {
+		return "", errors.New("invalid_grant")
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{"nonce": p.nonce, "iss": p.Issuer(), "aud": p.ClientID(), "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "provider-key"
+	return token.SignedString(p.key)
+}
+
+func (p *fakeOIDCProvider) Keys(ctx context.Context) ([]domain.JSONWebKey, error) {
+	return []domain.JSONWebKey{{
+		Kty: "RSA", Kid: "provider-key", Use: "sig", Alg: "RS256",
+		N: base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}, nil
+}
+
+func newTestOIDCLoginService(t *testing.T, users ...*domain.User) (*OIDCLoginService, *fakeOIDCProvider, *MockUserRepo, *MockUserIdentityRepo) {
+	provider := newFakeOIDCProvider(t)
+	userRepo := NewMockUserRepo(users...)
+	identities := &MockUserIdentityRepo{}
+	svc := NewOIDCLoginService(provider, &MockOIDCLoginRepo{Logins: make(map[string]*domain.OIDCLogin)}, identities, userRepo, newTestTwoFactorService(userRepo))
+	return svc, provider, userRepo, identities
+}
+
+// beginLogin starts a login and returns the state the provider would send back.
+func beginLogin(t *testing.T, svc *OIDCLoginService) string {
+	t.Helper()
+	authURL, state, err := svc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, _ := url.Parse(authURL)
+	if got := parsed.Query().Get("state"); got != state {
+		t.Fatalf("expected the returned state in the URL, got %q and %q", got, state)
+	}
+	return state
+}
+
+func TestOIDCLoginService_CreatesClientThenReusesIdentity(t *testing.T) {
+	ctx := context.Background()
+	svc, provider, userRepo, identities := newTestOIDCLoginService(t)
+	provider.claims = jwt.MapClaims{"sub": "google-1", "email": "Ana@Example.com", "email_verified": true, "name": "Ana"}
+
+	result, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Created || result.Tokens.AccessToken == "" || result.Tokens.RefreshToken == "" {
+		t.Fatalf("expected a new client with a session, got %+v", result)
+	}
+	user, _ := userRepo.GetByID(ctx, result.User.ID)
+	if user.Email != "ana@example.com" || user.Role != domain.RoleClient || !user.IsVerified || user.Password != "" {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	// The email changes at Google, the subject keeps pointing at the same user
+	provider.claims = jwt.MapClaims{"sub": "google-1", "email": "ana@new.example.com", "email_verified": true}
+	again, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.Created || again.User.ID != user.ID || len(identities.Identities) != 1 {
+		t.Errorf("expected the linked user to log in again, got %+v", again)
+	}
+}
+
+func TestOIDCLoginService_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	verified := &domain.User{ID: uuid.New(), Email: "ana@example.com", Password: "hash", Role: domain.RoleAdmin, IsVerified: true}
+	squatted := &domain.User{ID: uuid.New(), Email: "bob@example.com", Password: "attacker", Phone: "1166660000", PhoneVerified: true, Role: domain.RoleClient, VerificationHash: "t"}
+	svc, provider, userRepo, identities := newTestOIDCLoginService(t, verified, squatted)
+
+	provider.claims = jwt.MapClaims{"sub": "google-1", "email": "ana@example.com", "email_verified": "true"}
+	result, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Created || result.User.ID != verified.ID || identities.Identities[0].UserID != verified.ID {
+		t.Errorf("expected to log in as the existing user, got %+v", result.User)
+	}
+	if u, _ := userRepo.GetByID(ctx, verified.ID); u.Password != "hash" {
+		t.Error("expected a verified account to keep its password")
+	}
+
+	// Nobody proved owning the unverified account's email, so its password, phone and
+	// sessions stop working
+	provider.claims = jwt.MapClaims{"sub": "google-2", "email": "bob@example.com", "email_verified": true}
+	if _, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _ := userRepo.GetByID(ctx, squatted.ID)
+	if !u.IsVerified || u.Password != "" || u.VerificationHash != "" || u.Phone != "" || u.PhoneVerified || u.SessionsRevokedAt == nil {
+		t.Errorf("unexpected user after linking an unverified account: %+v", u)
+	}
+
+	// Unverified provider emails are never used to link
+	provider.claims = jwt.MapClaims{"sub": "google-3", "email": "ana@example.com", "email_verified": false}
+	if _, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1"); !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Errorf("expected ErrOIDCEmailUnverified, got %v", err)
+	}
+}
+
+func TestOIDCLoginService_RejectsInvalidCallbacks(t *testing.T) {
+	ctx := context.Background()
+	deactivatedAt := time.Now()
+	inactive := &domain.User{ID: uuid.New(), Email: "old@example.com", IsVerified: true, DeactivatedAt: &deactivatedAt}
+	svc, provider, _, _ := newTestOIDCLoginService(t, inactive)
+	good := jwt.MapClaims{"sub": "google-1", "email": "ana@example.com", "email_verified": true}
+
+	cases := []struct {
+		name   string
+		code   string
+		claims jwt.MapClaims
+	}{
+		{"wrong code", "bad-code", good},
+		{"wrong nonce", "good-code", jwt.MapClaims{"sub": "google-1", "nonce": "replayed"}},
+		{"wrong audience", "good-code", jwt.MapClaims{"sub": "google-1", "aud": "someone-else"}},
+		{"wrong issuer", "good-code", jwt.MapClaims{"sub": "google-1", "iss": "https://evil.test"}},
+		{"expired", "good-code", jwt.MapClaims{"sub": "google-1", "exp": time.Now().Add(-time.Hour).Unix()}},
+		{"other party", "good-code", jwt.MapClaims{"sub": "google-1", "aud": []string{"client-id", "other"}, "azp": "other"}},
+	}
+	for _, tc := range cases {
+		provider.claims = tc.claims
+		if _, err := svc.Complete(ctx, beginLogin(t, svc), tc.code, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCLogin) {
+			t.Errorf("%s: expected ErrInvalidOIDCLogin, got %v", tc.name, err)
+		}
+	}
+
+	// A state can only be used once
+	provider.claims = good
+	state := beginLogin(t, svc)
+	if _, err := svc.Complete(ctx, state, "good-code", "test", "127.0.0.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Complete(ctx, state, "good-code", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("expected a replayed state to be rejected, got %v", err)
+	}
+
+	provider.claims = jwt.MapClaims{"sub": "google-9", "email": "old@example.com", "email_verified": true}
+	if _, err := svc.Complete(ctx, beginLogin(t, svc), "good-code", "test", "127.0.0.1"); !errors.Is(err, ErrAccountInactive) {
+		t.Errorf("expected ErrAccountInactive, got %v", err)
+	}
+}
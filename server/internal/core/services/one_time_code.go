@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+const (
+	codeDigits      = 6
+	codeTTL         = 10 * time.Minute
+	codeMaxAttempts = 5
+	// Codes sent to the same destination for the same purpose within an hour
+	codesPerHour = 5
+)
+
+var (
+	ErrInvalidCode  = errors.New("the code is incorrect or was already used")
+	ErrCodeExpired  = errors.New("the code has expired, please request a new one")
+	ErrTooManyCodes = errors.New("too many codes requested, please try again later")
+)
+
+// codeSender delivers one-time codes by email or WhatsApp.
+type codeSender struct {
+	email ports.EmailService
+	msg   ports.MessagingService
+}
+
+func (s codeSender) send(ctx context.Context, channel domain.ContactChannel, destination, name, code string) error {
+	switch channel {
+	case domain.ChannelEmail:
+		return s.email.SendOneTimeCode(destination, name, code)
+	case domain.ChannelWhatsApp:
+		msg := fmt.Sprintf("Tu código de Barbería TON es %s. Vence en %d minutos. No lo compartas con nadie.", code, int(codeTTL.Minutes()))
+		return s.msg.SendWhatsApp(ctx, destination, msg)
+	default:
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+}
+
+// issueCode stores a new code for destination with payload attached and returns
+// it with the raw code to send. Destinations are rate limited per purpose.
+func issueCode(ctx context.Context, repo ports.OneTimeCodeRepository, purpose domain.CodePurpose, channel domain.ContactChannel, destination string, payload interface{}) (*domain.OneTimeCode, string, error) {
+	destination = normalizeDestination(channel, destination)
+	sent, err := repo.CountSince(ctx, purpose, destination, time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, "", err
+	}
+	if sent >= codesPerHour {
+		return nil, "", ErrTooManyCodes
+	}
+
+	raw, err := newNumericCode(codeDigits)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	code := &domain.OneTimeCode{
+		ID:          uuid.New(),
+		Purpose:     purpose,
+		Channel:     channel,
+		Destination: destination,
+		Payload:     string(data),
+		ExpiresAt:   time.Now().Add(codeTTL).UTC(),
+	}
+	code.CodeHash = hashCode(code.ID, raw)
+	if err := repo.Create(ctx, code); err != nil {
+		return nil, "", err
+	}
+	return code, raw, nil
+}
+
+// redeemCode checks raw against the stored code and consumes it, decoding its payload
+// into payload. Every guess counts towards codeMaxAttempts.
+func redeemCode(ctx context.Context, repo ports.OneTimeCodeRepository, id uuid.UUID, purpose domain.CodePurpose, raw string, payload interface{}) (*domain.OneTimeCode, error) {
+	code, err := repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code.Purpose != purpose || code.ConsumedAt != nil {
+		return nil, ErrInvalidCode
+	}
+	if time.Now().After(code.ExpiresAt) {
+		return nil, ErrCodeExpired
+	}
+
+	// Counted before comparing, so concurrent guesses cannot all get in under the limit
+	attempts, err := repo.IncrementAttempts(ctx, code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > codeMaxAttempts {
+		return nil, ErrInvalidCode
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(code.ID, strings.TrimSpace(raw))), []byte(code.CodeHash)) != 1 {
+		return nil, ErrInvalidCode
+	}
+
+	consumed, err := repo.Consume(ctx, code.ID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidCode
+	}
+	if payload != nil {
+		if err := json.Unmarshal([]byte(code.Payload), payload); err != nil {
+			return nil, err
+		}
+	}
+	return code, nil
+}
+
+// hashCode salts the code with its ID, so equal codes never share a hash.
+func hashCode(id uuid.UUID, raw string) string {
+	return hashToken(id.String() + ":" + raw)
+}
+
+func newNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+func normalizeDestination(channel domain.ContactChannel, destination string) string {
+	if channel == domain.ChannelEmail {
+		return domain.NormalizeEmail(destination)
+	}
+	return strings.TrimSpace(destination)
+}
+
+// maskDestination hides most of an email or phone so a challenge does not leak it.
+func maskDestination(channel domain.ContactChannel, destination string) string {
+	if channel == domain.ChannelEmail {
+		at := strings.LastIndex(destination, "@")
+		if at < 1 {
+			return "***"
+		}
+		return destination[:1] + "***" + destination[at:]
+	}
+	if len(destination) <= 4 {
+		return "***"
+	}
+	return "***" + destination[len(destination)-4:]
+}
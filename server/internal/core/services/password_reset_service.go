@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	// A new reset email is not sent while the previous one is younger than this
+	passwordResetThrottle = time.Minute
+	minPasswordLength     = 6
+)
+
+var (
+	ErrInvalidResetToken = errors.New("the reset link is invalid or has expired, please request a new one")
+	ErrPasswordTooShort  = errors.New("password must have at least 6 characters")
+)
+
+// PasswordResetService lets users set a new password through a link sent to their email.
+type PasswordResetService struct {
+	userRepo    ports.UserRepository
+	email       ports.EmailService
+	frontendURL string
+}
+
+func NewPasswordResetService(userRepo ports.UserRepository, email ports.EmailService, frontendURL string) *PasswordResetService {
+	return &PasswordResetService{userRepo: userRepo, email: email, frontendURL: frontendURL}
+}
+
+// RequestReset emails a reset link if an active user has this email. It returns nil
+// when there is no such user so callers cannot tell the difference.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return nil
+	}
+	now := time.Now()
+	if user.PasswordResetExpiresAt != nil && user.PasswordResetExpiresAt.Add(-passwordResetTTL).After(now.Add(-passwordResetThrottle)) {
+		return nil
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := now.Add(passwordResetTTL).UTC()
+	user.PasswordResetHash = hash
+	user.PasswordResetExpiresAt = &expiresAt
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	link := s.frontendURL + "/reset-password?token=" + raw
+	return s.email.SendPasswordResetEmail(user.Email, user.Name, link)
+}
+
+// ResetPassword sets the new password and signs the user out everywhere. The link
+// proves the user owns the email, so it also verifies it.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.GetByPasswordResetHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if user.PasswordResetExpiresAt == nil || now.After(*user.PasswordResetExpiresAt) || !user.Active() {
+		return ErrInvalidResetToken
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	// The token is used up by the same update that sets the password, so of two
+	// concurrent resets with it only one wins
+	reset, err := s.userRepo.ResetPassword(ctx, user.PasswordResetHash, string(hashed), now)
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return ErrInvalidResetToken
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordReset_UnknownEmailIsSilent(t *testing.T) {
+	sender := &MockSender{}
+	svc := NewPasswordResetService(NewMockUserRepo(), sender, "http://shop")
+
+	if err := svc.RequestReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("expected no error for an unknown email, got %v", err)
+	}
+	if sender.ResetLink != "" {
+		t.Error("expected no email to be sent")
+	}
+}
+
+func TestPasswordReset_SingleUseAndRevokesSessions(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Password: "old", IsVerified: true}
+	users := NewMockUserRepo(user)
+	sender := &MockSender{}
+	svc := NewPasswordResetService(users, sender, "http://shop")
+	ctx := context.Background()
+
+	if err := svc.RequestReset(ctx, "ANA@example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := strings.TrimPrefix(sender.ResetLink, "http://shop/reset-password?token=")
+	if token == "" || token == sender.ResetLink {
+		t.Fatalf("unexpected reset link %q", sender.ResetLink)
+	}
+	if users.Users[user.ID].PasswordResetHash == token {
+		t.Error("expected only the token hash to be stored")
+	}
+
+	if err := svc.ResetPassword(ctx, token, "123"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("expected ErrPasswordTooShort, got %v", err)
+	}
+	if err := svc.ResetPassword(ctx, token, "new-password"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated := users.Users[user.ID]
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password")) != nil {
+		t.Error("expected the new password to be stored")
+	}
+	if updated.SessionsRevokedAt == nil {
+		t.Error("expected existing sessions to be revoked")
+	}
+
+	if err := svc.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected the token to be single use, got %v", err)
+	}
+}
+
+func TestPasswordReset_ExpiredToken(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", PasswordResetHash: hashToken("tok"), PasswordResetExpiresAt: &expired}
+	svc := NewPasswordResetService(NewMockUserRepo(user), &MockSender{}, "http://shop")
+
+	if err := svc.ResetPassword(context.Background(), "tok", "new-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected ErrInvalidResetToken, got %v", err)
+	}
+}
+
+// racingResetRepo lets another reset use the token right after it is looked up.
+type racingResetRepo struct {
+	*MockUserRepo
+}
+
+func (r racingResetRepo) GetByPasswordResetHash(ctx context.Context, hash string) (*domain.User, error) {
+	user, err := r.MockUserRepo.GetByPasswordResetHash(ctx, hash)
+	if err == nil {
+		r.MockUserRepo.ResetPassword(ctx, hash, "theirs", time.Now())
+	}
+	return user, err
+}
+
+func TestPasswordReset_ConcurrentUseOfTheToken(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", PasswordResetHash: hashToken("tok"), PasswordResetExpiresAt: &expires}
+	users := NewMockUserRepo(user)
+	svc := NewPasswordResetService(racingResetRepo{users}, &MockSender{}, "http://shop")
+
+	if err := svc.ResetPassword(context.Background(), "tok", "new-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected ErrInvalidResetToken, got %v", err)
+	}
+	if users.Users[user.ID].Password != "theirs" {
+		t.Error("expected only the first reset to set the password")
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidChannel = errors.New("channel must be email or whatsapp")
+
+// loginCodePayload is what a login code is redeemed for.
+type loginCodePayload struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// PasswordlessLoginService logs users in with a one-time code sent to the email or
+// phone on file, for clients who would rather not keep a password. Codes share the
+// expiry, attempt and hourly limits of guest booking codes.
+type PasswordlessLoginService struct {
+	codes       ports.OneTimeCodeRepository
+	userRepo    ports.UserRepository
+	logins      ports.LoginStarter
+	sender      codeSender
+	frontendURL string
+}
+
+func NewPasswordlessLoginService(codes ports.OneTimeCodeRepository, userRepo ports.UserRepository, logins ports.LoginStarter, email ports.EmailService, msg ports.MessagingService, frontendURL string) *PasswordlessLoginService {
+	return &PasswordlessLoginService{codes: codes, userRepo: userRepo, logins: logins, sender: codeSender{email: email, msg: msg}, frontendURL: frontendURL}
+}
+
+func (s *PasswordlessLoginService) RequestCode(ctx context.Context, channel domain.ContactChannel, destination string) (*domain.LoginChallenge, error) {
+	if !channel.Valid() {
+		return nil, ErrInvalidChannel
+	}
+	if channel == domain.ChannelWhatsApp {
+		destination = normalizePhone(destination)
+	}
+	destination = normalizeDestination(channel, destination)
+	if destination == "" {
+		return nil, fmt.Errorf("%w: an email or phone is required", ErrInvalidChannel)
+	}
+
+	user, err := s.accountFor(ctx, channel, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// Destinations without an account get a code too, which is never sent nor
+	// redeemable, so the answer and the hourly limit are the same as for an account
+	// and the form cannot be used to find clients
+	var payload loginCodePayload
+	if user != nil {
+		payload.UserID = user.ID
+	}
+	code, raw, err := issueCode(ctx, s.codes, domain.PurposeLogin, channel, destination, payload)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return s.challenge(code), nil
+	}
+	if channel == domain.ChannelEmail {
+		link := s.frontendURL + "/login/code?" + url.Values{"id": {code.ID.String()}, "code": {raw}}.Encode()
+		err = s.sender.email.SendLoginLink(code.Destination, user.Name, link, raw)
+	} else {
+		err = s.sender.send(ctx, channel, code.Destination, user.Name, raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.challenge(code), nil
+}
+
+func (s *PasswordlessLoginService) challenge(code *domain.OneTimeCode) *domain.LoginChallenge {
+	return &domain.LoginChallenge{
+		ID:          code.ID,
+		Channel:     code.Channel,
+		Destination: maskDestination(code.Channel, code.Destination),
+		ExpiresAt:   code.ExpiresAt,
+	}
+}
+
+func (s *PasswordlessLoginService) VerifyCode(ctx context.Context, challengeID uuid.UUID, code, userAgent, ip string) (*domain.LoginResult, error) {
+	var payload loginCodePayload
+	issued, err := redeemCode(ctx, s.codes, challengeID, domain.PurposeLogin, code, &payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.UserID == uuid.Nil {
+		return nil, ErrInvalidCode // Issued for a destination without an account
+	}
+	user, err := s.userRepo.GetByID(ctx, payload.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, ErrAccountInactive
+	}
+
+	// A code delivered by email proves the user owns it, which whoever registered the
+	// account may not
+	if issued.Channel == domain.ChannelEmail && !user.IsVerified {
+		if err := claimEmail(ctx, s.userRepo, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.logins.StartLogin(ctx, user, userAgent, ip)
+}
+
+// accountFor returns the user who may log in with destination, or nil. Guests have
+// no account yet, and a phone alone does not log into an account that was never
+// verified, as a password login would not either.
+func (s *PasswordlessLoginService) accountFor(ctx context.Context, channel domain.ContactChannel, destination string) (*domain.User, error) {
+	var user *domain.User
+	var err error
+	if channel == domain.ChannelEmail {
+		user, err = s.userRepo.GetByEmail(ctx, destination)
+	} else {
+		user, err = s.userRepo.GetByPhone(ctx, phoneVariants(destination)...)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active() || user.IsGuest || (channel == domain.ChannelWhatsApp && !user.Verified()) {
+		return nil, nil
+	}
+	return user, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func newPasswordlessFixture(users ...*domain.User) (*PasswordlessLoginService, *MockSender, *MockUserRepo) {
+	userRepo := NewMockUserRepo(users...)
+	sender := &MockSender{}
+	return NewPasswordlessLoginService(&MockOneTimeCodeRepo{}, userRepo, newTestTwoFactorService(userRepo), sender, sender, "http://front"), sender, userRepo
+}
+
+func TestPasswordlessLogin_MagicLinkVerifiesEmail(t *testing.T) {
+	ctx := context.Background()
+	// Whoever registered the email set a password and verified their own phone
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Password: "hash", Phone: "1166660000", PhoneVerified: true, Role: domain.RoleClient, VerificationHash: "pending"}
+	svc, sender, userRepo := newPasswordlessFixture(user)
+
+	challenge, err := svc.RequestCode(ctx, domain.ChannelEmail, " Ana@Example.com ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	link, err := url.Parse(sender.LoginLink)
+	if err != nil || link.Query().Get("id") != challenge.ID.String() || link.Query().Get("code") != sender.EmailCode {
+		t.Fatalf("expected a link carrying the challenge and code, got %q", sender.LoginLink)
+	}
+	if challenge.Destination != "a***@example.com" {
+		t.Errorf("expected a masked destination, got %q", challenge.Destination)
+	}
+
+	result, err := svc.VerifyCode(ctx, challenge.ID, sender.EmailCode, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.User.ID != user.ID || result.Tokens.AccessToken == "" || result.Tokens.RefreshToken == "" {
+		t.Errorf("expected a session for the user, got %+v", result.Tokens)
+	}
+	if u, _ := userRepo.GetByID(ctx, user.ID); !u.IsVerified || u.VerificationHash != "" {
+		t.Error("expected the email to be verified by the link")
+	}
+	if u, _ := userRepo.GetByID(ctx, user.ID); u.Password != "" || u.Phone != "" || u.PhoneVerified || u.SessionsRevokedAt == nil {
+		t.Errorf("expected the registrant's password, phone and sessions to stop working, got %+v", u)
+	}
+
+	if _, err := svc.VerifyCode(ctx, challenge.ID, sender.EmailCode, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected a used code to be rejected, got %v", err)
+	}
+}
+
+func TestPasswordlessLogin_WhatsAppUsesNormalizedPhone(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Name: "Juan", Phone: "03492640018", Role: domain.RoleClient, IsVerified: true}
+	guest := &domain.User{ID: uuid.New(), Name: "Guest", Phone: "1155550000", IsGuest: true}
+	svc, sender, _ := newPasswordlessFixture(user, guest)
+
+	challenge, err := svc.RequestCode(ctx, domain.ChannelWhatsApp, "03492-640018")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(sender.WhatsApp)
+	if code == "" || challenge.Destination != "***0018" {
+		t.Fatalf("expected a code by WhatsApp to the phone on file, got %q to %q", sender.WhatsApp, challenge.Destination)
+	}
+	if result, err := svc.VerifyCode(ctx, challenge.ID, code, "test", "127.0.0.1"); err != nil || result.User.ID != user.ID {
+		t.Fatalf("expected to log in as the user, got %v", err)
+	}
+
+	// Unknown numbers and guests get a challenge that never works, and no message
+	for _, phone := range []string{"1199999999", "1155550000"} {
+		sender.WhatsApp = ""
+		challenge, err := svc.RequestCode(ctx, domain.ChannelWhatsApp, phone)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sender.WhatsApp != "" {
+			t.Errorf("%s: expected no message to be sent", phone)
+		}
+		if _, err := svc.VerifyCode(ctx, challenge.ID, "000000", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("%s: expected ErrInvalidCode, got %v", phone, err)
+		}
+	}
+}
+
+func TestPasswordlessLogin_Limits(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", IsVerified: true}
+	svc, sender, _ := newPasswordlessFixture(user)
+
+	challenge, err := svc.RequestCode(ctx, domain.ChannelEmail, user.Email)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < codeMaxAttempts; i++ {
+		if _, err := svc.VerifyCode(ctx, challenge.ID, "wrong", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("expected ErrInvalidCode, got %v", err)
+		}
+	}
+	if _, err := svc.VerifyCode(ctx, challenge.ID, sender.EmailCode, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected the code to be locked after too many attempts, got %v", err)
+	}
+
+	for i := 1; i < codesPerHour; i++ {
+		if _, err := svc.RequestCode(ctx, domain.ChannelEmail, user.Email); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := svc.RequestCode(ctx, domain.ChannelEmail, user.Email); !errors.Is(err, ErrTooManyCodes) {
+		t.Errorf("expected ErrTooManyCodes, got %v", err)
+	}
+	if _, err := svc.RequestCode(ctx, "sms", user.Email); !errors.Is(err, ErrInvalidChannel) {
+		t.Errorf("expected ErrInvalidChannel, got %v", err)
+	}
+}
+
+func TestPasswordlessLogin_UnknownDestination(t *testing.T) {
+	ctx := context.Background()
+	svc, sender, _ := newPasswordlessFixture(&domain.User{ID: uuid.New(), Email: "ana@example.com", IsVerified: true})
+
+	for i := 0; i < codesPerHour; i++ {
+		challenge, err := svc.RequestCode(ctx, domain.ChannelEmail, "nobody@example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := svc.VerifyCode(ctx, challenge.ID, "000000", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("expected ErrInvalidCode, got %v", err)
+		}
+	}
+	if sender.EmailCode != "" {
+		t.Error("expected no code sent to a destination without an account")
+	}
+	if _, err := svc.RequestCode(ctx, domain.ChannelEmail, "nobody@example.com"); !errors.Is(err, ErrTooManyCodes) {
+		t.Errorf("expected the same hourly limit as for an account, got %v", err)
+	}
+}
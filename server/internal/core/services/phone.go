@@ -0,0 +1,35 @@
+package services
+
+// normalizePhone sanitizes and formats a phone number for WhatsApp (Argentina specific).
+// Input: 03492-640018 -> Output: 5493492640018
+func normalizePhone(raw string) string {
+	sanitized := ""
+	for _, c := range raw {
+		if c >= '0' && c <= '9' {
+			sanitized += string(c)
+		}
+	}
+
+	// Heuristic for Argentina, so one number has one form whichever way it is typed:
+	// the trunk 0 and local 10-digit numbers get 549, and 54 without the mobile 9 gets it
+	switch {
+	case len(sanitized) > 1 && sanitized[0] == '0':
+		sanitized = "549" + sanitized[1:]
+	case len(sanitized) == 10:
+		sanitized = "549" + sanitized
+	case len(sanitized) == 12 && sanitized[:2] == "54" && sanitized[2] != '9':
+		sanitized = "549" + sanitized[2:]
+	}
+	return sanitized
+}
+
+// phoneVariants returns the digit-only forms an Argentine number may have been stored
+// in (international, with trunk 0, or local), for matching against existing clients.
+func phoneVariants(normalized string) []string {
+	variants := []string{normalized}
+	if len(normalized) > 3 && normalized[:3] == "549" {
+		local := normalized[3:]
+		variants = append(variants, "0"+local, local, "54"+local)
+	}
+	return variants
+}
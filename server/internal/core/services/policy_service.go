@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+var ErrBookingBlocked = errors.New("online booking is disabled for this client after repeated late cancellations or no-shows, please contact the shop")
+
+// PolicyService applies the cancellation policy: it flags late cancellations,
+// counts strikes per client and blocks online booking past the configured limit.
+type PolicyService struct {
+	userRepo     ports.UserRepository
+	settingsRepo ports.SettingsRepository
+}
+
+func NewPolicyService(userRepo ports.UserRepository, settingsRepo ports.SettingsRepository) *PolicyService {
+	return &PolicyService{userRepo: userRepo, settingsRepo: settingsRepo}
+}
+
+func (s *PolicyService) IsLateCancellation(ctx context.Context, appt *domain.Appointment, now time.Time) (bool, error) {
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+	window := time.Duration(settings.LateCancelHours) * time.Hour
+	return appt.StartTime.Sub(now) < window, nil
+}
+
+func (s *PolicyService) RecordLateCancellation(ctx context.Context, clientID uuid.UUID) error {
+	return s.recordStrike(ctx, clientID, 1, 0)
+}
+
+func (s *PolicyService) RecordNoShow(ctx context.Context, clientID uuid.UUID) error {
+	return s.recordStrike(ctx, clientID, 0, 1)
+}
+
+func (s *PolicyService) recordStrike(ctx context.Context, clientID uuid.UUID, lateCancels, noShows int) error {
+	if err := s.userRepo.IncrementPolicyCounters(ctx, clientID, lateCancels, noShows); err != nil {
+		return err
+	}
+
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if settings.BlockAfterStrikes <= 0 {
+		return nil
+	}
+	return s.userRepo.BlockBooking(ctx, clientID, settings.BlockAfterStrikes)
+}
+
+// CheckCanBook returns ErrBookingBlocked for blocked clients booking online.
+// Staff managing the agenda can still book them in from the admin panel.
+func (s *PolicyService) CheckCanBook(ctx context.Context, user *domain.User) error {
+	if user.BookingBlocked && !domain.ActorFromContext(ctx).Can(domain.PermManageAppointments) {
+		return ErrBookingBlocked
+	}
+	return nil
+}
+
+// BookingRestrictions tells whether a new online booking by user must be approved by the
+// admin or secured with a deposit, because the client is over the reliability threshold.
+func (s *PolicyService) BookingRestrictions(ctx context.Context, user *domain.User) (requiresApproval, depositRequired bool, err error) {
+	if domain.ActorFromContext(ctx).Can(domain.PermManageAppointments) {
+		return false, false, nil
+	}
+
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	if settings.RestrictAfterStrikes <= 0 || user.Strikes() < settings.RestrictAfterStrikes {
+		return false, false, nil
+	}
+
+	if settings.RestrictionMode == domain.RestrictDeposit {
+		return false, true, nil
+	}
+	return true, false, nil
+}
+
+func (s *PolicyService) ClearBlock(ctx context.Context, clientID uuid.UUID) error {
+	return s.userRepo.ClearBookingBlock(ctx, clientID)
+}
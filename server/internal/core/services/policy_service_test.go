@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+// MockUserRepo keeps users in memory, keyed by ID.
+type MockUserRepo struct {
+	Users map[uuid.UUID]*domain.User
+}
+
+func NewMockUserRepo(users ...*domain.User) *MockUserRepo {
+	m := &MockUserRepo{Users: make(map[uuid.UUID]*domain.User)}
+	for _, u := range users {
+		m.Users[u.ID] = u
+	}
+	return m
+}
+
+func (m *MockUserRepo) Create(ctx context.Context, user *domain.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	m.Users[user.ID] = user
+	return nil
+}
+func (m *MockUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *u
+	return &copy, nil
+}
+func (m *MockUserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, u := range m.Users {
+		if u.Email != "" && u.Email == domain.NormalizeEmail(email) {
+			copy := *u
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockUserRepo) GetByPhone(ctx context.Context, phones ...string) (*domain.User, error) {
+	for _, u := range m.Users {
+		for _, phone := range phones {
+			if u.Phone == phone {
+				copy := *u
+				return &copy, nil
+			}
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockUserRepo) GetByVerificationHash(ctx context.Context, hash string) (*domain.User, error) {
+	for _, u := range m.Users {
+		if hash != "" && u.VerificationHash == hash {
+			copy := *u
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockUserRepo) GetByPasswordResetHash(ctx context.Context, hash string) (*domain.User, error) {
+	for _, u := range m.Users {
+		if hash != "" && u.PasswordResetHash == hash {
+			copy := *u
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockUserRepo) ResetPassword(ctx context.Context, hash, password string, at time.Time) (bool, error) {
+	for _, u := range m.Users {
+		if hash == "" || u.PasswordResetHash != hash || u.PasswordResetExpiresAt == nil || !u.PasswordResetExpiresAt.After(at) || !u.Active() {
+			continue
+		}
+		u.Password = password
+		u.PasswordResetHash = ""
+		u.PasswordResetExpiresAt = nil
+		u.SessionsRevokedAt = &at
+		u.MarkEmailVerified()
+		return true, nil
+	}
+	return false, nil
+}
+func (m *MockUserRepo) SearchClients(ctx context.Context, search domain.ClientSearch) (*domain.ClientPage, error) {
+	return &domain.ClientPage{}, nil
+}
+func (m *MockUserRepo) ListStaff(ctx context.Context) ([]domain.User, error) {
+	var staff []domain.User
+	for _, u := range m.Users {
+		if u.Role.IsStaff() {
+			staff = append(staff, *u)
+		}
+	}
+	return staff, nil
+}
+func (m *MockUserRepo) Update(ctx context.Context, user *domain.User) error {
+	copy := *user
+	m.Users[user.ID] = &copy
+	return nil
+}
+func (m *MockUserRepo) UpdateVerificationToken(ctx context.Context, id uuid.UUID, hash string, expiresAt time.Time) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	u.VerificationHash = hash
+	u.VerificationExpiresAt = &expiresAt
+	return nil
+}
+func (m *MockUserRepo) MarkEmailVerified(ctx context.Context, hash string, at time.Time) (bool, error) {
+	for _, u := range m.Users {
+		if hash != "" && u.VerificationHash == hash && u.VerificationExpiresAt != nil && u.VerificationExpiresAt.After(at) {
+			u.MarkEmailVerified()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *MockUserRepo) MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok || u.Phone != phone {
+		return false, nil
+	}
+	u.PhoneVerified = true
+	return true, nil
+}
+func (m *MockUserRepo) ClaimGuest(ctx context.Context, id uuid.UUID, registration *domain.PendingRegistration) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok || !u.IsGuest || !u.Active() {
+		return false, nil
+	}
+	u.Name = registration.Name
+	u.Phone = registration.Phone
+	u.Password = registration.Password
+	u.PhoneVerified = false
+	u.MarkEmailVerified()
+	return true, nil
+}
+func (m *MockUserRepo) ClaimEmail(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok || u.IsVerified || !u.Active() {
+		return false, nil
+	}
+	u.ClaimEmail(at)
+	return true, nil
+}
+func (m *MockUserRepo) IncrementPolicyCounters(ctx context.Context, id uuid.UUID, lateCancels, noShows int) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	u.LateCancelCount += lateCancels
+	u.NoShowCount += noShows
+	u.PolicyStrikes += lateCancels + noShows
+	return nil
+}
+func (m *MockUserRepo) BlockBooking(ctx context.Context, id uuid.UUID, minStrikes int) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if u.PolicyStrikes >= minStrikes {
+		u.BookingBlocked = true
+	}
+	return nil
+}
+func (m *MockUserRepo) ClearBookingBlock(ctx context.Context, id uuid.UUID) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	u.BookingBlocked = false
+	u.PolicyStrikes = 0
+	return nil
+}
+func (m *MockUserRepo) RecordVisit(ctx context.Context, id uuid.UUID, at time.Time) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if u.LastVisitAt == nil || u.LastVisitAt.Before(at) {
+		u.LastVisitAt = &at
+	}
+	return nil
+}
+func (m *MockUserRepo) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if step <= u.TOTPLastStep {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	return true, nil
+}
+func (m *MockUserRepo) StartTOTPEnrollment(ctx context.Context, id uuid.UUID, secret string) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if u.TwoFactorEnabledAt != nil {
+		return false, nil
+	}
+	u.TOTPPendingSecret = secret
+	return true, nil
+}
+func (m *MockUserRepo) EnableTOTP(ctx context.Context, id uuid.UUID, pendingSecret string, step int64, at time.Time) (bool, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if u.TOTPPendingSecret != pendingSecret || u.TwoFactorEnabledAt != nil {
+		return false, nil
+	}
+	u.TOTPSecret, u.TOTPPendingSecret, u.TOTPLastStep, u.TwoFactorEnabledAt = pendingSecret, "", step, &at
+	return true, nil
+}
+func (m *MockUserRepo) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	u, ok := m.Users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	u.TOTPSecret, u.TOTPPendingSecret, u.TwoFactorEnabledAt = "", "", nil
+	return nil
+}
+func (m *MockUserRepo) EachClientForExport(ctx context.Context, fn func(row *domain.ClientExportRow) error) error {
+	for _, u := range m.Users {
+		row := &domain.ClientExportRow{ID: u.ID, Name: u.Name, Email: u.Email, Phone: u.Phone, LastVisitAt: u.LastVisitAt, CreatedAt: u.CreatedAt}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (m *MockUserRepo) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID, check func(survivor, duplicate *domain.User) error) error {
+	survivor, ok := m.Users[survivorID]
+	duplicate, ok2 := m.Users[duplicateID]
+	if !ok || !ok2 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := check(survivor, duplicate); err != nil {
+		return err
+	}
+	survivor.NoShowCount += duplicate.NoShowCount
+	survivor.LateCancelCount += duplicate.LateCancelCount
+	if survivor.Email == "" {
+		survivor.Email = duplicate.Email
+	}
+	now := time.Now()
+	duplicate.Email = ""
+	duplicate.MergedIntoID = &survivorID
+	duplicate.DeactivatedAt = &now
+	return nil
+}
+
+func TestPolicyService_IsLateCancellation(t *testing.T) {
+	settings := &MockSettingsRepo{Settings: &domain.Settings{LateCancelHours: 24}}
+	svc := NewPolicyService(NewMockUserRepo(), settings)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	late, _ := svc.IsLateCancellation(context.Background(), &domain.Appointment{StartTime: now.Add(23 * time.Hour)}, now)
+	if !late {
+		t.Error("expected cancellation 23h before the appointment to be late")
+	}
+	late, _ = svc.IsLateCancellation(context.Background(), &domain.Appointment{StartTime: now.Add(25 * time.Hour)}, now)
+	if late {
+		t.Error("expected cancellation 25h before the appointment to be on time")
+	}
+}
+
+func TestPolicyService_BlocksAfterStrikes(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Role: domain.RoleClient}
+	users := NewMockUserRepo(client)
+	settings := &MockSettingsRepo{Settings: &domain.Settings{BlockAfterStrikes: 2}}
+	svc := NewPolicyService(users, settings)
+
+	if err := svc.RecordLateCancellation(ctx, client.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, _ := users.GetByID(ctx, client.ID); u.BookingBlocked {
+		t.Fatal("client should not be blocked after one strike")
+	}
+
+	if err := svc.RecordNoShow(ctx, client.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _ := users.GetByID(ctx, client.ID)
+	if !u.BookingBlocked {
+		t.Fatal("client should be blocked after two strikes")
+	}
+	if u.LateCancelCount != 1 || u.NoShowCount != 1 {
+		t.Errorf("expected 1 late cancel and 1 no-show, got %d and %d", u.LateCancelCount, u.NoShowCount)
+	}
+
+	// Online bookings are refused, staff can still book
+	if err := svc.CheckCanBook(ctx, u); !errors.Is(err, ErrBookingBlocked) {
+		t.Errorf("expected ErrBookingBlocked, got %v", err)
+	}
+	staffCtx := domain.ContextWithActor(ctx, domain.Actor{ID: uuid.New(), Role: domain.RoleAdmin})
+	if err := svc.CheckCanBook(staffCtx, u); err != nil {
+		t.Errorf("expected staff booking to be allowed, got %v", err)
+	}
+
+	// Clearing resets strikes but keeps the lifetime counters
+	if err := svc.ClearBlock(ctx, client.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _ = users.GetByID(ctx, client.ID)
+	if u.BookingBlocked || u.PolicyStrikes != 0 || u.NoShowCount != 1 {
+		t.Errorf("unexpected user after clear: blocked=%v strikes=%d no-shows=%d", u.BookingBlocked, u.PolicyStrikes, u.NoShowCount)
+	}
+}
+
+func TestPolicyService_BookingRestrictions(t *testing.T) {
+	ctx := context.Background()
+	reliable := &domain.User{ID: uuid.New(), NoShowCount: 1}
+	unreliable := &domain.User{ID: uuid.New(), NoShowCount: 2, LateCancelCount: 1}
+
+	for _, mode := range []domain.RestrictionMode{domain.RestrictApproval, domain.RestrictDeposit} {
+		settings := &MockSettingsRepo{Settings: &domain.Settings{RestrictAfterStrikes: 3, RestrictionMode: mode}}
+		svc := NewPolicyService(NewMockUserRepo(), settings)
+
+		approval, deposit, err := svc.BookingRestrictions(ctx, reliable)
+		if err != nil || approval || deposit {
+			t.Errorf("%s: reliable client should not be restricted (approval=%v deposit=%v err=%v)", mode, approval, deposit, err)
+		}
+
+		approval, deposit, err = svc.BookingRestrictions(ctx, unreliable)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if approval != (mode == domain.RestrictApproval) || deposit != (mode == domain.RestrictDeposit) {
+			t.Errorf("%s: unexpected restrictions approval=%v deposit=%v", mode, approval, deposit)
+		}
+	}
+}
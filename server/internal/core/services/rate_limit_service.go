@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+const (
+	// Failed logins to an account from one IP before that IP is locked out of it
+	lockoutThreshold = 5
+	// Failed logins to an account from any IP before it is locked for everyone. Well
+	// above lockoutThreshold, so a single attacker cannot lock the owner out easily.
+	accountLockoutThreshold = 50
+	// The first lockout; each further failure doubles it, up to lockoutMax
+	lockoutBase = time.Minute
+	lockoutMax  = time.Hour
+	// Failed logins are forgotten after this long without a success
+	lockoutMemory = 24 * time.Hour
+)
+
+// RateLimitService throttles requests by bucket and locks accounts out of password
+// logins after repeated failures, for longer on every further failure. Failures are
+// counted per account and IP, with a looser ceiling for the account as a whole.
+type RateLimitService struct {
+	store ports.RateLimitStore
+}
+
+func NewRateLimitService(store ports.RateLimitStore) *RateLimitService {
+	return &RateLimitService{store: store}
+}
+
+func (s *RateLimitService) Allow(ctx context.Context, key string, limit domain.RateLimit) (time.Duration, error) {
+	if limit.Requests <= 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	counter, err := s.store.Increment(ctx, "limit:"+key, limit.Window, now)
+	if err != nil {
+		return 0, err
+	}
+	if counter.Count <= limit.Requests {
+		return 0, nil
+	}
+	return counter.ResetAt.Sub(now), nil
+}
+
+func (s *RateLimitService) LoginLockedFor(ctx context.Context, account, ip string) (time.Duration, error) {
+	now := time.Now()
+	var locked time.Duration
+	for _, failures := range loginFailureCounters(account, ip) {
+		counter, err := s.store.Get(ctx, failures.key, now)
+		if err != nil {
+			return 0, err
+		}
+		if counter != nil {
+			locked = max(locked, lockedFor(counter, failures.threshold, now))
+		}
+	}
+	return locked, nil
+}
+
+func (s *RateLimitService) LoginFailed(ctx context.Context, account, ip string) (time.Duration, error) {
+	now := time.Now()
+	var locked time.Duration
+	for _, failures := range loginFailureCounters(account, ip) {
+		counter, err := s.store.Increment(ctx, failures.key, lockoutMemory, now)
+		if err != nil {
+			return 0, err
+		}
+		locked = max(locked, lockedFor(counter, failures.threshold, now))
+	}
+	return locked, nil
+}
+
+func (s *RateLimitService) LoginSucceeded(ctx context.Context, account, ip string) error {
+	for _, failures := range loginFailureCounters(account, ip) {
+		if err := s.store.Delete(ctx, failures.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpired removes counters whose window has ended.
+func (s *RateLimitService) DeleteExpired(ctx context.Context) error {
+	_, err := s.store.DeleteExpired(ctx, time.Now())
+	return err
+}
+
+func (s *RateLimitService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Rate limit sweeper", s.DeleteExpired)
+}
+
+// lockedFor is what remains of the lockout set by the last failure.
+func lockedFor(failures *domain.RateCounter, threshold int, now time.Time) time.Duration {
+	if failures.Count < threshold {
+		return 0
+	}
+	lockout := lockoutBase
+	for i := threshold; i < failures.Count && lockout < lockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > lockoutMax {
+		lockout = lockoutMax
+	}
+	if remaining := failures.LastHitAt.Add(lockout).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+type loginFailureCounter struct {
+	key       string
+	threshold int
+}
+
+func loginFailureCounters(account, ip string) []loginFailureCounter {
+	return []loginFailureCounter{
+		{loginFailuresKey(account, ip), lockoutThreshold},
+		{loginFailuresKey(account, ""), accountLockoutThreshold},
+	}
+}
+
+// loginFailuresKey counts failures to the account from ip, or from anywhere when ip is empty.
+func loginFailuresKey(account, ip string) string {
+	key := "login-failures:" + domain.NormalizeEmail(account)
+	if ip != "" {
+		key += ":ip:" + ip
+	}
+	return key
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+type MockRateLimitStore struct {
+	Counters map[string]*domain.RateCounter
+}
+
+func NewMockRateLimitStore() *MockRateLimitStore {
+	return &MockRateLimitStore{Counters: make(map[string]*domain.RateCounter)}
+}
+
+func (m *MockRateLimitStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (*domain.RateCounter, error) {
+	counter, ok := m.Counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		counter = &domain.RateCounter{Key: key, ResetAt: now.Add(window)}
+		m.Counters[key] = counter
+	}
+	counter.Count++
+	counter.LastHitAt = now
+	copy := *counter
+	return &copy, nil
+}
+func (m *MockRateLimitStore) Get(ctx context.Context, key string, now time.Time) (*domain.RateCounter, error) {
+	counter, ok := m.Counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		return nil, nil
+	}
+	copy := *counter
+	return &copy, nil
+}
+func (m *MockRateLimitStore) Delete(ctx context.Context, key string) error {
+	delete(m.Counters, key)
+	return nil
+}
+func (m *MockRateLimitStore) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRateLimitService_Allow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockRateLimitStore()
+	svc := NewRateLimitService(store)
+	limit := domain.RateLimit{Requests: 3, Window: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if wait, err := svc.Allow(ctx, "auth:ip:1.2.3.4", limit); err != nil || wait != 0 {
+			t.Fatalf("request %d: expected to be allowed, got wait=%v err=%v", i+1, wait, err)
+		}
+	}
+	wait, _ := svc.Allow(ctx, "auth:ip:1.2.3.4", limit)
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("expected to wait for the window to end, got %v", wait)
+	}
+	if wait, _ := svc.Allow(ctx, "auth:ip:5.6.7.8", limit); wait != 0 {
+		t.Error("expected other buckets not to be affected")
+	}
+
+	// Once the window ends the bucket starts over
+	store.Counters["limit:auth:ip:1.2.3.4"].ResetAt = time.Now().Add(-time.Second)
+	if wait, _ := svc.Allow(ctx, "auth:ip:1.2.3.4", limit); wait != 0 {
+		t.Errorf("expected a new window to allow the request, got %v", wait)
+	}
+	if wait, _ := svc.Allow(ctx, "anything", domain.RateLimit{}); wait != 0 {
+		t.Error("expected a zero limit not to limit")
+	}
+}
+
+func TestRateLimitService_ProgressiveLockout(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockRateLimitStore()
+	svc := NewRateLimitService(store)
+
+	for i := 1; i < lockoutThreshold; i++ {
+		if locked, _ := svc.LoginFailed(ctx, "Ana@example.com", "1.2.3.4"); locked != 0 {
+			t.Fatalf("failure %d: expected no lockout yet, got %v", i, locked)
+		}
+	}
+	locked, _ := svc.LoginFailed(ctx, "ana@example.com", "1.2.3.4")
+	if locked <= lockoutBase-time.Second || locked > lockoutBase {
+		t.Errorf("expected a first lockout of %v, got %v", lockoutBase, locked)
+	}
+	if still, _ := svc.LoginLockedFor(ctx, "ANA@example.com", "1.2.3.4"); still <= 0 {
+		t.Error("expected the account to be locked regardless of email case")
+	}
+
+	locked, _ = svc.LoginFailed(ctx, "ana@example.com", "1.2.3.4")
+	if locked <= 2*lockoutBase-time.Second || locked > 2*lockoutBase {
+		t.Errorf("expected the lockout to double, got %v", locked)
+	}
+
+	store.Counters[loginFailuresKey("ana@example.com", "1.2.3.4")].Count = 40
+	if locked, _ := svc.LoginFailed(ctx, "ana@example.com", "1.2.3.4"); locked > lockoutMax || locked < lockoutMax-time.Second {
+		t.Errorf("expected the lockout to be capped at %v, got %v", lockoutMax, locked)
+	}
+
+	// The lockout runs from the last failure
+	store.Counters[loginFailuresKey("ana@example.com", "1.2.3.4")].LastHitAt = time.Now().Add(-2 * lockoutMax)
+	if still, _ := svc.LoginLockedFor(ctx, "ana@example.com", "1.2.3.4"); still != 0 {
+		t.Errorf("expected the lockout to be over, got %v", still)
+	}
+
+	if err := svc.LoginSucceeded(ctx, "ana@example.com", "1.2.3.4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if locked, _ := svc.LoginFailed(ctx, "ana@example.com", "1.2.3.4"); locked != 0 {
+		t.Errorf("expected a success to reset the failures, got %v", locked)
+	}
+}
+
+func TestRateLimitService_LockoutIsPerIPWithAccountCeiling(t *testing.T) {
+	ctx := context.Background()
+	svc := NewRateLimitService(NewMockRateLimitStore())
+
+	for i := 0; i < lockoutThreshold; i++ {
+		svc.LoginFailed(ctx, "ana@example.com", "6.6.6.6")
+	}
+	if locked, _ := svc.LoginLockedFor(ctx, "ana@example.com", "6.6.6.6"); locked <= 0 {
+		t.Error("expected the failing IP to be locked out")
+	}
+	if locked, _ := svc.LoginLockedFor(ctx, "ana@example.com", "1.2.3.4"); locked != 0 {
+		t.Errorf("expected the owner's IP not to be locked out, got %v", locked)
+	}
+
+	// Failures spread over many IPs still lock the account once they pile up
+	for i := lockoutThreshold; i < accountLockoutThreshold; i++ {
+		svc.LoginFailed(ctx, "ana@example.com", fmt.Sprintf("10.0.0.%d", i))
+	}
+	if locked, _ := svc.LoginLockedFor(ctx, "ana@example.com", "1.2.3.4"); locked <= 0 {
+		t.Error("expected the account to be locked for every IP")
+	}
+	if locked, _ := svc.LoginLockedFor(ctx, "bob@example.com", "6.6.6.6"); locked != 0 {
+		t.Errorf("expected other accounts not to be locked, got %v", locked)
+	}
+}
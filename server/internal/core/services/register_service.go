@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+)
+
+// ErrNotBillable is returned when recording a payment for an appointment that did not take place.
+var ErrNotBillable = errors.New("payments can only be recorded for confirmed or completed appointments")
+
+type RegisterService struct {
+	repo      ports.PaymentRepository
+	apptRepo  ports.AppointmentRepository
+	eventRepo ports.AppointmentEventRepository
+	tx        ports.Transactor
+	loc       *time.Location
+}
+
+// NewRegisterService takes the shop's timezone: payments are real instants, so a day
+// of takings runs from midnight to midnight there.
+func NewRegisterService(repo ports.PaymentRepository, apptRepo ports.AppointmentRepository, eventRepo ports.AppointmentEventRepository, tx ports.Transactor, loc *time.Location) *RegisterService {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &RegisterService{repo: repo, apptRepo: apptRepo, eventRepo: eventRepo, tx: tx, loc: loc}
+}
+
+func (s *RegisterService) RecordPayment(ctx context.Context, appointmentID uuid.UUID, payment *domain.Payment) error {
+	if !payment.Method.Valid() {
+		return fmt.Errorf("unknown payment method %q", payment.Method)
+	}
+	if payment.Amount < 0 || payment.Tip < 0 || payment.Discount < 0 {
+		return errors.New("amounts cannot be negative")
+	}
+	if payment.Total() == 0 {
+		return errors.New("payment amount is required")
+	}
+
+	appt, err := s.apptRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return err
+	}
+	if appt.Status != domain.StatusConfirmed && appt.Status != domain.StatusCompleted {
+		return ErrNotBillable
+	}
+
+	payment.ID = uuid.Nil
+	payment.AppointmentID = appt.ID
+	payment.RecordedByID = nil
+	if actor := domain.ActorFromContext(ctx); actor != nil {
+		payment.RecordedByID = &actor.ID
+	}
+	return inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, payment); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, appt.ID, domain.EventPaymentTaken, "", fmt.Sprintf("%.2f %s", payment.Total(), payment.Method))
+	})
+}
+
+func (s *RegisterService) ListPayments(ctx context.Context, appointmentID uuid.UUID) ([]domain.Payment, error) {
+	return s.repo.ListByAppointment(ctx, appointmentID)
+}
+
+func (s *RegisterService) CloseDay(ctx context.Context, date time.Time) (*domain.CashClose, error) {
+	start := dayStart(date, s.loc)
+	rows, err := s.repo.TotalsByMethod(ctx, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	byMethod := make(map[domain.PaymentMethod]domain.PaymentTotals, len(rows))
+	for _, row := range rows {
+		byMethod[row.Method] = row
+	}
+
+	// Every method gets a line, even if nothing was paid with it
+	report := &domain.CashClose{Date: start.Format("2006-01-02")}
+	for _, method := range domain.PaymentMethods {
+		line := byMethod[method]
+		line.Method = method
+		report.ByMethod = append(report.ByMethod, line)
+
+		report.Totals.Payments += line.Payments
+		report.Totals.Amount += line.Amount
+		report.Totals.Tips += line.Tips
+		report.Totals.Discounts += line.Discounts
+		report.Totals.Total += line.Total
+	}
+	return report, nil
+}
+
+// dayStart is midnight in loc of the calendar day of date, which is read as is.
+func dayStart(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
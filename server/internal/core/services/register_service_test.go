@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+// MockPaymentRepo keeps payments in memory and aggregates them like the SQL repository.
+type MockPaymentRepo struct {
+	Payments []domain.Payment
+}
+
+func (m *MockPaymentRepo) Create(ctx context.Context, payment *domain.Payment) error {
+	payment.ID = uuid.New()
+	if payment.CreatedAt.IsZero() {
+		payment.CreatedAt = time.Now().UTC()
+	}
+	m.Payments = append(m.Payments, *payment)
+	return nil
+}
+func (m *MockPaymentRepo) ListByAppointment(ctx context.Context, appointmentID uuid.UUID) ([]domain.Payment, error) {
+	var out []domain.Payment
+	for _, p := range m.Payments {
+		if p.AppointmentID == appointmentID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+func (m *MockPaymentRepo) TotalsByMethod(ctx context.Context, start, end time.Time) ([]domain.PaymentTotals, error) {
+	totals := map[domain.PaymentMethod]*domain.PaymentTotals{}
+	var out []domain.PaymentTotals
+	for _, p := range m.Payments {
+		if p.CreatedAt.Before(start) || !p.CreatedAt.Before(end) {
+			continue
+		}
+		t, ok := totals[p.Method]
+		if !ok {
+			t = &domain.PaymentTotals{Method: p.Method}
+			totals[p.Method] = t
+		}
+		t.Payments++
+		t.Amount += p.Amount
+		t.Tips += p.Tip
+		t.Discounts += p.Discount
+		t.Total += p.Total()
+	}
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	return out, nil
+}
+func (m *MockPaymentRepo) Summarize(ctx context.Context, start, end time.Time) (*domain.RevenueSummary, error) {
+	return &domain.RevenueSummary{}, nil
+}
+
+func TestRecordPayment_RejectsAppointmentsThatDidNotHappen(t *testing.T) {
+	appt := &domain.Appointment{ID: uuid.New(), Status: domain.StatusNoShow}
+	apptRepo := &MockAppointmentRepo{GetByIDFunc: func(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+		return appt, nil
+	}}
+	repo := &MockPaymentRepo{}
+	svc := NewRegisterService(repo, apptRepo, nil, nil, nil)
+
+	err := svc.RecordPayment(context.Background(), appt.ID, &domain.Payment{Method: domain.MethodCash, Amount: 8000})
+	if !errors.Is(err, ErrNotBillable) {
+		t.Fatalf("expected ErrNotBillable, got %v", err)
+	}
+
+	appt.Status = domain.StatusCompleted
+	if err := svc.RecordPayment(context.Background(), appt.ID, &domain.Payment{Method: "bitcoin", Amount: 8000}); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+	if err := svc.RecordPayment(context.Background(), appt.ID, &domain.Payment{Method: domain.MethodCash, Amount: 8000, Tip: 1000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.Payments) != 1 || repo.Payments[0].AppointmentID != appt.ID {
+		t.Fatalf("expected one payment for the appointment, got %+v", repo.Payments)
+	}
+}
+
+func TestCloseDay_TotalsByMethod(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	repo := &MockPaymentRepo{Payments: []domain.Payment{
+		{Method: domain.MethodCash, Amount: 8000, Tip: 500, CreatedAt: day.Add(10 * time.Hour)},
+		{Method: domain.MethodCash, Amount: 7000, Discount: 1000, CreatedAt: day.Add(11 * time.Hour)},
+		{Method: domain.MethodCard, Amount: 8000, CreatedAt: day.Add(12 * time.Hour)},
+		{Method: domain.MethodCash, Amount: 9999, CreatedAt: day.Add(25 * time.Hour)}, // Next day
+	}}
+	svc := NewRegisterService(repo, &MockAppointmentRepo{}, nil, nil, nil)
+
+	report, err := svc.CloseDay(context.Background(), day.Add(15*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Date != "2026-03-10" {
+		t.Errorf("expected date 2026-03-10, got %s", report.Date)
+	}
+	if len(report.ByMethod) != len(domain.PaymentMethods) {
+		t.Fatalf("expected a line per method, got %d", len(report.ByMethod))
+	}
+	cash := report.ByMethod[0]
+	if cash.Method != domain.MethodCash || cash.Payments != 2 || cash.Total != 15500 || cash.Discounts != 1000 {
+		t.Errorf("unexpected cash line: %+v", cash)
+	}
+	if report.Totals.Payments != 3 || report.Totals.Amount != 23000 || report.Totals.Tips != 500 || report.Totals.Total != 23500 {
+		t.Errorf("unexpected totals: %+v", report.Totals)
+	}
+}
+
+func TestCloseDay_UsesShopDay(t *testing.T) {
+	loc, err := time.LoadLocation("America/Argentina/Buenos_Aires")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	// Buenos Aires is UTC-3: the shop's 10th runs from 03:00 UTC on the 10th to 03:00 on the 11th
+	repo := &MockPaymentRepo{Payments: []domain.Payment{
+		{Method: domain.MethodCash, Amount: 1000, CreatedAt: time.Date(2026, 3, 10, 2, 30, 0, 0, time.UTC)}, // 23:30 on the 9th
+		{Method: domain.MethodCash, Amount: 2000, CreatedAt: time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)},
+		{Method: domain.MethodCash, Amount: 4000, CreatedAt: time.Date(2026, 3, 11, 1, 30, 0, 0, time.UTC)}, // 22:30 on the 10th
+	}}
+	svc := NewRegisterService(repo, &MockAppointmentRepo{}, nil, nil, loc)
+
+	report, err := svc.CloseDay(context.Background(), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Date != "2026-03-10" || report.Totals.Payments != 2 || report.Totals.Amount != 6000 {
+		t.Errorf("expected the payments of the shop's day, got %+v", report)
+	}
+}
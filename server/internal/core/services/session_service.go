@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL = 15 * time.Minute
+	// Sessions unused for this long expire; every refresh pushes the expiry forward
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidSession        = errors.New("your session has expired, please log in again")
+	ErrRefreshTokenReused    = errors.New("this session was closed because its refresh token was used twice, please log in again")
+	errMalformedRefreshToken = errors.New("malformed refresh token")
+)
+
+// SessionService issues short-lived access tokens and the rotating refresh tokens
+// that renew them, one session per login.
+type SessionService struct {
+	repo     ports.SessionRepository
+	userRepo ports.UserRepository
+	tokens   ports.TokenIssuer
+}
+
+func NewSessionService(repo ports.SessionRepository, userRepo ports.UserRepository, tokens ports.TokenIssuer) *SessionService {
+	return &SessionService{repo: repo, userRepo: userRepo, tokens: tokens}
+}
+
+// Start opens a session for a user who has just authenticated.
+func (s *SessionService) Start(ctx context.Context, user *domain.User, userAgent, ip string) (*domain.TokenPair, error) {
+	raw, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &domain.Session{
+		ID:               uuid.New(),
+		UserID:           user.ID,
+		RefreshTokenHash: hash,
+		UserAgent:        userAgent,
+		IP:               ip,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL),
+		CreatedAt:        now,
+	}
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.tokenPair(user, session, raw)
+}
+
+// Refresh exchanges a refresh token for a new pair. A token that was already rotated
+// means it was copied, so the whole session is revoked.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken, ip string) (*domain.TokenPair, error) {
+	id, raw, err := splitRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	session, user, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if hashToken(raw) != session.RefreshTokenHash {
+		if err := s.repo.Revoke(ctx, session.ID, domain.RevokedByReuse, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	newRaw, newHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(refreshTokenTTL)
+	rotated, err := s.repo.Rotate(ctx, session.ID, session.RefreshTokenHash, newHash, now, expiresAt, ip)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another refresh used the same token first
+		if err := s.repo.Revoke(ctx, session.ID, domain.RevokedByReuse, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	session.RefreshTokenHash = newHash
+	session.ExpiresAt = expiresAt
+	return s.tokenPair(user, session, newRaw)
+}
+
+// Logout closes the session of the refresh token. Unknown tokens are ignored.
+func (s *SessionService) Logout(ctx context.Context, refreshToken string) error {
+	id, raw, err := splitRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	session, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if hashToken(raw) != session.RefreshTokenHash {
+		return nil
+	}
+	return s.repo.Revoke(ctx, session.ID, domain.RevokedByLogout, time.Now().UTC())
+}
+
+func (s *SessionService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.repo.RevokeAllForUser(ctx, userID, domain.RevokedByLogoutAll, time.Now().UTC())
+	return err
+}
+
+func (s *SessionService) ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// RevokeSession lets an admin close one of the user's sessions.
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.repo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	return s.repo.Revoke(ctx, session.ID, domain.RevokedByAdmin, time.Now().UTC())
+}
+
+// Authenticate returns the user of an access token's session, failing with
+// ErrInvalidSession once the session is revoked or expired.
+func (s *SessionService) Authenticate(ctx context.Context, sessionID uuid.UUID) (*domain.User, error) {
+	_, user, err := s.load(ctx, sessionID)
+	return user, err
+}
+
+// load returns an active session and its user.
+func (s *SessionService) load(ctx context.Context, id uuid.UUID) (*domain.Session, *domain.User, error) {
+	session, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !session.Active(time.Now()) {
+		return nil, nil, ErrInvalidSession
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Active() {
+		return nil, nil, ErrAccountInactive
+	}
+	// e.g. a password reset after this session was opened
+	if user.SessionsRevokedAt != nil && session.CreatedAt.Before(*user.SessionsRevokedAt) {
+		return nil, nil, ErrInvalidSession
+	}
+	return session, user, nil
+}
+
+func (s *SessionService) tokenPair(user *domain.User, session *domain.Session, refreshRaw string) (*domain.TokenPair, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL)
+	signed, err := s.tokens.Sign(domain.AccessClaims{
+		UserID:    user.ID,
+		SessionID: session.ID,
+		Role:      user.Role,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:      signed,
+		AccessExpiresAt:  expiresAt.UTC(),
+		RefreshToken:     session.ID.String() + "." + refreshRaw,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Refresh tokens are "<session id>.<secret>" so the session is found without a hash lookup.
+func splitRefreshToken(token string) (uuid.UUID, string, error) {
+	idPart, raw, ok := strings.Cut(token, ".")
+	if !ok || raw == "" {
+		return uuid.Nil, "", errMalformedRefreshToken
+	}
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return uuid.Nil, "", errMalformedRefreshToken
+	}
+	return id, raw, nil
+}
+
+// DeleteExpired removes sessions that expired over a month ago; recent ones stay
+// visible to admins.
+func (s *SessionService) DeleteExpired(ctx context.Context) error {
+	_, err := s.repo.DeleteExpired(ctx, time.Now().Add(-refreshTokenTTL).UTC())
+	return err
+}
+
+func (s *SessionService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Session sweeper", s.DeleteExpired)
+}
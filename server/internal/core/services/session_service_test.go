@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+type MockSessionRepo struct {
+	Sessions map[uuid.UUID]*domain.Session
+}
+
+func NewMockSessionRepo() *MockSessionRepo {
+	return &MockSessionRepo{Sessions: make(map[uuid.UUID]*domain.Session)}
+}
+
+func (m *MockSessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	copy := *session
+	m.Sessions[session.ID] = &copy
+	return nil
+}
+func (m *MockSessionRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Session, error) {
+	session, ok := m.Sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *session
+	return &copy, nil
+}
+func (m *MockSessionRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+	var sessions []domain.Session
+	for _, s := range m.Sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, *s)
+		}
+	}
+	return sessions, nil
+}
+func (m *MockSessionRepo) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash string, usedAt, expiresAt time.Time, ip string) (bool, error) {
+	s := m.Sessions[id]
+	if s.RefreshTokenHash != oldHash || s.RevokedAt != nil {
+		return false, nil
+	}
+	s.RefreshTokenHash, s.LastUsedAt, s.ExpiresAt, s.IP = newHash, usedAt, expiresAt, ip
+	return true, nil
+}
+func (m *MockSessionRepo) Revoke(ctx context.Context, id uuid.UUID, reason string, at time.Time) error {
+	if s := m.Sessions[id]; s.RevokedAt == nil {
+		s.RevokedAt, s.RevokedReason = &at, reason
+	}
+	return nil
+}
+func (m *MockSessionRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID, reason string, at time.Time) (int64, error) {
+	var n int64
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt, s.RevokedReason = &at, reason
+			n++
+		}
+	}
+	return n, nil
+}
+func (m *MockSessionRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newSessionFixture() (*SessionService, *MockSessionRepo, *domain.User) {
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient, IsVerified: true}
+	repo := NewMockSessionRepo()
+	return NewSessionService(repo, NewMockUserRepo(user), NewHMACTokenIssuer("test-secret")), repo, user
+}
+
+func TestSessionService_RefreshRotates(t *testing.T) {
+	svc, _, user := newSessionFixture()
+	ctx := context.Background()
+
+	first, err := svc.Start(ctx, user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Error("expected a new token pair")
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, "127.0.0.1"); err != nil {
+		t.Errorf("expected the rotated token to work, got %v", err)
+	}
+}
+
+func TestSessionService_ReuseRevokesSession(t *testing.T) {
+	svc, repo, user := newSessionFixture()
+	ctx := context.Background()
+
+	first, _ := svc.Start(ctx, user, "test", "127.0.0.1")
+	second, err := svc.Refresh(ctx, first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.Refresh(ctx, first.RefreshToken, "10.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, "127.0.0.1"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("expected the whole session to be revoked, got %v", err)
+	}
+	for _, s := range repo.Sessions {
+		if s.RevokedReason != domain.RevokedByReuse {
+			t.Errorf("expected reason %q, got %q", domain.RevokedByReuse, s.RevokedReason)
+		}
+	}
+}
+
+func TestSessionService_LogoutAndRevocations(t *testing.T) {
+	svc, repo, user := newSessionFixture()
+	ctx := context.Background()
+
+	phone, _ := svc.Start(ctx, user, "phone", "127.0.0.1")
+	laptop, _ := svc.Start(ctx, user, "laptop", "127.0.0.1")
+
+	if err := svc.Logout(ctx, phone.RefreshToken); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, phone.RefreshToken, ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("expected logged out session to be invalid, got %v", err)
+	}
+
+	var laptopID uuid.UUID
+	for id, s := range repo.Sessions {
+		if s.UserAgent == "laptop" {
+			laptopID = id
+		}
+	}
+	if _, err := svc.Authenticate(ctx, laptopID); err != nil {
+		t.Fatalf("expected the other session to stay open, got %v", err)
+	}
+
+	if err := svc.RevokeSession(ctx, uuid.New(), laptopID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected sessions of other users to be hidden, got %v", err)
+	}
+	if err := svc.LogoutAll(ctx, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, laptop.RefreshToken, ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("expected every session to be closed, got %v", err)
+	}
+}
+
+func TestSessionService_PasswordResetClosesOlderSessions(t *testing.T) {
+	svc, _, user := newSessionFixture()
+	ctx := context.Background()
+
+	tokens, _ := svc.Start(ctx, user, "test", "127.0.0.1")
+	users := svc.userRepo.(*MockUserRepo)
+	revoked := time.Now().Add(time.Second)
+	users.Users[user.ID].SessionsRevokedAt = &revoked
+
+	if _, err := svc.Refresh(ctx, tokens.RefreshToken, ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("expected ErrInvalidSession, got %v", err)
+	}
+}
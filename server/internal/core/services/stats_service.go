@@ -2,22 +2,38 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
 	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
 )
 
+// ErrInvalidRange is returned for analytics queries with an empty, reversed or too long range.
+var ErrInvalidRange = errors.New("invalid date range")
+
+// maxAnalyticsDays bounds a report so day grouping stays a reasonable size.
+const maxAnalyticsDays = 3 * 366
+
 type StatsService struct {
-	apptRepo ports.AppointmentRepository
+	apptRepo      ports.AppointmentRepository
+	paymentRepo   ports.PaymentRepository
+	analyticsRepo ports.AnalyticsRepository
+	loc           *time.Location
 }
 
-func NewStatsService(apptRepo ports.AppointmentRepository) *StatsService {
-	return &StatsService{apptRepo: apptRepo}
+// NewStatsService takes the shop's timezone, in which the revenue of a day or month
+// is counted. Appointments are already stored as the shop's wall clock.
+func NewStatsService(apptRepo ports.AppointmentRepository, paymentRepo ports.PaymentRepository, analyticsRepo ports.AnalyticsRepository, loc *time.Location) *StatsService {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &StatsService{apptRepo: apptRepo, paymentRepo: paymentRepo, analyticsRepo: analyticsRepo, loc: loc}
 }
 
 func (s *StatsService) GetMonthlyStats(ctx context.Context) (map[string]interface{}, error) {
-	now := time.Now()
+	now := time.Now().In(s.loc)
 	month := now.Month()
 	year := now.Year()
 
@@ -31,19 +47,137 @@ func (s *StatsService) GetMonthlyStats(ctx context.Context) (map[string]interfac
 		return nil, err
 	}
 
-	// Attendance rate logic would need more granular status (Completed vs NoShow)
-	// For now, using Confirmed vs Total as a proxy or just returning raw numbers.
+	noShows, err := s.apptRepo.CountNoShowsByMonth(ctx, month, year)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attendance rate: share of closed appointments the client actually showed up to
+	attendanceRate := 0.0
+	if completed+noShows > 0 {
+		attendanceRate = float64(completed) / float64(completed+noShows)
+	}
 
 	pending, err := s.apptRepo.CountByStatus(ctx, domain.StatusPending)
 	if err != nil {
 		return nil, err
 	}
 
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, s.loc)
+	revenue, err := s.paymentRepo.Summarize(ctx, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Average ticket: revenue per paid appointment, tips excluded
+	averageTicket := 0.0
+	if revenue.Tickets > 0 {
+		averageTicket = revenue.Revenue / float64(revenue.Tickets)
+	}
+
 	return map[string]interface{}{
 		"total_appointments": total,
 		"completed":          completed,
+		"no_shows":           noShows,
+		"attendance_rate":    attendanceRate,
 		"pending":            pending,
+		"revenue":            revenue.Revenue,
+		"average_ticket":     averageTicket,
+		"tips":               revenue.Tips,
+		"discounts":          revenue.Discounts,
 		"month":              month.String(),
 		"year":               year,
 	}, nil
 }
+
+// GetAnalytics reports on the days in [from, to), one entry per period including empty ones.
+func (s *StatsService) GetAnalytics(ctx context.Context, from, to time.Time, groupBy domain.Grouping) (*domain.Analytics, error) {
+	if !groupBy.Valid() {
+		return nil, fmt.Errorf("%w: unknown grouping %q", ErrInvalidRange, groupBy)
+	}
+	start := truncateDay(from)
+	end := truncateDay(to)
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidRange)
+	}
+	if end.Sub(start) > maxAnalyticsDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: at most %d days", ErrInvalidRange, maxAnalyticsDays)
+	}
+
+	appts, apptTotals, err := s.analyticsRepo.AppointmentsByPeriod(ctx, start, end, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	available, err := s.analyticsRepo.AvailabilityByPeriod(ctx, start, end, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := s.analyticsRepo.RevenueByPeriod(ctx, dayStart(start, s.loc), dayStart(end, s.loc), groupBy, s.loc)
+	if err != nil {
+		return nil, err
+	}
+	heatmap, err := s.analyticsRepo.Heatmap(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lay out every period of the range, then merge the rows each query returned
+	report := &domain.Analytics{
+		From:    start.Format("2006-01-02"),
+		To:      end.Format("2006-01-02"),
+		GroupBy: groupBy,
+		Totals:  apptTotals,
+		Heatmap: heatmap,
+	}
+	index := make(map[string]int)
+	for p := periodStart(start, groupBy); p.Before(end); p = nextPeriod(p, groupBy) {
+		key := p.Format("2006-01-02")
+		index[key] = len(report.Periods)
+		report.Periods = append(report.Periods, domain.PeriodStats{PeriodStart: p, Period: key})
+	}
+	for _, rows := range [][]domain.PeriodStats{appts, available, revenue} {
+		for _, row := range rows {
+			if i, ok := index[row.PeriodStart.Format("2006-01-02")]; ok {
+				report.Periods[i].Add(row)
+			}
+		}
+	}
+
+	for i := range report.Periods {
+		report.Periods[i].ComputeRates()
+		report.Totals.AvailableMinutes += report.Periods[i].AvailableMinutes
+		report.Totals.Revenue += report.Periods[i].Revenue
+		report.Totals.Tips += report.Periods[i].Tips
+	}
+	report.Totals.ComputeRates()
+	return report, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart matches Postgres date_trunc: weeks start on Monday.
+func periodStart(day time.Time, groupBy domain.Grouping) time.Time {
+	switch groupBy {
+	case domain.GroupByWeek:
+		offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+		return day.AddDate(0, 0, -offset)
+	case domain.GroupByMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextPeriod(p time.Time, groupBy domain.Grouping) time.Time {
+	switch groupBy {
+	case domain.GroupByWeek:
+		return p.AddDate(0, 0, 7)
+	case domain.GroupByMonth:
+		return p.AddDate(0, 1, 0)
+	default:
+		return p.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+// MockAnalyticsRepo returns canned rows per query.
+type MockAnalyticsRepo struct {
+	Appointments []domain.PeriodStats
+	Total        domain.PeriodStats
+	Available    []domain.PeriodStats
+	Revenue      []domain.PeriodStats
+}
+
+func (m *MockAnalyticsRepo) AppointmentsByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) ([]domain.PeriodStats, domain.PeriodStats, error) {
+	return m.Appointments, m.Total, nil
+}
+func (m *MockAnalyticsRepo) AvailabilityByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping) ([]domain.PeriodStats, error) {
+	return m.Available, nil
+}
+func (m *MockAnalyticsRepo) RevenueByPeriod(ctx context.Context, start, end time.Time, groupBy domain.Grouping, loc *time.Location) ([]domain.PeriodStats, error) {
+	return m.Revenue, nil
+}
+func (m *MockAnalyticsRepo) Heatmap(ctx context.Context, start, end time.Time) ([]domain.HeatmapCell, error) {
+	return nil, nil
+}
+
+func TestGetAnalytics_WeeklyPeriodsAndRates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, time.UTC) }
+	repo := &MockAnalyticsRepo{
+		// 2026-06-01 and 2026-06-08 are Mondays
+		Appointments: []domain.PeriodStats{{PeriodStart: day(8), Appointments: 4, Completed: 1, Cancelled: 1, NoShows: 1, BookedMinutes: 180}},
+		Total:        domain.PeriodStats{Appointments: 4, Completed: 1, Cancelled: 1, NoShows: 1, BookedMinutes: 180, NewClients: 1},
+		Available:    []domain.PeriodStats{{PeriodStart: day(1), AvailableMinutes: 240}, {PeriodStart: day(8), AvailableMinutes: 360}},
+		Revenue:      []domain.PeriodStats{{PeriodStart: day(8), Revenue: 16000, Tips: 1000}},
+	}
+	svc := NewStatsService(&MockAppointmentRepo{}, &MockPaymentRepo{}, repo, nil)
+
+	// Wednesday 3rd to Wednesday 17th: touches three weeks
+	report, err := svc.GetAnalytics(context.Background(), day(3), day(17), domain.GroupByWeek)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(report.Periods) != 3 {
+		t.Fatalf("expected 3 weekly periods, got %d", len(report.Periods))
+	}
+	if report.Periods[0].Period != "2026-06-01" || report.Periods[2].Period != "2026-06-15" {
+		t.Errorf("unexpected periods: %s .. %s", report.Periods[0].Period, report.Periods[2].Period)
+	}
+
+	week := report.Periods[1]
+	if week.OccupancyRate != 0.5 || week.CancellationRate != 0.25 || week.NoShowRate != 0.5 {
+		t.Errorf("unexpected rates: %+v", week)
+	}
+	if week.Revenue != 16000 {
+		t.Errorf("expected revenue merged into the period, got %v", week.Revenue)
+	}
+
+	if report.Totals.AvailableMinutes != 600 || report.Totals.NewClients != 1 || report.Totals.OccupancyRate != 0.3 {
+		t.Errorf("unexpected totals: %+v", report.Totals)
+	}
+}
+
+func TestGetAnalytics_RejectsBadRanges(t *testing.T) {
+	svc := NewStatsService(&MockAppointmentRepo{}, &MockPaymentRepo{}, &MockAnalyticsRepo{}, nil)
+	now := time.Now()
+
+	cases := []struct {
+		from, to time.Time
+		groupBy  domain.Grouping
+	}{
+		{now, now, domain.GroupByDay},
+		{now, now.AddDate(0, 0, -1), domain.GroupByDay},
+		{now, now.AddDate(5, 0, 0), domain.GroupByMonth},
+		{now, now.AddDate(0, 0, 1), "year"},
+	}
+	for _, tc := range cases {
+		if _, err := svc.GetAnalytics(context.Background(), tc.from, tc.to, tc.groupBy); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("expected ErrInvalidRange for %v..%v by %s, got %v", tc.from, tc.to, tc.groupBy, err)
+		}
+	}
+}
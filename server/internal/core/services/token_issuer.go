@@ -0,0 +1,250 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// signingKey is one entry of the key ring. Retired keys only have the public half.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// TokenIssuer signs access tokens with one key and verifies them with any key of the
+// ring, picked by the "kid" header, so keys can be rotated without logging users out.
+type TokenIssuer struct {
+	signing *signingKey
+	keys    map[string]*signingKey
+}
+
+// NewHMACTokenIssuer signs with a shared secret. It is meant for development only:
+// nothing can be published to verify these tokens.
+func NewHMACTokenIssuer(secret string) *TokenIssuer {
+	key := &signingKey{kid: "hs256", method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &TokenIssuer{signing: key, keys: map[string]*signingKey{key.kid: key}}
+}
+
+// LoadTokenIssuer reads every "<kid>.pem" file in dir. Files may hold an RSA or Ed25519
+// private key, or only the public key of a retired one. Tokens are signed with
+// signingKID, which can be empty when the directory holds a single private key.
+func LoadTokenIssuer(dir, signingKID string) (*TokenIssuer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &TokenIssuer{keys: make(map[string]*signingKey)}
+	var privateKIDs []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := parseSigningKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		issuer.keys[kid] = key
+		if key.private != nil {
+			privateKIDs = append(privateKIDs, kid)
+		}
+	}
+
+	if signingKID == "" {
+		if len(privateKIDs) != 1 {
+			return nil, fmt.Errorf("%d private keys in %s, set the signing key id", len(privateKIDs), dir)
+		}
+		signingKID = privateKIDs[0]
+	}
+	issuer.signing = issuer.keys[signingKID]
+	if issuer.signing == nil || issuer.signing.private == nil {
+		return nil, fmt.Errorf("no private key %q in %s", signingKID, dir)
+	}
+	return issuer, nil
+}
+
+func (t *TokenIssuer) Sign(claims domain.AccessClaims) (string, error) {
+	token := jwt.NewWithClaims(t.signing.method, jwt.MapClaims{
+		"sub":  claims.UserID.String(),
+		"sid":  claims.SessionID.String(),
+		"role": string(claims.Role),
+		"iat":  claims.IssuedAt.Unix(),
+		"exp":  claims.ExpiresAt.Unix(),
+	})
+	token.Header["kid"] = t.signing.kid
+	return token.SignedString(t.signing.private)
+}
+
+// Verify checks the signature and expiry of a token, failing with ErrInvalidToken.
+func (t *TokenIssuer) Verify(tokenString string) (*domain.AccessClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := t.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// The algorithm comes from our key, never from the token
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	sub, _ := mapClaims.GetSubject()
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sid, _ := mapClaims["sid"].(string)
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	role, _ := mapClaims["role"].(string)
+
+	claims := &domain.AccessClaims{UserID: userID, SessionID: sessionID, Role: domain.Role(role)}
+	if iat, _ := mapClaims.GetIssuedAt(); iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	if exp, _ := mapClaims.GetExpirationTime(); exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys that verify our tokens, sorted by kid. Shared secrets
+// are never published.
+func (t *TokenIssuer) JWKS() []domain.JSONWebKey {
+	keys := make([]domain.JSONWebKey, 0, len(t.keys))
+	for _, key := range t.keys {
+		jwk := domain.JSONWebKey{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
+
+// Asymmetric reports whether tokens are signed with a private key rather than a shared secret.
+func (t *TokenIssuer) Asymmetric() bool {
+	_, hmac := t.signing.method.(*jwt.SigningMethodHMAC)
+	return !hmac
+}
+
+// parseJWK is the reverse of JWKS, for verifying tokens signed by someone else.
+func parseJWK(jwk domain.JSONWebKey) (*signingKey, error) {
+	key := &signingKey{kid: jwk.Kid}
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < 2048 || pub.E < 3 {
+			return nil, errors.New("RSA keys must have at least 2048 bits")
+		}
+		key.method, key.public = jwt.SigningMethodRS256, pub
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		key.method, key.public = jwt.SigningMethodEdDSA, ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+	if jwk.Alg != "" && jwk.Alg != key.method.Alg() {
+		return nil, fmt.Errorf("unsupported algorithm %q", jwk.Alg)
+	}
+	return key, nil
+}
+
+func parseSigningKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must have at least 2048 bits")
+	}
+	return key, nil
+}
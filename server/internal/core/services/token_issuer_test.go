@@ -0,0 +1,149 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func writeKey(t *testing.T, dir, kid string, key interface{}, public bool) {
+	t.Helper()
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testClaims() domain.AccessClaims {
+	now := time.Now()
+	return domain.AccessClaims{UserID: uuid.New(), SessionID: uuid.New(), Role: domain.RoleAdmin, IssuedAt: now, ExpiresAt: now.Add(time.Minute)}
+}
+
+func TestTokenIssuer_RotationKeepsOldTokensValid(t *testing.T) {
+	oldPub, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writeKey(t, dir, "old", oldKey, false)
+
+	before, err := LoadTokenIssuer(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims := testClaims()
+	oldToken, err := before.Sign(claims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Rotate: a new RSA key signs, the old one is kept only to verify
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, dir, "new", newKey, false)
+	writeKey(t, dir, "old", oldPub, true)
+	after, err := LoadTokenIssuer(dir, "new")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := after.Verify(oldToken)
+	if err != nil {
+		t.Fatalf("expected token signed with the retired key to verify, got %v", err)
+	}
+	if got.UserID != claims.UserID || got.SessionID != claims.SessionID || got.Role != claims.Role {
+		t.Errorf("unexpected claims %+v", got)
+	}
+
+	newToken, _ := after.Sign(testClaims())
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != "new" || parsed.Method.Alg() != "RS256" {
+		t.Errorf("expected RS256 token with kid new, got %v", parsed.Header)
+	}
+
+	jwks := after.JWKS()
+	if len(jwks) != 2 || jwks[0].Kid != "new" || jwks[0].Kty != "RSA" || jwks[1].Kid != "old" || jwks[1].Crv != "Ed25519" {
+		t.Errorf("unexpected JWKS %+v", jwks)
+	}
+}
+
+func TestTokenIssuer_RejectsForgedTokens(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writeKey(t, dir, "main", key, false)
+	issuer, err := LoadTokenIssuer(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// HS256 keyed with the public key must not pass as the EdDSA key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": uuid.NewString(), "sid": uuid.NewString(), "exp": time.Now().Add(time.Minute).Unix()})
+	forged.Header["kid"] = "main"
+	signed, _ := forged.SignedString([]byte(pub))
+	if _, err := issuer.Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected algorithm confusion to be rejected, got %v", err)
+	}
+
+	expired := testClaims()
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	token, _ := issuer.Sign(expired)
+	if _, err := issuer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected expired token to be rejected, got %v", err)
+	}
+
+	other, _ := LoadTokenIssuer(dir, "")
+	other.signing = &signingKey{kid: "main", method: jwt.SigningMethodEdDSA, private: ed25519.NewKeyFromSeed(make([]byte, 32))}
+	token, _ = other.Sign(testClaims())
+	if _, err := issuer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token signed with another key to be rejected, got %v", err)
+	}
+}
+
+func TestTokenIssuer_NeedsSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, b, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "a", a, false)
+	writeKey(t, dir, "b", b, false)
+
+	if _, err := LoadTokenIssuer(dir, ""); err == nil {
+		t.Error("expected an error choosing between two private keys")
+	}
+	if _, err := LoadTokenIssuer(dir, "missing"); err == nil {
+		t.Error("expected an error for an unknown signing key")
+	}
+	if _, err := LoadTokenIssuer(dir, "b"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHMACTokenIssuer_PublishesNothing(t *testing.T) {
+	issuer := NewHMACTokenIssuer("secret")
+	token, _ := issuer.Sign(testClaims())
+	if _, err := issuer.Verify(token); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(issuer.JWKS()) != 0 {
+		t.Error("expected the shared secret not to be published")
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+const (
+	// Time to type the code, or to set up the app when enrolment is required
+	twoFactorLoginTTL         = 10 * time.Minute
+	twoFactorLoginMaxAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+var (
+	ErrInvalidTwoFactorLogin = errors.New("this login has expired, please log in again")
+	ErrInvalidTwoFactorCode  = errors.New("the code is incorrect")
+	ErrTwoFactorRequired     = errors.New("two-factor authentication cannot be turned off for this account")
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already on")
+	ErrTwoFactorNotEnabled   = errors.New("two-factor authentication is off")
+	ErrNoTwoFactorEnrollment = errors.New("start setting up the authenticator app first")
+	ErrTooManyTwoFactorCodes = errors.New("too many incorrect codes, please try again later")
+)
+
+// TwoFactorService asks for a code from an authenticator app after the password, or
+// any other way of logging in, for users who turned it on and for roles that must.
+type TwoFactorService struct {
+	logins   ports.TwoFactorLoginRepository
+	recovery ports.RecoveryCodeRepository
+	userRepo ports.UserRepository
+	sessions ports.SessionService
+	// Counts wrong codes given to change the settings of a signed-in account
+	failures ports.RateLimitStore
+}
+
+func NewTwoFactorService(logins ports.TwoFactorLoginRepository, recovery ports.RecoveryCodeRepository, userRepo ports.UserRepository, sessions ports.SessionService, failures ports.RateLimitStore) *TwoFactorService {
+	return &TwoFactorService{logins: logins, recovery: recovery, userRepo: userRepo, sessions: sessions, failures: failures}
+}
+
+func (s *TwoFactorService) StartLogin(ctx context.Context, user *domain.User, userAgent, ip string) (*domain.LoginResult, error) {
+	if !user.TwoFactorEnabled() && !user.Role.RequiresTwoFactor() {
+		tokens, err := s.sessions.Start(ctx, user, userAgent, ip)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResult{User: user, Tokens: tokens}, nil
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	login := &domain.TwoFactorLogin{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(twoFactorLoginTTL).UTC(),
+	}
+	if err := s.logins.Create(ctx, login); err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{User: user, TwoFactor: &domain.TwoFactorChallenge{
+		Token:              raw,
+		ExpiresAt:          login.ExpiresAt,
+		EnrollmentRequired: !user.TwoFactorEnabled(),
+	}}, nil
+}
+
+func (s *TwoFactorService) CompleteLogin(ctx context.Context, challengeToken, code, userAgent, ip string) (*domain.LoginResult, error) {
+	login, user, err := s.challenge(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrNoTwoFactorEnrollment
+	}
+	if err := s.checkCode(ctx, user, code); err != nil {
+		return nil, s.failedAttempt(ctx, login, err)
+	}
+	return s.finishLogin(ctx, login, user, userAgent, ip)
+}
+
+func (s *TwoFactorService) EnrollForLogin(ctx context.Context, challengeToken string) (*domain.TwoFactorEnrollment, error) {
+	_, user, err := s.challenge(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	return s.enroll(ctx, user)
+}
+
+func (s *TwoFactorService) ConfirmForLogin(ctx context.Context, challengeToken, code, userAgent, ip string) (*domain.LoginResult, []string, error) {
+	login, user, err := s.challenge(ctx, challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, nil, ErrTwoFactorEnabled
+	}
+	codes, err := s.confirm(ctx, user, code)
+	if err != nil {
+		return nil, nil, s.failedAttempt(ctx, login, err)
+	}
+	result, err := s.finishLogin(ctx, login, user, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, codes, nil
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &domain.TwoFactorStatus{
+		Enabled:   user.TwoFactorEnabled(),
+		EnabledAt: user.TwoFactorEnabledAt,
+		Required:  user.Role.RequiresTwoFactor(),
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.recovery.CountUnused(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	return s.enroll(ctx, user)
+}
+
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	return s.confirm(ctx, user, code)
+}
+
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkAccountCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(ctx, user.ID)
+}
+
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role.RequiresTwoFactor() {
+		return ErrTwoFactorRequired
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.checkAccountCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.recovery.DeleteAll(ctx, user.ID)
+}
+
+// DeleteExpired removes login challenges nobody completed.
+func (s *TwoFactorService) DeleteExpired(ctx context.Context) error {
+	_, err := s.logins.DeleteExpired(ctx, time.Now())
+	return err
+}
+
+func (s *TwoFactorService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Two-factor login sweeper", s.DeleteExpired)
+}
+
+// challenge returns the pending login of the token and its user.
+func (s *TwoFactorService) challenge(ctx context.Context, token string) (*domain.TwoFactorLogin, *domain.User, error) {
+	if token == "" {
+		return nil, nil, ErrInvalidTwoFactorLogin
+	}
+	login, err := s.logins.GetByTokenHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidTwoFactorLogin
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(login.ExpiresAt) || login.Attempts >= twoFactorLoginMaxAttempts {
+		return nil, nil, ErrInvalidTwoFactorLogin
+	}
+
+	user, err := s.userRepo.GetByID(ctx, login.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidTwoFactorLogin
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Active() {
+		return nil, nil, ErrAccountInactive
+	}
+	return login, user, nil
+}
+
+// failedAttempt counts a wrong code against the login, which stops working after a few.
+func (s *TwoFactorService) failedAttempt(ctx context.Context, login *domain.TwoFactorLogin, err error) error {
+	if !errors.Is(err, ErrInvalidTwoFactorCode) {
+		return err
+	}
+	attempts, incErr := s.logins.IncrementAttempts(ctx, login.ID)
+	if incErr != nil {
+		return incErr
+	}
+	if attempts >= twoFactorLoginMaxAttempts {
+		return ErrInvalidTwoFactorLogin
+	}
+	return err
+}
+
+// checkAccountCode checks a code given to change the settings of a signed-in account.
+// Like a login, it stops accepting codes for a while after a few wrong ones. Every try
+// is counted up front, so parallel requests cannot get past the limit.
+func (s *TwoFactorService) checkAccountCode(ctx context.Context, user *domain.User, code string) error {
+	key := "two-factor-failures:" + user.ID.String()
+	tries, err := s.failures.Increment(ctx, key, twoFactorLoginTTL, time.Now())
+	if err != nil {
+		return err
+	}
+	if tries.Count > twoFactorLoginMaxAttempts {
+		return ErrTooManyTwoFactorCodes
+	}
+	if err := s.checkCode(ctx, user, code); err != nil {
+		return err
+	}
+	return s.failures.Delete(ctx, key)
+}
+
+func (s *TwoFactorService) finishLogin(ctx context.Context, login *domain.TwoFactorLogin, user *domain.User, userAgent, ip string) (*domain.LoginResult, error) {
+	if err := s.logins.Delete(ctx, login.ID); err != nil {
+		return nil, err
+	}
+	tokens, err := s.sessions.Start(ctx, user, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// checkCode accepts a code from the app, once, or an unused recovery code.
+func (s *TwoFactorService) checkCode(ctx context.Context, user *domain.User, code string) error {
+	if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		used, err := s.userRepo.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	if recoveryCode := normalizeRecoveryCode(code); recoveryCode != "" {
+		used, err := s.recovery.Use(ctx, user.ID, hashToken(recoveryCode), time.Now())
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *TwoFactorService) enroll(ctx context.Context, user *domain.User) (*domain.TwoFactorEnrollment, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	started, err := s.userRepo.StartTOTPEnrollment(ctx, user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, ErrTwoFactorEnabled
+	}
+	user.TOTPPendingSecret = secret
+
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	return &domain.TwoFactorEnrollment{Secret: secret, URI: totpURI(secret, account)}, nil
+}
+
+// confirm turns two-factor authentication on once the app shows it was set up right.
+func (s *TwoFactorService) confirm(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	if user.TOTPPendingSecret == "" {
+		return nil, ErrNoTwoFactorEnrollment
+	}
+	step, ok := matchTOTP(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	// Another enrolment may have replaced the secret the code was checked against
+	now := time.Now().UTC()
+	enabled, err := s.userRepo.EnableTOTP(ctx, user.ID, user.TOTPPendingSecret, step, now)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrNoTwoFactorEnrollment
+	}
+	user.TOTPSecret = user.TOTPPendingSecret
+	user.TOTPPendingSecret = ""
+	user.TOTPLastStep = step
+	user.TwoFactorEnabledAt = &now
+	return s.newRecoveryCodes(ctx, user.ID)
+}
+
+// newRecoveryCodes replaces the user's recovery codes. They are shown once; only
+// their hashes are kept.
+func (s *TwoFactorService) newRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i], hashes[i] = code, hashToken(code)
+	}
+	if err := s.recovery.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
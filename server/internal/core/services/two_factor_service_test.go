@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+type MockTwoFactorLoginRepo struct {
+	Logins map[string]*domain.TwoFactorLogin
+}
+
+func (m *MockTwoFactorLoginRepo) Create(ctx context.Context, login *domain.TwoFactorLogin) error {
+	copy := *login
+	m.Logins[login.TokenHash] = &copy
+	return nil
+}
+func (m *MockTwoFactorLoginRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.TwoFactorLogin, error) {
+	login, ok := m.Logins[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *login
+	return &copy, nil
+}
+func (m *MockTwoFactorLoginRepo) IncrementAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	for _, login := range m.Logins {
+		if login.ID == id {
+			login.Attempts++
+			return login.Attempts, nil
+		}
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+func (m *MockTwoFactorLoginRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	for hash, login := range m.Logins {
+		if login.ID == id {
+			delete(m.Logins, hash)
+		}
+	}
+	return nil
+}
+func (m *MockTwoFactorLoginRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	for hash, login := range m.Logins {
+		if login.ExpiresAt.Before(before) {
+			delete(m.Logins, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type MockRecoveryCodeRepo struct {
+	Codes []domain.RecoveryCode
+}
+
+func (m *MockRecoveryCodeRepo) Replace(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	m.DeleteAll(ctx, userID)
+	for _, hash := range hashes {
+		m.Codes = append(m.Codes, domain.RecoveryCode{ID: uuid.New(), UserID: userID, CodeHash: hash})
+	}
+	return nil
+}
+func (m *MockRecoveryCodeRepo) Use(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	for i := range m.Codes {
+		if m.Codes[i].UserID == userID && m.Codes[i].CodeHash == hash && m.Codes[i].UsedAt == nil {
+			m.Codes[i].UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *MockRecoveryCodeRepo) CountUnused(ctx context.Context, userID uuid.UUID) (int, error) {
+	n := 0
+	for _, code := range m.Codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+func (m *MockRecoveryCodeRepo) DeleteAll(ctx context.Context, userID uuid.UUID) error {
+	kept := m.Codes[:0]
+	for _, code := range m.Codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	m.Codes = kept
+	return nil
+}
+
+func newTestTwoFactorService(userRepo *MockUserRepo) *TwoFactorService {
+	sessions := NewSessionService(NewMockSessionRepo(), userRepo, NewHMACTokenIssuer("test-secret"))
+	return NewTwoFactorService(&MockTwoFactorLoginRepo{Logins: make(map[string]*domain.TwoFactorLogin)}, &MockRecoveryCodeRepo{}, userRepo, sessions, NewMockRateLimitStore())
+}
+
+// appCode returns the code an authenticator app shows, steps periods from now.
+func appCode(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid secret %q: %v", secret, err)
+	}
+	return totpCode(key, time.Now().Unix()/int64(totpPeriod.Seconds())+steps)
+}
+
+func TestTOTP_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	step, ok := matchTOTP(secret, "287082", time.Unix(59, 0))
+	if !ok || step != 1 {
+		t.Fatalf("expected the RFC 6238 test vector to match step 1, got %d, %v", step, ok)
+	}
+	if _, ok := matchTOTP(secret, "287082", time.Unix(59+3*30, 0)); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := matchTOTP("", "328482", time.Unix(59, 0)); ok {
+		t.Error("expected no code to match an empty secret")
+	}
+}
+
+func TestTwoFactor_AdminMustEnroll(t *testing.T) {
+	ctx := context.Background()
+	admin := &domain.User{ID: uuid.New(), Name: "Ayrton", Email: "admin@example.com", Role: domain.RoleAdmin, IsVerified: true}
+	userRepo := NewMockUserRepo(admin)
+	svc := newTestTwoFactorService(userRepo)
+
+	result, err := svc.StartLogin(ctx, admin, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Tokens != nil || result.TwoFactor == nil || !result.TwoFactor.EnrollmentRequired {
+		t.Fatalf("expected a challenge asking to enroll, got %+v", result)
+	}
+	token := result.TwoFactor.Token
+	if _, err := svc.CompleteLogin(ctx, token, "123456", "test", "127.0.0.1"); !errors.Is(err, ErrNoTwoFactorEnrollment) {
+		t.Errorf("expected ErrNoTwoFactorEnrollment, got %v", err)
+	}
+
+	enrollment, err := svc.EnrollForLogin(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uri, err := url.Parse(enrollment.URI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Query().Get("secret") != enrollment.Secret {
+		t.Fatalf("unexpected provisioning URI %q", enrollment.URI)
+	}
+	if _, _, err := svc.ConfirmForLogin(ctx, token, "000000", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+
+	result, codes, err := svc.ConfirmForLogin(ctx, token, appCode(t, enrollment.Secret, 0), "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Tokens == nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("expected a session and recovery codes, got %+v and %d codes", result, len(codes))
+	}
+	if u := userRepo.Users[admin.ID]; !u.TwoFactorEnabled() || u.TOTPPendingSecret != "" {
+		t.Errorf("expected two-factor authentication on, got %+v", u)
+	}
+	if _, err := svc.CompleteLogin(ctx, token, appCode(t, enrollment.Secret, 1), "test", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorLogin) {
+		t.Errorf("expected a finished challenge to stop working, got %v", err)
+	}
+	if err := svc.Disable(ctx, admin.ID, appCode(t, enrollment.Secret, 1)); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Errorf("expected admins not to turn it off, got %v", err)
+	}
+}
+
+func TestTwoFactor_LoginWithCodes(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient, IsVerified: true}
+	userRepo := NewMockUserRepo(client)
+	svc := newTestTwoFactorService(userRepo)
+
+	// Off by default for clients
+	result, err := svc.StartLogin(ctx, client, "test", "127.0.0.1")
+	if err != nil || result.Tokens == nil || result.TwoFactor != nil {
+		t.Fatalf("expected a session without a challenge, got %+v, %v", result, err)
+	}
+
+	enrollment, err := svc.Enroll(ctx, client.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	confirmCode := appCode(t, enrollment.Secret, 0)
+	codes, err := svc.Confirm(ctx, client.ID, confirmCode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	login := func() string {
+		result, err := svc.StartLogin(ctx, userRepo.Users[client.ID], "test", "127.0.0.1")
+		if err != nil || result.TwoFactor == nil || result.TwoFactor.EnrollmentRequired {
+			t.Fatalf("expected a challenge, got %+v, %v", result, err)
+		}
+		return result.TwoFactor.Token
+	}
+
+	// The code used to confirm cannot be replayed
+	token := login()
+	if _, err := svc.CompleteLogin(ctx, token, confirmCode, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected a used code to be rejected, got %v", err)
+	}
+	if result, err := svc.CompleteLogin(ctx, token, appCode(t, enrollment.Secret, 1), "test", "127.0.0.1"); err != nil || result.Tokens == nil {
+		t.Fatalf("expected a session, got %+v, %v", result, err)
+	}
+
+	// Recovery codes work once, however they are typed
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	if _, err := svc.CompleteLogin(ctx, login(), typed, "test", "127.0.0.1"); err != nil {
+		t.Fatalf("expected the recovery code to work, got %v", err)
+	}
+	if _, err := svc.CompleteLogin(ctx, login(), codes[0], "test", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected a used recovery code to be rejected, got %v", err)
+	}
+	if status, _ := svc.Status(ctx, client.ID); !status.Enabled || status.Required || status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// Too many wrong codes end the login
+	token = login()
+	for i := 0; i < twoFactorLoginMaxAttempts; i++ {
+		svc.CompleteLogin(ctx, token, "000000", "test", "127.0.0.1")
+	}
+	if _, err := svc.CompleteLogin(ctx, token, codes[1], "test", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorLogin) {
+		t.Errorf("expected the login to be locked, got %v", err)
+	}
+
+	if err := svc.Disable(ctx, client.ID, codes[1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := userRepo.Users[client.ID]; u.TwoFactorEnabled() || u.TOTPSecret != "" {
+		t.Errorf("expected two-factor authentication off, got %+v", u)
+	}
+}
+
+func TestTwoFactor_AccountCodeLimit(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient, IsVerified: true}
+	svc := newTestTwoFactorService(NewMockUserRepo(client))
+
+	enrollment, err := svc.Enroll(ctx, client.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	codes, err := svc.Confirm(ctx, client.ID, appCode(t, enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < twoFactorLoginMaxAttempts; i++ {
+		if _, err := svc.RegenerateRecoveryCodes(ctx, client.ID, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("expected ErrInvalidTwoFactorCode, got %v", err)
+		}
+	}
+	if err := svc.Disable(ctx, client.ID, codes[0]); !errors.Is(err, ErrTooManyTwoFactorCodes) {
+		t.Errorf("expected even a right code to be refused after too many wrong ones, got %v", err)
+	}
+}
+
+func TestTwoFactor_WritesOnlyItsOwnFields(t *testing.T) {
+	ctx := context.Background()
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient, IsVerified: true}
+	userRepo := NewMockUserRepo(client)
+	svc := newTestTwoFactorService(userRepo)
+
+	first, err := svc.Enroll(ctx, client.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := *userRepo.Users[client.ID]
+
+	// Meanwhile the client changes their phone and starts over on another device
+	userRepo.Users[client.ID].Phone = "1155550000"
+	second, err := svc.Enroll(ctx, client.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.confirm(ctx, &stale, appCode(t, first.Secret, 0)); !errors.Is(err, ErrNoTwoFactorEnrollment) {
+		t.Errorf("expected a replaced enrolment not to be confirmed, got %v", err)
+	}
+
+	if _, err := svc.Confirm(ctx, client.ID, appCode(t, second.Secret, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u := userRepo.Users[client.ID]
+	if !u.TwoFactorEnabled() || u.TOTPSecret != second.Secret || u.Phone != "1155550000" {
+		t.Errorf("expected the second secret on and the new phone kept, got %+v", u)
+	}
+	if _, err := svc.Enroll(ctx, client.ID); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Errorf("expected ErrTwoFactorEnabled, got %v", err)
+	}
+
+	userRepo.Users[client.ID].Name = "Ana María"
+	if err := svc.Disable(ctx, client.ID, appCode(t, second.Secret, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := userRepo.Users[client.ID]; u.TwoFactorEnabled() || u.TOTPSecret != "" || u.Name != "Ana María" || u.Phone != "1155550000" {
+		t.Errorf("expected two-factor authentication off and the profile kept, got %+v", u)
+	}
+}
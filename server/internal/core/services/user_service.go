@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailTaken      = errors.New("another user already has this email")
+	ErrInvalidMerge    = errors.New("these users cannot be merged")
+	ErrInvalidUser     = errors.New("invalid user")
+	ErrAccountInactive = errors.New("this account has been deactivated, please contact the shop")
+	ErrForbidden       = errors.New("you are not allowed")
+)
+
+// UserService is the admin side of user management.
+type UserService struct {
+	repo ports.UserRepository
+}
+
+func NewUserService(repo ports.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// Create adds a user on the shop's word, so they start verified.
+func (s *UserService) Create(ctx context.Context, input domain.NewUser) (*domain.User, error) {
+	user := &domain.User{
+		Name:       strings.TrimSpace(input.Name),
+		Email:      domain.NormalizeEmail(input.Email),
+		Phone:      strings.TrimSpace(input.Phone),
+		Role:       input.Role,
+		IsVerified: true,
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleClient
+	}
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+	if user.Role != domain.RoleClient {
+		if err := checkManagesUsers(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if user.Role != domain.RoleClient && input.Password == "" {
+		return nil, fmt.Errorf("%w: staff users need a password", ErrInvalidUser)
+	}
+	if input.Password != "" {
+		if len(input.Password) < minPasswordLength {
+			return nil, ErrPasswordTooShort
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = string(hash)
+	}
+
+	if err := s.ensureEmailFree(ctx, user.Email, uuid.Nil); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *UserService) Update(ctx context.Context, id uuid.UUID, changes domain.UserChanges) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Whoever can edit a staff account could log in as them, so only user managers may
+	if user.Role != domain.RoleClient {
+		if err := checkManagesUsers(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if changesContact(user, changes) {
+		if err := checkManagesClientsPrivate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if changes.Name != nil {
+		user.Name = strings.TrimSpace(*changes.Name)
+	}
+	if changes.Email != nil {
+		user.Email = domain.NormalizeEmail(*changes.Email)
+		if err := s.ensureEmailFree(ctx, user.Email, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if changes.Phone != nil {
+		user.Phone = strings.TrimSpace(*changes.Phone)
+	}
+	if changes.Role != nil && *changes.Role != user.Role {
+		if err := checkRoleChange(ctx, user); err != nil {
+			return nil, err
+		}
+		user.Role = *changes.Role
+	}
+	if changes.IsVerified != nil {
+		user.IsVerified = *changes.IsVerified
+	}
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetRole changes what the user may do. Nobody changes their own role, so the shop
+// cannot be left without an admin by mistake.
+func (s *UserService) SetRole(ctx context.Context, id uuid.UUID, role domain.Role) (*domain.User, error) {
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, role)
+	}
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.MergedIntoID != nil {
+		return nil, fmt.Errorf("%w: user was merged into %s", ErrInvalidMerge, user.MergedIntoID)
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if err := checkRoleChange(ctx, user); err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Deactivate stops the user from logging in and booking. Their history is kept.
+func (s *UserService) Deactivate(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return s.setActive(ctx, id, false)
+}
+
+func (s *UserService) Reactivate(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return s.setActive(ctx, id, true)
+}
+
+func (s *UserService) setActive(ctx context.Context, id uuid.UUID, active bool) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.MergedIntoID != nil {
+		return nil, fmt.Errorf("%w: user was merged into %s", ErrInvalidMerge, user.MergedIntoID)
+	}
+	if actor := domain.ActorFromContext(ctx); actor != nil && actor.ID == user.ID && !active {
+		return nil, fmt.Errorf("%w: you cannot deactivate your own account", ErrInvalidUser)
+	}
+
+	if active {
+		user.DeactivatedAt = nil
+	} else if user.DeactivatedAt == nil {
+		now := time.Now()
+		user.DeactivatedAt = &now
+	}
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Merge folds duplicate into survivor: appointments, notes, history, logins and
+// sessions move over, and duplicate is deactivated, pointing at survivor.
+func (s *UserService) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID) (*domain.User, error) {
+	if survivorID == duplicateID {
+		return nil, fmt.Errorf("%w: a user cannot be merged into itself", ErrInvalidMerge)
+	}
+	// Checked on the locked users, so a concurrent merge cannot slip in between
+	err := s.repo.Merge(ctx, survivorID, duplicateID, func(survivor, duplicate *domain.User) error {
+		if survivor.MergedIntoID != nil || duplicate.MergedIntoID != nil {
+			return fmt.Errorf("%w: one of the users was already merged", ErrInvalidMerge)
+		}
+		if survivor.Role != duplicate.Role {
+			return fmt.Errorf("%w: users have different roles", ErrInvalidMerge)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, survivorID)
+}
+
+func (s *UserService) ensureEmailFree(ctx context.Context, email string, self uuid.UUID) error {
+	if email == "" {
+		return nil
+	}
+	existing, err := s.repo.GetByEmail(ctx, email)
+	if err == nil && existing.ID != self {
+		return ErrEmailTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// checkManagesUsers returns ErrForbidden unless the actor may manage users. Calls
+// without an actor come from the server itself and are allowed.
+func checkManagesUsers(ctx context.Context) error {
+	if actor := domain.ActorFromContext(ctx); actor != nil && !actor.Can(domain.PermManageUsers) {
+		return fmt.Errorf("%w to manage staff accounts", ErrForbidden)
+	}
+	return nil
+}
+
+// checkManagesClientsPrivate returns ErrForbidden unless the actor may change how a
+// client is reached and whether they are verified, which is enough to take over the account.
+func checkManagesClientsPrivate(ctx context.Context) error {
+	if actor := domain.ActorFromContext(ctx); actor != nil && !actor.Can(domain.PermManageClientsPrivate) {
+		return fmt.Errorf("%w to change a client's email, phone or verification", ErrForbidden)
+	}
+	return nil
+}
+
+// changesContact reports whether changes would set a different email, phone or
+// verification; forms resend unchanged fields, which anyone editing the client may do.
+func changesContact(user *domain.User, changes domain.UserChanges) bool {
+	return (changes.Email != nil && domain.NormalizeEmail(*changes.Email) != user.Email) ||
+		(changes.Phone != nil && strings.TrimSpace(*changes.Phone) != user.Phone) ||
+		(changes.IsVerified != nil && *changes.IsVerified != user.IsVerified)
+}
+
+func checkRoleChange(ctx context.Context, user *domain.User) error {
+	if err := checkManagesUsers(ctx); err != nil {
+		return err
+	}
+	if actor := domain.ActorFromContext(ctx); actor != nil && actor.ID == user.ID {
+		return fmt.Errorf("%w: you cannot change your own role", ErrInvalidUser)
+	}
+	return nil
+}
+
+func validateUser(user *domain.User) error {
+	if user.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidUser)
+	}
+	if user.Email == "" && user.Phone == "" {
+		return fmt.Errorf("%w: email or phone is required", ErrInvalidUser)
+	}
+	if user.Email != "" {
+		if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+			return fmt.Errorf("%w: invalid email", ErrInvalidUser)
+		}
+	}
+	if !user.Role.Valid() {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidUser, user.Role)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+)
+
+func TestUserService_CreateRejectsEmailInAnyCase(t *testing.T) {
+	existing := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient}
+	svc := NewUserService(NewMockUserRepo(existing))
+
+	_, err := svc.Create(context.Background(), domain.NewUser{Name: "Ana B", Email: " ANA@Example.com "})
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+
+	for _, input := range []domain.NewUser{{Email: "beto@example.com"}, {Name: "Beto"}, {Name: "Beto", Email: "beto"}} {
+		if _, err := svc.Create(context.Background(), input); !errors.Is(err, ErrInvalidUser) {
+			t.Errorf("%+v: expected ErrInvalidUser, got %v", input, err)
+		}
+	}
+
+	user, err := svc.Create(context.Background(), domain.NewUser{Name: "Beto", Email: "Beto@Example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Email != "beto@example.com" || !user.IsVerified || user.Role != domain.RoleClient {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestUserService_UpdateKeepsUnsetFields(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Phone: "1155550000", Role: domain.RoleClient}
+	svc := NewUserService(NewMockUserRepo(user))
+
+	name := "Ana María"
+	updated, err := svc.Update(context.Background(), user.ID, domain.UserChanges{Name: &name})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Name != name || updated.Email != "ana@example.com" || updated.Phone != "1155550000" {
+		t.Errorf("unexpected user %+v", updated)
+	}
+}
+
+func TestUserService_DeactivatedUserCannotBook(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient}
+	users := NewMockUserRepo(user)
+	svc := NewUserService(users)
+
+	deactivated, err := svc.Deactivate(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deactivated.Active() {
+		t.Fatal("expected user to be inactive")
+	}
+
+	appts := NewAppointmentService(&MockAppointmentRepo{}, nil, nil, users, nil, nil, nil, nil, nil, nil, nil, nil)
+	_, err = appts.CreateAppointmentForClient(context.Background(), user.ID, domain.BookingRequest{
+		StartTime: time.Now().Add(48 * time.Hour),
+	})
+	if !errors.Is(err, ErrAccountInactive) {
+		t.Fatalf("expected ErrAccountInactive, got %v", err)
+	}
+
+	if _, err := svc.Reactivate(context.Background(), user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !users.Users[user.ID].Active() {
+		t.Error("expected user to be active again")
+	}
+}
+
+func TestUserService_Merge(t *testing.T) {
+	survivor := &domain.User{ID: uuid.New(), Name: "Ana", Phone: "1155550000", Role: domain.RoleClient, NoShowCount: 1}
+	duplicate := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient, NoShowCount: 2}
+	admin := &domain.User{ID: uuid.New(), Name: "Ayrton", Email: "ayrton@example.com", Role: domain.RoleAdmin}
+	svc := NewUserService(NewMockUserRepo(survivor, duplicate, admin))
+
+	if _, err := svc.Merge(context.Background(), survivor.ID, survivor.ID); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("expected ErrInvalidMerge merging a user into itself, got %v", err)
+	}
+	if _, err := svc.Merge(context.Background(), survivor.ID, admin.ID); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("expected ErrInvalidMerge merging users with different roles, got %v", err)
+	}
+
+	merged, err := svc.Merge(context.Background(), survivor.ID, duplicate.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Email != "ana@example.com" || merged.NoShowCount != 3 {
+		t.Errorf("unexpected survivor %+v", merged)
+	}
+	if duplicate.MergedIntoID == nil || *duplicate.MergedIntoID != survivor.ID || duplicate.Active() {
+		t.Errorf("expected duplicate to point at survivor and be inactive, got %+v", duplicate)
+	}
+
+	if _, err := svc.Merge(context.Background(), survivor.ID, duplicate.ID); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("expected ErrInvalidMerge merging an already merged user, got %v", err)
+	}
+}
+
+func TestUserService_OnlyUserManagersHandleStaff(t *testing.T) {
+	admin := &domain.User{ID: uuid.New(), Name: "Ayrton", Email: "ayrton@example.com", Role: domain.RoleAdmin}
+	receptionist := &domain.User{ID: uuid.New(), Name: "Rocío", Email: "rocio@example.com", Role: domain.RoleReceptionist}
+	client := &domain.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com", Role: domain.RoleClient}
+	users := NewMockUserRepo(admin, receptionist, client)
+	svc := NewUserService(users)
+	asReceptionist := domain.ContextWithActor(context.Background(), domain.Actor{ID: receptionist.ID, Role: receptionist.Role})
+	asAdmin := domain.ContextWithActor(context.Background(), domain.Actor{ID: admin.ID, Role: admin.Role})
+
+	// Receptionists look after clients, but cannot touch staff accounts or roles
+	name := "Ana María"
+	if _, err := svc.Update(asReceptionist, client.ID, domain.UserChanges{Name: &name}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, phone, verified := "ana@otro.example.com", "1155559999", true
+	for _, changes := range []domain.UserChanges{{Email: &other}, {Phone: &phone}, {IsVerified: &verified}} {
+		if _, err := svc.Update(asReceptionist, client.ID, changes); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%+v: expected ErrForbidden changing how a client is reached, got %v", changes, err)
+		}
+	}
+	if _, err := svc.Update(asAdmin, client.ID, domain.UserChanges{Phone: &phone}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unchanged := "ana@example.com"
+	if _, err := svc.Update(asReceptionist, client.ID, domain.UserChanges{Name: &name, Email: &unchanged}); err != nil {
+		t.Errorf("expected resending the same email to be allowed, got %v", err)
+	}
+	email := "rocio@evil.example.com"
+	if _, err := svc.Update(asReceptionist, admin.ID, domain.UserChanges{Email: &email}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden editing an admin, got %v", err)
+	}
+	if _, err := svc.SetRole(asReceptionist, client.ID, domain.RoleAdmin); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden setting a role, got %v", err)
+	}
+	if _, err := svc.Create(asReceptionist, domain.NewUser{Name: "Beto", Email: "beto@example.com", Role: domain.RoleBarber, Password: "password123"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden creating staff, got %v", err)
+	}
+
+	updated, err := svc.SetRole(asAdmin, client.ID, domain.RoleBarber)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Role != domain.RoleBarber || users.Users[client.ID].Role != domain.RoleBarber {
+		t.Errorf("expected the user to be a barber, got %q", updated.Role)
+	}
+	if _, err := svc.SetRole(asAdmin, admin.ID, domain.RoleClient); err == nil {
+		t.Error("expected an admin not to change their own role")
+	}
+	if _, err := svc.SetRole(asAdmin, client.ID, "owner"); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidClaim         = errors.New("invalid or already used claim link")
+	ErrClaimExpired         = errors.New("claim link has expired")
+	ErrInvalidWaitlistEntry = errors.New("invalid waitlist entry")
+	ErrSlotsAvailable       = errors.New("there are free slots for that day, book one instead")
+)
+
+type WaitlistService struct {
+	repo         ports.WaitlistRepository
+	apptRepo     ports.AppointmentRepository
+	holdRepo     ports.SlotHoldRepository
+	tx           ports.Transactor
+	settingsRepo ports.SettingsRepository
+	availSvc     ports.AvailabilityService
+	apptSvc      ports.AppointmentService
+	msgSvc       ports.MessagingService
+	frontendURL  string
+	loc          *time.Location // Where the shop is, for the times told to clients
+}
+
+// NewWaitlistService builds the service. holdRepo may be nil, in which case slot holds
+// do not keep a freed slot from being offered, nor offered slots from being booked.
+// loc defaults to UTC.
+func NewWaitlistService(repo ports.WaitlistRepository, apptRepo ports.AppointmentRepository, holdRepo ports.SlotHoldRepository, tx ports.Transactor, settingsRepo ports.SettingsRepository, availSvc ports.AvailabilityService, apptSvc ports.AppointmentService, msgSvc ports.MessagingService, frontendURL string, loc *time.Location) *WaitlistService {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &WaitlistService{
+		repo:         repo,
+		apptRepo:     apptRepo,
+		holdRepo:     holdRepo,
+		tx:           tx,
+		settingsRepo: settingsRepo,
+		availSvc:     availSvc,
+		apptSvc:      apptSvc,
+		msgSvc:       msgSvc,
+		frontendURL:  frontendURL,
+		loc:          loc,
+	}
+}
+
+func (s *WaitlistService) Join(ctx context.Context, entry *domain.WaitlistEntry) error {
+	date, err := time.Parse("2006-01-02", entry.Date)
+	if err != nil {
+		return fmt.Errorf("%w: invalid date format, use YYYY-MM-DD", ErrInvalidWaitlistEntry)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if date.Before(today) {
+		return fmt.Errorf("%w: cannot join the waitlist for a past date", ErrInvalidWaitlistEntry)
+	}
+	for _, t := range []string{entry.WindowStart, entry.WindowEnd} {
+		if t == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", t); err != nil {
+			return fmt.Errorf("%w: invalid time window format, use HH:MM", ErrInvalidWaitlistEntry)
+		}
+	}
+	if entry.WindowStart != "" && entry.WindowEnd != "" && entry.WindowStart > entry.WindowEnd {
+		return fmt.Errorf("%w: window_start must be before window_end", ErrInvalidWaitlistEntry)
+	}
+
+	// The waitlist is only for days, or windows, that are full, not for closed days
+	open, err := s.availSvc.IsOpen(ctx, date)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return fmt.Errorf("%w: the shop is closed that day", ErrInvalidWaitlistEntry)
+	}
+	slots, err := s.availSvc.GetAvailableSlots(ctx, date, "")
+	if err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if slot.After(time.Now()) && entry.Accepts(slot.Format("15:04")) {
+			return ErrSlotsAvailable
+		}
+	}
+
+	entry.Status = domain.WaitlistWaiting
+	entry.OfferedStart = nil
+	entry.OfferExpiresAt = nil
+	entry.ClaimTokenHash = ""
+	entry.AppointmentID = nil
+	return s.repo.Create(ctx, entry)
+}
+
+func (s *WaitlistService) ListByDate(ctx context.Context, date string) ([]domain.WaitlistEntry, error) {
+	return s.repo.ListByDate(ctx, date)
+}
+
+func (s *WaitlistService) OfferSlot(ctx context.Context, start time.Time) error {
+	start = start.UTC()
+	if !start.After(time.Now()) {
+		return nil
+	}
+
+	free, err := s.slotFree(ctx, start)
+	if err != nil || !free {
+		return err
+	}
+
+	entries, err := s.repo.ListByDate(ctx, start.Format("2006-01-02"), domain.WaitlistWaiting, domain.WaitlistOffered)
+	if err != nil {
+		return err
+	}
+
+	var candidates []*domain.WaitlistEntry
+	for i := range entries {
+		e := &entries[i]
+		if e.Status == domain.WaitlistOffered {
+			if e.OfferedStart != nil && e.OfferedStart.Equal(start) {
+				return nil // Already being offered to someone
+			}
+			continue
+		}
+		if e.Accepts(start.Format("15:04")) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	claimMinutes := settings.WaitlistClaimMinutes
+	if claimMinutes <= 0 {
+		claimMinutes = domain.DefaultSettings().WaitlistClaimMinutes
+	}
+
+	raw, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().UTC().Add(time.Duration(claimMinutes) * time.Minute)
+
+	// The slot is held for the offer, so nobody else books it before the client can.
+	// The claim token is the hold token, which lets the claim book it.
+	if s.holdRepo != nil {
+		hold := &domain.SlotHold{TokenHash: hash, StartTime: start, EndTime: start.Add(1 * time.Hour), ExpiresAt: expiresAt}
+		if err := s.holdRepo.Create(ctx, hold); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return nil // Someone started booking it meanwhile
+			}
+			return err
+		}
+	}
+
+	// The first in line not changed meanwhile, e.g. offered another slot, gets it
+	var next *domain.WaitlistEntry
+	for _, e := range candidates {
+		e.Status = domain.WaitlistOffered
+		e.OfferedStart = &start
+		e.OfferExpiresAt = &expiresAt
+		e.ClaimTokenHash = hash
+		offered, err := s.repo.UpdateIf(ctx, e, domain.WaitlistWaiting)
+		if err != nil {
+			s.releaseHold(ctx, hash)
+			return err
+		}
+		if offered {
+			next = e
+			break
+		}
+	}
+	if next == nil {
+		s.releaseHold(ctx, hash)
+		return nil
+	}
+
+	// Notify client (best-effort)
+	if phone := normalizePhone(next.ClientPhone); s.msgSvc != nil && phone != "" {
+		link := s.frontendURL + "/waitlist/claim?token=" + raw
+		// The slot is already wall clock, the expiry is a real instant
+		msg := fmt.Sprintf("¡Se liberó un turno el %s! Reservalo antes de las %s desde este link: %s",
+			start.Format("2006-01-02 15:04"), expiresAt.In(s.loc).Format("15:04"), link)
+		if err := s.msgSvc.SendWhatsApp(ctx, phone, msg); err != nil {
+			log.Printf("Failed to send waitlist offer to %s: %v", phone, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *WaitlistService) Claim(ctx context.Context, token string) (*domain.Appointment, error) {
+	entry, err := s.repo.GetByClaimTokenHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidClaim
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != domain.WaitlistOffered || entry.OfferedStart == nil {
+		return nil, ErrInvalidClaim
+	}
+	if entry.OfferExpiresAt != nil && time.Now().After(*entry.OfferExpiresAt) {
+		return nil, ErrClaimExpired
+	}
+
+	notes := "Reservado desde lista de espera"
+	if entry.Service != "" {
+		notes += ": " + entry.Service
+	}
+	// The entry and its appointment are stored together, so neither is left without
+	// the other
+	var appt *domain.Appointment
+	err = inTransaction(ctx, s.tx, func(ctx context.Context) error {
+		// Taken first, so of concurrent claims, or a claim and the expiry, only one wins
+		entry.Status = domain.WaitlistBooked
+		entry.ClaimTokenHash = ""
+		claimed, err := s.repo.UpdateIf(ctx, entry, domain.WaitlistOffered)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrInvalidClaim
+		}
+
+		appt, err = s.apptSvc.CreateAppointment(ctx, domain.BookingRequest{
+			ClientName:  entry.ClientName,
+			ClientEmail: entry.ClientEmail,
+			ClientPhone: entry.ClientPhone,
+			StartTime:   *entry.OfferedStart,
+			Service:     entry.Service,
+			Notes:       notes,
+			HoldToken:   token, // The offer's hold
+			// The claim link was sent to the phone by WhatsApp
+			VerifiedVia: domain.ChannelWhatsApp,
+		})
+		if err != nil {
+			return err
+		}
+		entry.AppointmentID = &appt.ID
+		return s.repo.Update(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appt, nil
+}
+
+func (s *WaitlistService) ExpireOffers(ctx context.Context) error {
+	entries, err := s.repo.ListExpiredOffers(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	// One entry failing must not keep the slots of the others from moving on
+	for i := range entries {
+		e := &entries[i]
+		slot := *e.OfferedStart
+		hash := e.ClaimTokenHash
+		e.Status = domain.WaitlistExpired
+		e.ClaimTokenHash = ""
+		expired, err := s.repo.UpdateIf(ctx, e, domain.WaitlistOffered)
+		if err != nil {
+			log.Printf("Failed to expire waitlist offer %s: %v", e.ID, err)
+			continue
+		}
+		if !expired {
+			continue // Claimed meanwhile
+		}
+		s.releaseHold(ctx, hash)
+		if err := s.OfferSlot(ctx, slot); err != nil {
+			log.Printf("Failed to offer %s to the next in the waitlist: %v", slot.Format("2006-01-02 15:04"), err)
+		}
+	}
+	return nil
+}
+
+// RunSweeper calls ExpireOffers every interval until ctx is cancelled.
+func (s *WaitlistService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "Waitlist sweeper", s.ExpireOffers)
+}
+
+// releaseHold deletes the hold of an offer, if any (best-effort: it expires anyway).
+func (s *WaitlistService) releaseHold(ctx context.Context, hash string) {
+	if s.holdRepo == nil || hash == "" {
+		return
+	}
+	hold, err := s.holdRepo.GetByTokenHash(ctx, hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err == nil {
+		err = s.holdRepo.Delete(ctx, hold.ID)
+	}
+	if err != nil {
+		log.Printf("Failed to release the hold of a waitlist offer: %v", err)
+	}
+}
+
+// slotFree reports whether no active appointment, nor a client filling the booking
+// form, holds the one-hour slot at start.
+func (s *WaitlistService) slotFree(ctx context.Context, start time.Time) (bool, error) {
+	end := start.Add(1 * time.Hour)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	appts, err := s.apptRepo.ListByDateRange(ctx, day, day.Add(24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	for _, a := range appts {
+		if !a.Status.OccupiesSlot() {
+			continue
+		}
+		if a.StartTime.Before(end) && a.EndTime.After(start) {
+			return false, nil
+		}
+	}
+
+	if s.holdRepo == nil {
+		return true, nil
+	}
+	holds, err := s.holdRepo.ListActiveByDateRange(ctx, day, day.Add(24*time.Hour), time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	for _, h := range holds {
+		if h.StartTime.Before(end) && h.EndTime.After(start) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/renatowilliner/barberia_ayrton/server/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+// MockWaitlistRepo keeps entries in the order they joined.
+type MockWaitlistRepo struct {
+	Entries    []*domain.WaitlistEntry
+	FailUpdate map[uuid.UUID]bool
+}
+
+func (m *MockWaitlistRepo) Create(ctx context.Context, entry *domain.WaitlistEntry) error {
+	entry.ID = uuid.New()
+	copy := *entry
+	m.Entries = append(m.Entries, &copy)
+	return nil
+}
+func (m *MockWaitlistRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.WaitlistEntry, error) {
+	for _, e := range m.Entries {
+		if e.ID == id {
+			copy := *e
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockWaitlistRepo) GetByClaimTokenHash(ctx context.Context, hash string) (*domain.WaitlistEntry, error) {
+	for _, e := range m.Entries {
+		if hash != "" && e.ClaimTokenHash == hash {
+			copy := *e
+			return &copy, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+func (m *MockWaitlistRepo) ListByDate(ctx context.Context, date string, statuses ...domain.WaitlistStatus) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	for _, e := range m.Entries {
+		if e.Date != date {
+			continue
+		}
+		for _, status := range statuses {
+			if e.Status == status {
+				entries = append(entries, *e)
+				break
+			}
+		}
+		if len(statuses) == 0 {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+func (m *MockWaitlistRepo) ListExpiredOffers(ctx context.Context, now time.Time) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	for _, e := range m.Entries {
+		if e.Status == domain.WaitlistOffered && e.OfferExpiresAt != nil && e.OfferExpiresAt.Before(now) {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+func (m *MockWaitlistRepo) Update(ctx context.Context, entry *domain.WaitlistEntry) error {
+	if m.FailUpdate[entry.ID] {
+		return errors.New("update failed")
+	}
+	for i, e := range m.Entries {
+		if e.ID == entry.ID {
+			copy := *entry
+			m.Entries[i] = &copy
+		}
+	}
+	return nil
+}
+
+func (m *MockWaitlistRepo) UpdateIf(ctx context.Context, entry *domain.WaitlistEntry, status domain.WaitlistStatus) (bool, error) {
+	for _, e := range m.Entries {
+		if e.ID == entry.ID && e.Status != status {
+			return false, nil
+		}
+	}
+	return true, m.Update(ctx, entry)
+}
+
+func (m *MockWaitlistRepo) byName(name string) *domain.WaitlistEntry {
+	for _, e := range m.Entries {
+		if e.ClientName == name {
+			return e
+		}
+	}
+	return nil
+}
+
+type waitlistFixture struct {
+	svc    *WaitlistService
+	repo   *MockWaitlistRepo
+	appts  *MockAppointmentRepo
+	holds  *MockSlotHoldRepo
+	sender *MockSender
+	// The only slot of the day, 10:00 two days from now
+	slot time.Time
+}
+
+func newWaitlistFixture() *waitlistFixture {
+	day := time.Now().UTC().Add(48 * time.Hour).Truncate(24 * time.Hour)
+	f := &waitlistFixture{
+		repo:   &MockWaitlistRepo{},
+		appts:  &MockAppointmentRepo{Stored: make(map[uuid.UUID]*domain.Appointment)},
+		holds:  &MockSlotHoldRepo{},
+		sender: &MockSender{},
+		slot:   day.Add(10 * time.Hour),
+	}
+	f.appts.ListByDateRangeFunc = func(ctx context.Context, start, end time.Time) ([]domain.Appointment, error) {
+		var appts []domain.Appointment
+		for _, a := range f.appts.Stored {
+			if !a.StartTime.Before(start) && a.StartTime.Before(end) {
+				appts = append(appts, *a)
+			}
+		}
+		return appts, nil
+	}
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "10:00", EndTime: "10:00", SlotDuration: 60}, nil
+	}}
+	settings := &MockSettingsRepo{Settings: &domain.Settings{WaitlistClaimMinutes: 30}}
+	availSvc := NewAvailabilityService(avail, f.appts, f.holds, nil, nil)
+	apptSvc := NewAppointmentService(f.appts, avail, nil, NewMockUserRepo(), f.holds, nil, nil, settings, nil, nil, &MockCalendar{}, f.sender)
+	f.svc = NewWaitlistService(f.repo, f.appts, f.holds, &MockTransactor{}, settings, availSvc, apptSvc, f.sender, "https://example.com", nil)
+	return f
+}
+
+// book fills the slot and returns the appointment.
+func (f *waitlistFixture) book() *domain.Appointment {
+	appt := &domain.Appointment{ID: uuid.New(), StartTime: f.slot, EndTime: f.slot.Add(time.Hour), Status: domain.StatusConfirmed}
+	f.appts.Stored[appt.ID] = appt
+	return appt
+}
+
+func (f *waitlistFixture) join(t *testing.T, name, phone string) {
+	t.Helper()
+	err := f.svc.Join(context.Background(), &domain.WaitlistEntry{ClientName: name, ClientPhone: phone, Date: f.slot.Format("2006-01-02")})
+	if err != nil {
+		t.Fatalf("unexpected error joining %s: %v", name, err)
+	}
+}
+
+// claimToken is the token in the last claim link sent.
+func (f *waitlistFixture) claimToken() string {
+	return regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(f.sender.WhatsApp)[1]
+}
+
+func TestWaitlist_JoinOnlyWhenFull(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	date := f.slot.Format("2006-01-02")
+
+	if err := f.svc.Join(ctx, &domain.WaitlistEntry{ClientName: "Ana", Date: date}); !errors.Is(err, ErrSlotsAvailable) {
+		t.Fatalf("expected ErrSlotsAvailable while the slot is free, got %v", err)
+	}
+	if err := f.svc.Join(ctx, &domain.WaitlistEntry{ClientName: "Ana", Date: date, WindowStart: "12:00"}); err != nil {
+		t.Errorf("expected to join for a window with no free slot, got %v", err)
+	}
+
+	f.book()
+	for _, entry := range []*domain.WaitlistEntry{
+		{Date: "tomorrow"},
+		{Date: "2001-01-01"},
+		{Date: date, WindowStart: "10"},
+		{Date: date, WindowStart: "12:00", WindowEnd: "11:00"},
+	} {
+		if err := f.svc.Join(ctx, entry); !errors.Is(err, ErrInvalidWaitlistEntry) {
+			t.Errorf("%+v: expected ErrInvalidWaitlistEntry, got %v", entry, err)
+		}
+	}
+
+	f.join(t, "Bob", "1155550000")
+	if e := f.repo.byName("Bob"); e.Status != domain.WaitlistWaiting {
+		t.Errorf("expected a waiting entry, got %+v", e)
+	}
+}
+
+func TestWaitlist_OfferClaimAndRollover(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	appt := f.book()
+	f.join(t, "Ana", "1155550000")
+	f.join(t, "Bob", "1166660000")
+
+	// A slot still taken is not offered
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.repo.byName("Ana").Status != domain.WaitlistWaiting {
+		t.Fatal("expected no offer while the slot is booked")
+	}
+
+	appt.Status = domain.StatusCancelled
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ana := f.repo.byName("Ana")
+	if ana.Status != domain.WaitlistOffered || !ana.OfferedStart.Equal(f.slot) || f.repo.byName("Bob").Status != domain.WaitlistWaiting {
+		t.Fatalf("expected the slot offered to the first in line, got %+v", ana)
+	}
+	anaToken := f.claimToken()
+
+	// Offering it again does not skip ahead to the next in line
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.repo.byName("Bob").Status != domain.WaitlistWaiting {
+		t.Fatal("expected one offer per slot at a time")
+	}
+
+	// Nobody claims it in time, so it rolls over to the next in line
+	past := time.Now().Add(-time.Minute)
+	ana.OfferExpiresAt = &past
+	if err := f.svc.ExpireOffers(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.repo.byName("Ana").Status != domain.WaitlistExpired || f.repo.byName("Bob").Status != domain.WaitlistOffered {
+		t.Fatalf("expected the offer to move on, got %+v and %+v", f.repo.byName("Ana"), f.repo.byName("Bob"))
+	}
+	if _, err := f.svc.Claim(ctx, anaToken); !errors.Is(err, ErrInvalidClaim) {
+		t.Errorf("expected the expired link to stop working, got %v", err)
+	}
+
+	booked, err := f.svc.Claim(ctx, f.claimToken())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bob := f.repo.byName("Bob")
+	if !booked.StartTime.Equal(f.slot) || bob.Status != domain.WaitlistBooked || *bob.AppointmentID != booked.ID {
+		t.Errorf("expected the claim to book the slot, got %+v for %+v", booked, bob)
+	}
+}
+
+func TestWaitlist_ClaimExpired(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	f.book()
+	f.join(t, "Ana", "1155550000")
+	for _, a := range f.appts.Stored {
+		a.Status = domain.StatusCancelled
+	}
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	f.repo.byName("Ana").OfferExpiresAt = &past
+	if _, err := f.svc.Claim(ctx, f.claimToken()); !errors.Is(err, ErrClaimExpired) {
+		t.Errorf("expected ErrClaimExpired, got %v", err)
+	}
+}
+
+func TestWaitlist_HeldSlotIsNotOffered(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	appt := f.book()
+	f.join(t, "Ana", "1155550000")
+
+	appt.Status = domain.StatusCancelled
+	f.holds.Holds = []domain.SlotHold{{ID: uuid.New(), StartTime: f.slot, EndTime: f.slot.Add(time.Hour)}}
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.repo.byName("Ana").Status != domain.WaitlistWaiting {
+		t.Error("expected no offer while someone holds the slot")
+	}
+}
+
+func TestWaitlist_ExpireOffersContinuesAfterAFailure(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	for _, name := range []string{"Ana", "Bob"} {
+		start := f.slot
+		f.repo.Entries = append(f.repo.Entries, &domain.WaitlistEntry{
+			ID: uuid.New(), ClientName: name, Date: f.slot.Format("2006-01-02"),
+			Status: domain.WaitlistOffered, OfferedStart: &start, OfferExpiresAt: &past,
+		})
+	}
+	f.repo.FailUpdate = map[uuid.UUID]bool{f.repo.byName("Ana").ID: true}
+
+	if err := f.svc.ExpireOffers(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.repo.byName("Bob").Status != domain.WaitlistExpired {
+		t.Error("expected the other offers to be expired")
+	}
+}
+
+func TestWaitlist_OfferHoldsTheSlot(t *testing.T) {
+	f := newWaitlistFixture()
+	ctx := context.Background()
+	appt := f.book()
+	f.join(t, "Ana", "1155550000")
+	appt.Status = domain.StatusCancelled
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.holds.Holds) != 1 || !f.holds.Holds[0].ExpiresAt.Equal(*f.repo.byName("Ana").OfferExpiresAt) {
+		t.Fatalf("expected the slot held until the offer expires, got %+v", f.holds.Holds)
+	}
+
+	// Nobody else books it while it is offered
+	_, err := f.svc.apptSvc.CreateAppointment(ctx, domain.BookingRequest{
+		ClientName: "Eva", ClientPhone: "1177770000", StartTime: f.slot, VerifiedVia: domain.ChannelWhatsApp,
+	})
+	if !errors.Is(err, ErrSlotHeld) {
+		t.Fatalf("expected ErrSlotHeld, got %v", err)
+	}
+
+	// Expiring the offer releases it
+	past := time.Now().Add(-time.Minute)
+	f.repo.byName("Ana").OfferExpiresAt = &past
+	if err := f.svc.ExpireOffers(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.holds.Holds) != 0 {
+		t.Errorf("expected the hold released, got %+v", f.holds.Holds)
+	}
+}
+
+func TestWaitlist_JoinRejectsClosedDays(t *testing.T) {
+	day := time.Now().UTC().Add(48 * time.Hour).Truncate(24 * time.Hour)
+	avail := &MockAvailabilityRepo{GetByDateFunc: func(ctx context.Context, date string) (*domain.Availability, error) {
+		return &domain.Availability{Date: date, StartTime: "10:00", EndTime: "18:00", IsBlocked: true}, nil
+	}}
+	appts := &MockAppointmentRepo{}
+	svc := NewWaitlistService(&MockWaitlistRepo{}, appts, nil, nil, &MockSettingsRepo{}, NewAvailabilityService(avail, appts, nil, nil, nil), nil, nil, "", nil)
+
+	err := svc.Join(context.Background(), &domain.WaitlistEntry{ClientName: "Ana", Date: day.Format("2006-01-02")})
+	if !errors.Is(err, ErrInvalidWaitlistEntry) {
+		t.Errorf("expected ErrInvalidWaitlistEntry for a blocked day, got %v", err)
+	}
+}
+
+func TestWaitlist_OfferExpiryInShopTime(t *testing.T) {
+	f := newWaitlistFixture()
+	f.svc.loc = time.FixedZone("ART", -3*60*60)
+	ctx := context.Background()
+	appt := f.book()
+	f.join(t, "Ana", "1155550000")
+	appt.Status = domain.StatusCancelled
+	if err := f.svc.OfferSlot(ctx, f.slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "antes de las " + f.repo.byName("Ana").OfferExpiresAt.In(f.svc.loc).Format("15:04") + " desde"
+	if !strings.Contains(f.sender.WhatsApp, want) {
+		t.Errorf("expected the expiry in shop time (%q), got %q", want, f.sender.WhatsApp)
+	}
+}
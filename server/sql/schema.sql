@@ -1,17 +1,50 @@
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+-- Accent-insensitive, substring client search
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- unaccent is not IMMUTABLE; this wrapper can be used in indexes
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+    AS $$ SELECT public.unaccent('public.unaccent', $1) $$
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
 
 -- Users Table
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(255) NOT NULL,
-    email VARCHAR(255) UNIQUE NOT NULL,
+    email VARCHAR(255) NOT NULL, -- empty for imported clients without email
     phone VARCHAR(50) NOT NULL,
-    role VARCHAR(50) DEFAULT 'client',
+    role VARCHAR(50) DEFAULT 'client', -- client, admin, receptionist, barber
+    last_visit_at TIMESTAMP WITH TIME ZONE,
+    late_cancel_count INTEGER DEFAULT 0,
+    no_show_count INTEGER DEFAULT 0,
+    policy_strikes INTEGER DEFAULT 0, -- late cancellations + no-shows since the last admin clear
+    booking_blocked BOOLEAN DEFAULT FALSE,
+    is_guest BOOLEAN DEFAULT FALSE, -- created by a guest booking; claimed when the guest registers
+    phone_verified BOOLEAN DEFAULT FALSE, -- verified with a WhatsApp code instead of the email link
+    verification_hash VARCHAR(64), -- sha256 of the emailed verification token; cleared once used
+    verification_expires_at TIMESTAMP WITH TIME ZONE,
+    password_reset_hash VARCHAR(64), -- sha256 of the emailed reset token; cleared once used
+    password_reset_expires_at TIMESTAMP WITH TIME ZONE,
+    sessions_revoked_at TIMESTAMP WITH TIME ZONE, -- tokens issued earlier are rejected
+    totp_secret VARCHAR(64), -- base32 authenticator app secret; set while two-factor authentication is on
+    totp_pending_secret VARCHAR(64), -- secret being set up, until a code from it is confirmed
+    totp_last_step BIGINT DEFAULT 0, -- time step of the last code accepted, so codes work once
+    two_factor_enabled_at TIMESTAMP WITH TIME ZONE,
+    deactivated_at TIMESTAMP WITH TIME ZONE, -- set by an admin or by a merge; blocks login and booking
+    merged_into_id UUID REFERENCES users(id), -- survivor this duplicate was merged into
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Only non-empty emails must be unique, ignoring case
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (lower(email)) WHERE email <> '';
+CREATE INDEX IF NOT EXISTS idx_users_password_reset_hash ON users (password_reset_hash);
+CREATE INDEX IF NOT EXISTS idx_users_verification_hash ON users (verification_hash);
+CREATE INDEX IF NOT EXISTS idx_users_search ON users
+    USING gin (immutable_unaccent(lower(name || ' ' || email || ' ' || phone)) gin_trgm_ops);
+
 -- Availabilities Table
 -- Availabilities Table
 CREATE TABLE IF NOT EXISTS availabilities (
@@ -21,6 +54,7 @@ CREATE TABLE IF NOT EXISTS availabilities (
     end_time VARCHAR(5) NOT NULL,   -- HH:MM
     slot_duration INTEGER DEFAULT 60,
     is_blocked BOOLEAN DEFAULT FALSE,
+    open_on_holiday BOOLEAN DEFAULT FALSE, -- Work this date even if it is a public holiday
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 
@@ -35,9 +69,24 @@ CREATE TABLE IF NOT EXISTS appointments (
     client_id UUID REFERENCES users(id),
     start_time TIMESTAMP WITH TIME ZONE NOT NULL,
     end_time TIMESTAMP WITH TIME ZONE NOT NULL,
-    status VARCHAR(50) DEFAULT 'pending',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, confirmed, cancelled, completed, no_show, rescheduled
     google_event_id VARCHAR(255),
+    service VARCHAR(255),
     notes TEXT,
+    rescheduled_from_id UUID REFERENCES appointments(id),
+    barber_id UUID REFERENCES users(id),
+    requires_approval BOOLEAN DEFAULT FALSE,
+    deposit_required BOOLEAN DEFAULT FALSE,
+    deposit_amount NUMERIC(12,2),
+    payment_status VARCHAR(50), -- pending, paid, failed, refunded
+    payment_provider_id VARCHAR(255),
+    payment_id VARCHAR(255),
+    payment_checkout_url TEXT,
+    cancelled_by VARCHAR(50), -- client, shop
+    cancellation_reason VARCHAR(50),
+    cancellation_note TEXT,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    late_cancellation BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     
@@ -45,3 +94,206 @@ CREATE TABLE IF NOT EXISTS appointments (
     -- This unique constraint prevents exact start time duplicates.
     CONSTRAINT unique_slot UNIQUE (start_time) 
 );
+-- Speeds up the first-visit lookup of the analytics queries
+CREATE INDEX IF NOT EXISTS idx_appointments_client_id_start_time ON appointments(client_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_appointments_barber_id ON appointments(barber_id);
+
+-- Settings Table (single row, id = 1)
+CREATE TABLE IF NOT EXISTS settings (
+    id INTEGER PRIMARY KEY,
+    business_name VARCHAR(255),
+    closed_on_holidays BOOLEAN DEFAULT TRUE,
+    waitlist_claim_minutes INTEGER DEFAULT 30,
+    slot_hold_minutes INTEGER DEFAULT 10,
+    late_cancel_hours INTEGER DEFAULT 24,
+    block_after_strikes INTEGER DEFAULT 0, -- 0 disables blocking
+    restrict_after_strikes INTEGER DEFAULT 0, -- 0 disables restrictions
+    restriction_mode VARCHAR(50) DEFAULT 'approval', -- approval, deposit
+    deposit_amount NUMERIC(12,2) DEFAULT 0,
+    deposit_services TEXT, -- comma-separated service names
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Waitlist Table
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    client_name VARCHAR(255) NOT NULL,
+    client_email VARCHAR(255) NOT NULL,
+    client_phone VARCHAR(50) NOT NULL,
+    date DATE NOT NULL,
+    window_start VARCHAR(5), -- HH:MM
+    window_end VARCHAR(5),   -- HH:MM
+    service VARCHAR(255),
+    status VARCHAR(50) DEFAULT 'waiting',
+    offered_start TIMESTAMP WITH TIME ZONE,
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    claim_token_hash VARCHAR(64),
+    appointment_id UUID REFERENCES appointments(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_date ON waitlist_entries(date);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_claim_token_hash ON waitlist_entries(claim_token_hash);
+
+-- Slot Holds Table
+-- Short-lived reservations while a client fills the booking form.
+-- The exclusion constraint lets the database arbitrate between server instances.
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE UNIQUE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT slot_holds_no_overlap EXCLUDE USING gist (tstzrange(start_time, end_time) WITH &&)
+);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_expires_at ON slot_holds(expires_at);
+
+-- Appointment Events Table (append-only audit trail)
+CREATE TABLE IF NOT EXISTS appointment_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    appointment_id UUID NOT NULL REFERENCES appointments(id),
+    type VARCHAR(50) NOT NULL, -- created, confirmed, cancelled, rescheduled, completed, no_show, notes_changed, payment_recorded
+    actor_id UUID REFERENCES users(id), -- NULL for guests and system actions
+    before TEXT,
+    after TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_appointment_events_appointment_id ON appointment_events(appointment_id);
+
+-- Payments Table (point of sale)
+-- amount is what was charged after discount; tip is paid on top of it.
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    appointment_id UUID NOT NULL REFERENCES appointments(id),
+    method VARCHAR(50) NOT NULL, -- cash, transfer, card, mercadopago
+    amount NUMERIC(12,2) DEFAULT 0,
+    tip NUMERIC(12,2) DEFAULT 0,
+    discount NUMERIC(12,2) DEFAULT 0,
+    note TEXT,
+    recorded_by_id UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payments_appointment_id ON payments(appointment_id);
+CREATE INDEX IF NOT EXISTS idx_payments_created_at ON payments(created_at);
+
+-- Client Notes Table (private to the shop)
+CREATE TABLE IF NOT EXISTS client_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    client_id UUID NOT NULL REFERENCES users(id),
+    author_id UUID REFERENCES users(id), -- NULL for notes brought in by an import
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_client_notes_client_id ON client_notes(client_id);
+
+-- One-Time Codes Table
+-- Codes sent by email or WhatsApp to confirm guest bookings. Only the hash is stored.
+CREATE TABLE IF NOT EXISTS one_time_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    purpose VARCHAR(50) NOT NULL, -- guest_booking
+    channel VARCHAR(20) NOT NULL, -- email, whatsapp
+    destination VARCHAR(255) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    payload TEXT, -- JSON of what to do once redeemed
+    attempts INTEGER DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_one_time_codes_destination ON one_time_codes(purpose, destination);
+
+-- Sessions Table
+-- One row per login. The refresh token is rotated on every use and only its hash is kept;
+-- access tokens carry the session id so revoking the row logs the device out.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    refresh_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_agent TEXT,
+    ip VARCHAR(64),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_reason VARCHAR(20), -- logout, logout_all, admin, reuse
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- OIDC Logins Table
+-- Logins started with an OpenID Connect provider (Google), deleted when the provider
+-- redirects back. Only the hash of the state is kept; the PKCE verifier never leaves the server.
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(50) NOT NULL,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User Identities Table
+-- Accounts at external identity providers linked to a user, by the provider's subject.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255), -- as reported by the provider when linked
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_subject ON user_identities(provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Rate Counters Table
+-- Rate limit and failed login counters, when RATE_LIMIT_BACKEND=postgres shares them
+-- between instances. Each counts hits in a fixed window ending at reset_at.
+CREATE TABLE IF NOT EXISTS rate_counters (
+    key VARCHAR(255) PRIMARY KEY, -- e.g. auth:ip:203.0.113.7, login-failures:ana@example.com
+    count INTEGER NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reset_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_counters_reset_at ON rate_counters(reset_at);
+
+-- Two-Factor Logins Table
+-- Logins waiting for a code from the authenticator app; no session exists until then.
+-- Only the hash of the challenge token is kept.
+CREATE TABLE IF NOT EXISTS two_factor_logins (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_two_factor_logins_user_id ON two_factor_logins(user_id);
+
+-- Recovery Codes Table
+-- Single-use codes that stand in for the authenticator app. Only their hashes are kept.
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_hash ON recovery_codes(user_id, code_hash);
+
+-- Pending Registrations Table
+-- Registrations with the email of a guest. The guest keeps its details until the
+-- emailed link is used; only the hash of its token is kept.
+CREATE TABLE IF NOT EXISTS pending_registrations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id), -- the guest being claimed
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    password VARCHAR(255) NOT NULL, -- bcrypt hash
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_pending_registrations_user_id ON pending_registrations(user_id);